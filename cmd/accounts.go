@@ -1,12 +1,16 @@
 package cmd
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/yanicksenn/gtasks/internal/auth"
 	"github.com/yanicksenn/gtasks/internal/config"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/ui"
 )
 
 var accountsCmd = &cobra.Command{
@@ -17,35 +21,182 @@ var accountsCmd = &cobra.Command{
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Authenticate with Google and add a new account",
+	Long: `Authenticate with Google and add a new account.
+
+By default, the browser is opened on Google's consent page and the result is
+received on a local port. On machines without a browser, e.g. over SSH, use
+--no-browser to open the printed URL on any machine and paste the URL it
+redirects to, or --device to enter a code on another device.
+
+--scopes requests other scopes than the configured ones, e.g. tasks.readonly
+for a read-only account, and saves them as the scopes of the account. With
+--account, the login is for that account and uses its configured scopes.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		h, err := NewCommandHelper(cmd)
+		// Get the flag values
+		noBrowser, _ := cmd.Flags().GetBool("no-browser")
+		device, _ := cmd.Flags().GetBool("device")
+		scopes, _ := cmd.Flags().GetStringSlice("scopes")
+		account, _ := cmd.Flags().GetString("account")
+
+		// Logging in needs no client, which would start a login itself.
+		printer, err := newPrinter(cmd)
 		if err != nil {
 			return err
 		}
-		user, err := auth.LoginViaWebFlow(context.Background())
+
+		opts := auth.LoginOptions{Flow: auth.BrowserFlow, In: cmd.InOrStdin(), Out: cmd.ErrOrStderr(), Scopes: scopes, Account: account}
+		if noBrowser {
+			opts.Flow = auth.ManualFlow
+		}
+		if device {
+			opts.Flow = auth.DeviceFlow
+		}
+		user, err := auth.Login(cmd.Context(), opts)
 		if err != nil {
-			return fmt.Errorf("error during authentication: %w", err)
+			return &gtasks.Error{Kind: gtasks.ErrUnauthenticated, Message: fmt.Sprintf("error during authentication: %v", err), Err: err}
+		}
+
+		err = config.Update(func(cfg *config.Config) error {
+			// Logging in to a given account does not switch to it.
+			if account == "" || cfg.ActiveAccount == "" {
+				cfg.ActiveAccount = user
+			}
+			if len(scopes) > 0 {
+				cfg.SetAccountScopes(user, auth.ExpandScopes(scopes))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error saving config: %w", err)
 		}
 
+		return printer.PrintSuccess(fmt.Sprintf("Successfully logged in as %s.", user))
+	},
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Log out of the active account, or of the account given by --account",
+	Long: `Log out of the active account, or of the account given by --account.
+
+The token is removed from the credential store. With --revoke, it is also
+revoked at Google, so that it stops working on every machine it was copied
+to. --all logs out of and revokes the tokens of all accounts.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the flag values
+		revoke, _ := cmd.Flags().GetBool("revoke")
+		all, _ := cmd.Flags().GetBool("all")
+		account, _ := cmd.Flags().GetString("account")
+
+		// Logging out needs no client, which would start a login itself.
+		printer, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
 		cfg, err := config.Load()
 		if err != nil {
 			return fmt.Errorf("error loading config: %w", err)
 		}
 
-		cfg.ActiveAccount = user
-		if err := cfg.Save(); err != nil {
+		var accounts []string
+		if all {
+			if account != "" {
+				return usageError(errors.New("--account and --all cannot be used together"))
+			}
+			if accounts, err = auth.ListAccounts(); err != nil {
+				return fmt.Errorf("error listing accounts: %w", err)
+			}
+			slices.Sort(accounts)
+			revoke = true
+		} else {
+			if account == "" {
+				account = cfg.ActiveAccount
+			}
+			if account == "" {
+				return gtasks.NewError(gtasks.ErrNotFound, "no active account to log out of")
+			}
+			accounts = []string{account}
+		}
+
+		// Log out of the other accounts even if one fails.
+		var errs []error
+		for _, account := range accounts {
+			if err := logoutAccount(cmd, printer, cfg, account, revoke); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// logoutAccount removes the token of an account, clears the active account
+// if it was the one, and reports the logout.
+func logoutAccount(cmd *cobra.Command, printer *ui.Printer, cfg *config.Config, account string, revoke bool) error {
+	if err := removeAccount(cmd, account, revoke); err != nil {
+		return err
+	}
+	if account == cfg.ActiveAccount {
+		err := config.Update(func(cfg *config.Config) error {
+			// Another process may have switched accounts meanwhile.
+			if cfg.ActiveAccount == account {
+				cfg.ActiveAccount = ""
+			}
+			return nil
+		})
+		if err != nil {
 			return fmt.Errorf("error saving config: %w", err)
 		}
+	}
+	if revoke {
+		return printer.PrintSuccess(fmt.Sprintf("Successfully logged out of %s and revoked its token.", account))
+	}
+	return printer.PrintSuccess(fmt.Sprintf("Successfully logged out of %s.", account))
+}
 
-		return h.Printer.PrintSuccess(fmt.Sprintf("Successfully logged in as %s.", user))
+var removeAccountCmd = &cobra.Command{
+	Use:   "remove [EMAIL]",
+	Short: "Remove an account that is not the active one",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the flag values
+		revoke, _ := cmd.Flags().GetBool("revoke")
+
+		printer, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+
+		email := args[0]
+		if email == cfg.ActiveAccount {
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "%s is the active account. Use 'gtasks accounts logout' or switch to another account first", email)
+		}
+		if err := removeAccount(cmd, email, revoke); err != nil {
+			return err
+		}
+		return printer.PrintSuccess(fmt.Sprintf("Successfully removed %s.", email))
 	},
 }
 
-var logoutCmd = &cobra.Command{
-	Use:   "logout",
-	Short: "Log out of the active account",
+var accountStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the token status of all accounts",
+	Long: `Show the token status of all accounts: the expiry of the access token, the
+granted scopes and when the token was last refreshed. The stored tokens are
+shown as they are; with --check-refresh, every token is refreshed to check
+that refreshing works.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		h, err := NewCommandHelper(cmd)
+		// Get the flag values
+		checkRefresh, _ := cmd.Flags().GetBool("check-refresh")
+		offline, _ := cmd.Flags().GetBool("offline")
+		if checkRefresh && offline {
+			return usageError(errors.New("--check-refresh and --offline cannot be used together"))
+		}
+
+		printer, err := newPrinter(cmd)
 		if err != nil {
 			return err
 		}
@@ -54,19 +205,61 @@ var logoutCmd = &cobra.Command{
 			return fmt.Errorf("error loading config: %w", err)
 		}
 
-		if err := auth.Logout(cfg.ActiveAccount); err != nil {
-			return fmt.Errorf("error logging out: %w", err)
+		statuses, err := auth.Status(cmd.Context(), auth.StatusOptions{CheckRefresh: checkRefresh})
+		if err != nil {
+			return fmt.Errorf("error getting account status: %w", err)
 		}
 
-		h.Printer.PrintSuccess(fmt.Sprintf("Successfully logged out of %s.", cfg.ActiveAccount))
-		cfg.ActiveAccount = ""
-		if err := cfg.Save(); err != nil {
-			return fmt.Errorf("error saving config: %w", err)
+		printed := &ui.AccountStatuses{}
+		for _, status := range statuses {
+			item := ui.AccountStatus{
+				Account:  status.Account,
+				Active:   status.Account == cfg.ActiveAccount,
+				ClientID: status.ClientID,
+				Scopes:   status.Scopes,
+				Refresh:  ui.RefreshUnchecked,
+			}
+			if !status.Expiry.IsZero() {
+				item.Expiry = status.Expiry.Format(time.RFC3339)
+			}
+			if !status.Refreshed.IsZero() {
+				item.Refreshed = status.Refreshed.Format(time.RFC3339)
+			}
+			if status.Checked {
+				item.Refresh = ui.RefreshOK
+			}
+			if status.RefreshErr != nil {
+				item.Refresh = ui.RefreshFailed
+				item.Error = status.RefreshErr.Error()
+			}
+			printed.Items = append(printed.Items, item)
 		}
-		return nil
+		return printer.PrintAccountStatuses(printed)
 	},
 }
 
+// removeAccount removes the token of an account, revoking it first if
+// requested.
+func removeAccount(cmd *cobra.Command, account string, revoke bool) error {
+	accounts, err := auth.ListAccounts()
+	if err != nil {
+		return fmt.Errorf("error listing accounts: %w", err)
+	}
+	if !slices.Contains(accounts, account) {
+		return gtasks.NewError(gtasks.ErrNotFound, "account %s not found", account)
+	}
+	if revoke {
+		if err := auth.Revoke(cmd.Context(), account); err != nil {
+			return fmt.Errorf("error revoking the token of %s: %w", account, err)
+		}
+		return nil
+	}
+	if err := auth.Logout(account); err != nil {
+		return fmt.Errorf("error logging out: %w", err)
+	}
+	return nil
+}
+
 var listAccountsCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all authenticated accounts",
@@ -102,19 +295,63 @@ var switchAccountCmd = &cobra.Command{
 
 		for _, account := range accounts {
 			if account == email {
-				cfg, err := config.Load()
+				err := config.Update(func(cfg *config.Config) error {
+					cfg.ActiveAccount = email
+					return nil
+				})
 				if err != nil {
-					return fmt.Errorf("error loading config: %w", err)
-				}
-				cfg.ActiveAccount = email
-				if err := cfg.Save(); err != nil {
 					return fmt.Errorf("error saving config: %w", err)
 				}
 				return h.Printer.PrintSuccess(fmt.Sprintf("Successfully switched to %s.", email))
 			}
 		}
 
-		return fmt.Errorf("account %s not found. Please log in first", email)
+		return gtasks.NewError(gtasks.ErrNotFound, "account %s not found. Please log in first", email)
+	},
+}
+
+var migrateCredentialsCmd = &cobra.Command{
+	Use:   "migrate-credentials",
+	Short: "Move the stored tokens to another credential store",
+	Long: `Move the stored tokens to another credential store.
+
+The tokens of all accounts are copied to the destination store and removed
+from the source store. The destination becomes the configured credential
+store. Available stores: plaintext, encrypted-file and secret-service.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the flag values
+		to, _ := cmd.Flags().GetString("to")
+		from, _ := cmd.Flags().GetString("from")
+
+		// Migrating needs no client, which would load the tokens itself.
+		printer, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+		if from == "" {
+			from = cfg.CredentialStore
+		}
+
+		source, err := auth.NewCredentialStore(from)
+		if err != nil {
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "%v", err)
+		}
+		destination, err := auth.NewCredentialStore(to)
+		if err != nil {
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "%v", err)
+		}
+
+		moved, err := auth.MigrateCredentials(source, destination)
+		if err != nil {
+			return fmt.Errorf("error migrating credentials: %w", err)
+		}
+
+		return printer.PrintSuccess(fmt.Sprintf("Moved %d account(s) from %s to %s.", moved, source.Name(), destination.Name()))
 	},
 }
 
@@ -124,4 +361,23 @@ func init() {
 	accountsCmd.AddCommand(logoutCmd)
 	accountsCmd.AddCommand(listAccountsCmd)
 	accountsCmd.AddCommand(switchAccountCmd)
-}
\ No newline at end of file
+	accountsCmd.AddCommand(migrateCredentialsCmd)
+	accountsCmd.AddCommand(removeAccountCmd)
+	accountsCmd.AddCommand(accountStatusCmd)
+
+	loginCmd.Flags().Bool("no-browser", false, "Print the login URL instead of opening the browser and read the redirect URL or code from stdin")
+	loginCmd.Flags().Bool("device", false, "Log in by entering a code on another device (needs a custom OAuth client of the type \"TVs and Limited Input devices\")")
+	loginCmd.MarkFlagsMutuallyExclusive("no-browser", "device")
+	loginCmd.Flags().StringSlice("scopes", nil, "The OAuth scopes to request, e.g. tasks.readonly (default: the configured scopes)")
+
+	logoutCmd.Flags().Bool("revoke", false, "Also revoke the token at Google")
+	logoutCmd.Flags().Bool("all", false, "Log out of all accounts and revoke their tokens")
+
+	removeAccountCmd.Flags().Bool("revoke", false, "Also revoke the token at Google")
+
+	accountStatusCmd.Flags().Bool("check-refresh", false, "Refresh every token to check that refreshing works")
+
+	migrateCredentialsCmd.Flags().String("to", "", "The credential store to move the tokens to (plaintext, encrypted-file or secret-service)")
+	migrateCredentialsCmd.Flags().String("from", "", "The credential store to move the tokens from (default: the configured store)")
+	migrateCredentialsCmd.MarkFlagRequired("to")
+}
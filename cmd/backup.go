@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/lockedfile"
+	"github.com/yanicksenn/gtasks/internal/store"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up all task lists and tasks of the account",
+	Long: `Snapshots every task list and task of the active account, including completed
+and hidden tasks with all their fields, into a versioned archive. The archive
+uses the format of the offline store, so 'gtasks restore --seed-offline' can
+load it into offline mode.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Get the flag values
+		file, _ := cmd.Flags().GetString("file")
+		workers, _ := cmd.Flags().GetInt("workers")
+		if file == "" {
+			file = "gtasks-backup-" + time.Now().Format("20060102-150405") + ".json"
+		}
+
+		// Back up the account
+		account := h.Account
+		if offline, _ := cmd.Flags().GetBool("offline"); offline {
+			account = "offline"
+		}
+		archive, err := gtasks.Backup(cmd.Context(), h.Client, gtasks.BackupOptions{Account: account, Workers: workers})
+		if err != nil {
+			return fmt.Errorf("error backing up: %w", err)
+		}
+
+		// Write the archive
+		if file == "-" {
+			return store.WriteArchive(cmd.OutOrStdout(), archive)
+		}
+		var buf bytes.Buffer
+		if err := store.WriteArchive(&buf, archive); err != nil {
+			return fmt.Errorf("error writing backup file: %w", err)
+		}
+		if err := writeNewFile(file, buf.Bytes()); err != nil {
+			return fmt.Errorf("error writing backup file: %w", err)
+		}
+
+		count := 0
+		for _, items := range archive.Data.Tasks {
+			count += len(items)
+		}
+		return h.Printer.PrintSuccess(fmt.Sprintf("Backed up %d task list(s) and %d task(s) to %s", len(archive.Data.TaskLists), count, file))
+	},
+}
+
+// writeNewFile writes data to a file that must not exist yet. The name is
+// reserved first, so that an existing file is never overwritten, and the
+// data is then written through a temporary file, so that a failed write
+// never leaves a truncated file behind.
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err := lockedfile.Write(path, data, 0600); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [FILE]",
+	Short: "Restore task lists and tasks from a backup",
+	Long: `Recreates the task lists and tasks of a backup in the active account, which
+may be another account than the one that was backed up. Task lists are matched
+by ID, then by title, and created if they do not exist. Tasks are matched by
+ID, so restoring into the same account only recreates what is missing and
+reverts what was changed. Tasks created by a restore remember their original
+ID in their notes, so restoring again does not create duplicates. Tasks that
+are not in the backup are left alone. Use '-' to read from stdin.
+
+With --dry-run, the differences between the backup and the current state are
+printed instead. With --seed-offline, the backup replaces the content of the
+offline store instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the flag values
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		seedOffline, _ := cmd.Flags().GetBool("seed-offline")
+
+		// Read the archive
+		var in io.Reader = cmd.InOrStdin()
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("error opening backup file: %w", err)
+			}
+			defer f.Close()
+			in = f
+		}
+		archive, err := store.ReadArchive(in)
+		if err != nil {
+			return fmt.Errorf("error reading backup file: %w", err)
+		}
+
+		if seedOffline {
+			local, err := gtasks.OpenOfflineStore()
+			if err != nil {
+				return fmt.Errorf("error opening offline store: %w", err)
+			}
+			if err := local.Seed(archive.Data); err != nil {
+				return fmt.Errorf("error seeding offline store: %w (run 'gtasks sync' first)", err)
+			}
+			quiet, _ := cmd.Flags().GetBool("quiet")
+			if !quiet {
+				fmt.Fprintf(cmd.OutOrStdout(), "Seeded the offline store with %d task list(s)\n", len(archive.Data.TaskLists))
+			}
+			return nil
+		}
+
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Restore the archive
+		result, err := gtasks.Restore(cmd.Context(), h.Client, gtasks.RestoreOptions{DryRun: dryRun}, archive)
+		if err != nil {
+			return fmt.Errorf("error restoring: %w", err)
+		}
+
+		// Print the differences
+		if dryRun {
+			for _, list := range result.TaskLists {
+				if list.Created {
+					h.Printer.PrintSuccess(fmt.Sprintf("+ %s (new task list)", list.TaskList.Title))
+				} else if list.Result.Created+list.Result.Updated > 0 {
+					h.Printer.PrintSuccess(fmt.Sprintf("  %s", list.TaskList.Title))
+				}
+				for _, action := range list.Result.Actions {
+					switch action.Action {
+					case gtasks.ImportCreated:
+						h.Printer.PrintSuccess(fmt.Sprintf("    + %s", action.Task.Title))
+					case gtasks.ImportUpdated:
+						h.Printer.PrintSuccess(fmt.Sprintf("    ~ %s (%s)", action.Task.Title, strings.Join(action.Changes, ", ")))
+					}
+				}
+			}
+		}
+
+		verb := "Restored"
+		if dryRun {
+			verb = "Would restore"
+		}
+		return h.Printer.PrintSuccess(fmt.Sprintf("%s %d task list(s): %d task list(s) and %d task(s) created, %d task(s) updated, %d unchanged",
+			verb, len(result.TaskLists), result.TaskListsCreated, result.Created, result.Updated, result.Unchanged))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(backupCmd)
+	RootCmd.AddCommand(restoreCmd)
+
+	backupCmd.Flags().String("file", "", "The file to write the backup to ('-' for stdout; defaults to gtasks-backup-<time>.json)")
+	backupCmd.Flags().Int("workers", gtasks.DefaultWorkers, "The number of task lists to fetch concurrently")
+
+	restoreCmd.Flags().Bool("dry-run", false, "Print the differences between the backup and the current state without changing anything")
+	restoreCmd.Flags().Bool("seed-offline", false, "Replace the content of the offline store with the backup")
+
+	restoreCmd.MarkFlagsMutuallyExclusive("dry-run", "seed-offline")
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"google.golang.org/api/tasks/v1"
+)
+
+// addSelectionFlags adds the flags that select the tasks of a bulk command.
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("tasklist", "@default", "The ID of the task list")
+	cmd.Flags().String("title-contains", "", "Select tasks by title (case-insensitive)")
+	cmd.Flags().String("notes-contains", "", "Select tasks by notes (case-insensitive)")
+	cmd.Flags().String("due-before", "", "Select tasks with a due date before the specified date (e.g., '2025-12-31' or 'next friday')")
+	cmd.Flags().String("due-after", "", "Select tasks with a due date after the specified date (e.g., '2025-12-31' or 'today')")
+	cmd.Flags().String("query", "", `Select tasks by a query (e.g., 'status:needsAction AND due<today+7d')`)
+	cmd.Flags().Bool("show-completed", false, "Include completed tasks when selecting by filter")
+	cmd.Flags().Bool("show-hidden", false, "Include hidden tasks when selecting by filter")
+	cmd.Flags().Bool("dry-run", false, "Print the selected tasks without changing them")
+	cmd.Flags().Int("workers", gtasks.DefaultWorkers, "Number of concurrent API calls")
+}
+
+// getTaskIDs returns the task IDs given as arguments. A single "-" argument
+// reads whitespace-separated IDs from stdin instead.
+func getTaskIDs(cmd *cobra.Command, args []string) ([]string, bool, error) {
+	if len(args) != 1 || args[0] != "-" {
+		return args, false, nil
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(cmd.InOrStdin())
+	for scanner.Scan() {
+		ids = append(ids, strings.Fields(scanner.Text())...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, true, fmt.Errorf("error reading task IDs: %w", err)
+	}
+	return ids, true, nil
+}
+
+// bulkSelection holds the tasks selected by a bulk command.
+type bulkSelection struct {
+	Tasks      []*tasks.Task
+	TaskListID string
+	// ByFilter is set if the tasks were selected by filter instead of IDs.
+	ByFilter bool
+	// FromStdin is set if the task IDs were read from stdin.
+	FromStdin bool
+	Workers   int
+}
+
+// selectTasks resolves the tasks selected by the arguments and flags of a
+// bulk command. Completed and hidden tasks are always included if
+// includeCompleted is set.
+func selectTasks(cmd *cobra.Command, h *CommandHelper, args []string, includeCompleted bool) (*bulkSelection, error) {
+	// Get the flag values
+	ids, fromStdin, err := getTaskIDs(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	tasklist, _ := cmd.Flags().GetString("tasklist")
+	titleContains, _ := cmd.Flags().GetString("title-contains")
+	notesContains, _ := cmd.Flags().GetString("notes-contains")
+	dueBefore, _ := cmd.Flags().GetString("due-before")
+	dueAfter, _ := cmd.Flags().GetString("due-after")
+	query, _ := cmd.Flags().GetString("query")
+	showCompleted, _ := cmd.Flags().GetBool("show-completed")
+	showHidden, _ := cmd.Flags().GetBool("show-hidden")
+	workers, _ := cmd.Flags().GetInt("workers")
+
+	opts := gtasks.SelectTasksOptions{
+		TaskListID: tasklist,
+		TaskIDs:    ids,
+		Filter: gtasks.FilterOptions{
+			TitleContains: titleContains,
+			NotesContains: notesContains,
+			DueBefore:     dueBefore,
+			DueAfter:      dueAfter,
+			Query:         query,
+		},
+		ShowCompleted: showCompleted || includeCompleted,
+		ShowHidden:    showHidden || includeCompleted,
+		Workers:       workers,
+	}
+
+	// Select the tasks
+	selected, err := gtasks.SelectTasks(cmd.Context(), h.Client, opts)
+	if errors.Is(err, gtasks.ErrEmptySelection) {
+		return nil, gtasks.NewError(gtasks.ErrInvalidArgument, "specify task IDs, '-' to read them from stdin, or a filter")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error selecting tasks: %w", err)
+	}
+
+	return &bulkSelection{
+		Tasks:      selected,
+		TaskListID: tasklist,
+		ByFilter:   len(ids) == 0,
+		FromStdin:  fromStdin,
+		Workers:    workers,
+	}, nil
+}
+
+// isBulk reports whether the selection may affect more than the single task
+// named on the command line.
+func (s *bulkSelection) isBulk() bool {
+	return s.ByFilter || s.FromStdin || len(s.Tasks) > 1
+}
+
+// printDryRun prints the selected tasks if --dry-run is set and reports
+// whether it was.
+func printDryRun(cmd *cobra.Command, h *CommandHelper, s *bulkSelection, verb string) (bool, error) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if !dryRun {
+		return false, nil
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Dry run: would %s %d task(s)\n", verb, len(s.Tasks))
+	return true, h.Printer.PrintTasks(&tasks.Tasks{Items: s.Tasks})
+}
+
+// confirmBulk asks the user to confirm a destructive bulk action unless
+// --yes is set. IDs read from stdin leave no way to answer, so --yes is
+// required in that case.
+func confirmBulk(cmd *cobra.Command, s *bulkSelection, verb string) (bool, error) {
+	yes, _ := cmd.Flags().GetBool("yes")
+	if yes || !s.isBulk() {
+		return true, nil
+	}
+	if s.FromStdin {
+		return false, gtasks.NewError(gtasks.ErrInvalidArgument, "refusing to %s tasks read from stdin without --yes", verb)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "This will %s %d task(s):\n", verb, len(s.Tasks))
+	for _, task := range s.Tasks {
+		fmt.Fprintf(cmd.ErrOrStderr(), "  %s (%s)\n", task.Title, task.Id)
+	}
+	fmt.Fprint(cmd.ErrOrStderr(), "Continue? [y/N] ")
+
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, nil
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// runBulk applies fn to the selected tasks and calls success for every task
+// that succeeded. It returns an error listing the tasks that failed.
+func runBulk(cmd *cobra.Command, s *bulkSelection, gerund string, fn func(ctx context.Context, task *tasks.Task) (*tasks.Task, error), success func(task *tasks.Task) error) error {
+	if len(s.Tasks) == 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "No tasks selected")
+		return nil
+	}
+
+	results := gtasks.RunBulk(cmd.Context(), s.Tasks, s.Workers, fn)
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", result.Task.Title, result.Task.Id, result.Err))
+			continue
+		}
+		if err := success(result.Task); err != nil {
+			return err
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(s.Tasks) == 1 {
+		return fmt.Errorf("error %s task: %w", gerund, results[0].Err)
+	}
+	return fmt.Errorf("error %s %d of %d tasks: %w", gerund, len(errs), len(s.Tasks), errors.Join(errs...))
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+)
+
+// Exit codes of the gtasks process. Errors without a kind exit with
+// ExitError.
+const (
+	ExitError           = 1
+	ExitInvalidArgument = 2
+	ExitNotFound        = 3
+	ExitConflict        = 4
+	ExitUnauthenticated = 5
+	ExitRateLimited     = 6
+)
+
+// errorKind describes an error kind on the command line.
+type errorKind struct {
+	kind     error
+	code     string
+	exitCode int
+}
+
+// errorKinds maps the error kinds of gtasks to codes and exit codes.
+var errorKinds = []errorKind{
+	{gtasks.ErrInvalidArgument, "invalid_argument", ExitInvalidArgument},
+	{gtasks.ErrNotFound, "not_found", ExitNotFound},
+	{gtasks.ErrConflict, "conflict", ExitConflict},
+	{gtasks.ErrUnauthenticated, "unauthenticated", ExitUnauthenticated},
+	{gtasks.ErrRateLimited, "rate_limited", ExitRateLimited},
+}
+
+// kindOf returns the description of the kind of an error.
+func kindOf(err error) errorKind {
+	kind := gtasks.KindOf(err)
+	for _, k := range errorKinds {
+		if k.kind == kind {
+			return k
+		}
+	}
+	return errorKind{code: "error", exitCode: ExitError}
+}
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return kindOf(err).exitCode
+}
+
+// errorObject is the machine-readable form of an error.
+type errorObject struct {
+	Error struct {
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		ExitCode int    `json:"exitCode"`
+	} `json:"error"`
+}
+
+// PrintError writes an error returned by Execute to w. With --output json,
+// the error is written as a JSON object with its code, message and exit
+// code.
+func PrintError(w io.Writer, err error) {
+	if output, _ := RootCmd.PersistentFlags().GetString("output"); output == "json" {
+		var obj errorObject
+		kind := kindOf(err)
+		obj.Error.Code = kind.code
+		obj.Error.Message = err.Error()
+		obj.Error.ExitCode = kind.exitCode
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if encoder.Encode(obj) == nil {
+			return
+		}
+	}
+	fmt.Fprintf(w, "Error: %s\n", err)
+}
+
+// usageError marks an error in the flags or arguments of a command as an
+// invalid argument.
+func usageError(err error) error {
+	if err == nil || errors.Is(err, gtasks.ErrInvalidArgument) {
+		return err
+	}
+	return &gtasks.Error{Kind: gtasks.ErrInvalidArgument, Err: err}
+}
+
+// markUsageErrors marks the argument errors of a command and its
+// subcommands as invalid arguments.
+func markUsageErrors(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			return usageError(args(cmd, a))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/export"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/lockedfile"
+	"google.golang.org/api/tasks/v1"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the tasks of a task list to a file",
+	Long: `Exports all tasks of a task list, including completed and hidden tasks, in a
+format other tools can read. Supported formats: ics (iCalendar VTODO), todotxt
+(todo.txt, with the task list as +project) and markdown (a checklist). With
+--all-lists, the todotxt format exports every task list into one file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the flag values
+		format, _ := cmd.Flags().GetString("format")
+		tasklist, _ := cmd.Flags().GetString("tasklist")
+		file, _ := cmd.Flags().GetString("file")
+		allLists, _ := cmd.Flags().GetBool("all-lists")
+		write, err := exportWriter(format)
+		if err != nil {
+			return err
+		}
+		if allLists && format != "todotxt" {
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "--all-lists is only supported by the todotxt format")
+		}
+
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Get the task lists and their tasks
+		var lists []*tasks.TaskList
+		if allLists {
+			result, err := h.Client.ListTaskLists(cmd.Context(), gtasks.ListTaskListsOptions{})
+			if err != nil {
+				return fmt.Errorf("error listing task lists: %w", err)
+			}
+			lists = result.Items
+			if len(lists) == 0 {
+				return gtasks.NewError(gtasks.ErrNotFound, "no task lists to export")
+			}
+		} else {
+			list, err := h.Client.GetTaskList(cmd.Context(), gtasks.GetTaskListOptions{TaskListID: tasklist})
+			if err != nil {
+				return fmt.Errorf("error getting task list: %w", err)
+			}
+			lists = []*tasks.TaskList{list}
+		}
+		var items []export.Item
+		for _, list := range lists {
+			listOpts := gtasks.ListTasksOptions{TaskListID: list.Id, ShowCompleted: true, ShowHidden: true, SortBy: "position"}
+			result, err := h.Client.ListTasks(cmd.Context(), listOpts)
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+			for _, item := range export.ItemsFromTasks(result.Items) {
+				item.TaskList = list.Title
+				items = append(items, item)
+			}
+		}
+
+		// Write the file, naming it after the task list if there is only one
+		name := ""
+		if len(lists) == 1 {
+			name = lists[0].Title
+		}
+		if file == "" || file == "-" {
+			if err := write(cmd.OutOrStdout(), items, name); err != nil {
+				return fmt.Errorf("error exporting tasks: %w", err)
+			}
+			return nil
+		}
+		var buf bytes.Buffer
+		if err := write(&buf, items, name); err != nil {
+			return fmt.Errorf("error exporting tasks: %w", err)
+		}
+		if err := lockedfile.Write(file, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("error writing file: %w", err)
+		}
+		return nil
+	},
+}
+
+// exportWriter returns the function that writes items in the given format.
+// name is the name of the exported task list.
+func exportWriter(format string) (func(w io.Writer, items []export.Item, name string) error, error) {
+	switch format {
+	case "ics":
+		return func(w io.Writer, items []export.Item, name string) error {
+			return export.WriteICS(w, items, export.ICSOptions{Name: name, Now: time.Now()})
+		}, nil
+	case "todotxt":
+		return func(w io.Writer, items []export.Item, name string) error {
+			return export.WriteTodoTxt(w, items)
+		}, nil
+	case "markdown":
+		return func(w io.Writer, items []export.Item, name string) error {
+			return export.WriteMarkdown(w, items)
+		}, nil
+	default:
+		return nil, gtasks.NewError(gtasks.ErrInvalidArgument, "unsupported export format: %s", format)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().String("format", "ics", "The export format (ics, todotxt, markdown)")
+	exportCmd.Flags().String("tasklist", "@default", "The ID of the task list")
+	exportCmd.Flags().Bool("all-lists", false, "Export all task lists (todotxt only)")
+	exportCmd.Flags().String("file", "", "The file to write to (stdout if empty)")
+
+	exportCmd.MarkFlagsMutuallyExclusive("tasklist", "all-lists")
+}
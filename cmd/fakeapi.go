@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/fakeapi"
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+var fakeAPICmd = &cobra.Command{
+	Use:    "fake-api",
+	Short:  "Serve a local fake of the Google Tasks API",
+	Hidden: true,
+	Long: `Serves a fake of the Google Tasks API for demos and tests, backed by a store
+in the format of the offline store. Point other gtasks commands at it with the
+hidden --api-endpoint flag; they then need no network access or login.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Get the flag values
+		listen, _ := cmd.Flags().GetString("listen")
+		file, _ := cmd.Flags().GetString("file")
+
+		// Open the store with a default task list
+		st, err := store.NewInMemoryStore(file)
+		if err != nil {
+			return fmt.Errorf("error opening store: %w", err)
+		}
+		if lists, _ := st.ListTaskLists(); len(lists) == 0 {
+			if _, err := st.CreateTaskList(&tasks.TaskList{Title: "My Tasks"}); err != nil {
+				return fmt.Errorf("error creating default task list: %w", err)
+			}
+		}
+
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			return fmt.Errorf("error listening on %s: %w", listen, err)
+		}
+		server := &http.Server{Handler: fakeapi.New(st)}
+		go func() {
+			<-cmd.Context().Done()
+			server.Close()
+		}()
+
+		url := "http://" + listener.Addr().String() + "/"
+		fmt.Fprintf(cmd.OutOrStdout(), "Serving a fake Google Tasks API at %s\nUse it with: gtasks --api-endpoint %s tasks list\n", url, url)
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(fakeAPICmd)
+
+	fakeAPICmd.Flags().String("listen", "127.0.0.1:8081", "The address to listen on")
+	fakeAPICmd.Flags().String("file", "", "The file to keep the data in (defaults to memory only)")
+}
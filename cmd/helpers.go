@@ -1,7 +1,8 @@
 package cmd
 
 import (
-	"context"
+	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/yanicksenn/gtasks/internal/config"
@@ -14,29 +15,66 @@ type CommandHelper struct {
 	Client  gtasks.Client
 	Config  *config.Config
 	Printer *ui.Printer
+	// Account is the account the command acts for: the --account flag or
+	// the active account.
+	Account string
 }
 
 // NewCommandHelper creates a new CommandHelper.
 func NewCommandHelper(cmd *cobra.Command) (*CommandHelper, error) {
-	cfg, err := config.Load()
+	client, err := gtasks.NewClientFromCommand(cmd, cmd.Context())
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := gtasks.NewClientFromCommand(cmd, context.Background())
+	// Load the config after the client, whose creation may have logged in
+	// a new active account.
+	cfg, err := config.Load()
 	if err != nil {
 		return nil, err
 	}
 
-	quiet, _ := cmd.Flags().GetBool("quiet")
-	outputFormat, _ := cmd.Flags().GetString("output")
+	printer, err := newPrinter(cmd)
+	if err != nil {
+		return nil, err
+	}
 
-	printer := ui.NewPrinter(cmd.OutOrStdout(), outputFormat, quiet)
+	account, _ := cmd.Flags().GetString("account")
+	if account == "" {
+		account = cfg.ActiveAccount
+	}
 
 	return &CommandHelper{
 		Client:  client,
 		Config:  cfg,
 		Printer: printer,
+		Account: account,
 	}, nil
 }
 
+// newPrinter creates a printer based on the output flags of a command.
+func newPrinter(cmd *cobra.Command) (*ui.Printer, error) {
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	outputFormat, _ := cmd.Flags().GetString("output")
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	noHeaders, _ := cmd.Flags().GetBool("no-headers")
+	templateFile, _ := cmd.Flags().GetString("template-file")
+
+	printer := ui.NewPrinter(cmd.OutOrStdout(), outputFormat, quiet)
+	printer.SetColumns(columns)
+	printer.SetNoHeaders(noHeaders)
+	printer.SetColor(ui.ColorEnabled(cmd.OutOrStdout()))
+	printer.SetWidth(ui.TerminalWidth(cmd.OutOrStdout()))
+	if templateFile != "" {
+		text, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template file: %w", err)
+		}
+		printer.SetTemplate(string(text))
+	}
+	if err := printer.Validate(); err != nil {
+		return nil, usageError(err)
+	}
+	return printer, nil
+}
+
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/export"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/lockedfile"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [FILE]",
+	Short: "Import tasks from a file into a task list",
+	Long: `Imports tasks from a file into a task list. Use '-' to read from stdin.
+Supported formats: ics (iCalendar VTODO), todotxt (todo.txt) and markdown
+(checklists). The format is derived from the file extension unless --format is
+given.
+
+Tasks are matched by their UID, so importing the same file again updates the
+tasks it created instead of creating duplicates. Markdown has no UIDs, so its
+tasks are always created, keeping the nesting of the checklist. todo.txt tasks
+go into the task list named by their first +project, which is created if it
+does not exist; tasks without a project go into --tasklist.
+
+With --sync-file, a todo.txt file and a task list are kept in step instead:
+new lines are created, edited lines update their task, removed lines delete
+their task, and the file is rewritten from the task list. When a task and
+its line both changed since the last sync, the task wins. The state of the
+last sync is kept in a hidden file next to the file; nothing is deleted on
+the first sync.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Get the flag values
+		format, _ := cmd.Flags().GetString("format")
+		tasklist, _ := cmd.Flags().GetString("tasklist")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		syncFile, _ := cmd.Flags().GetString("sync-file")
+		if syncFile != "" {
+			if len(args) > 0 {
+				return gtasks.NewError(gtasks.ErrInvalidArgument, "a file cannot be imported together with --sync-file")
+			}
+			return runSyncFile(cmd, h, syncFile, tasklist, dryRun)
+		}
+		if len(args) == 0 {
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "a file to import is required")
+		}
+		if format == "" {
+			format = strings.TrimPrefix(filepath.Ext(args[0]), ".")
+		}
+
+		// Read the file
+		var in io.Reader = cmd.InOrStdin()
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("error opening file: %w", err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		var items []export.Item
+		switch format {
+		case "ics", "ical":
+			items, err = export.ReadICS(in)
+		case "todotxt", "txt":
+			items, err = export.ReadTodoTxt(in)
+		case "markdown", "md":
+			items, err = export.ReadMarkdown(in)
+		default:
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "unsupported import format: %q (use --format)", format)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+
+		// Import the tasks
+		opts := gtasks.ImportOptions{TaskListID: tasklist, DryRun: dryRun}
+		result, err := gtasks.ImportTaskLists(cmd.Context(), h.Client, opts, items)
+		if err != nil {
+			return fmt.Errorf("error importing tasks: %w", err)
+		}
+
+		// Print a summary
+		if dryRun {
+			printImportActions(cmd, result.Actions)
+		}
+		summary := fmt.Sprintf("Imported %d task(s): %d created, %d updated, %d unchanged",
+			len(result.Actions), result.Created, result.Updated, result.Unchanged)
+		if result.TaskListsCreated > 0 {
+			summary += fmt.Sprintf(" (%d task list(s) created)", result.TaskListsCreated)
+		}
+		return h.Printer.PrintSuccess(summary)
+	},
+}
+
+// runSyncFile keeps a todo.txt file and a task list in step.
+func runSyncFile(cmd *cobra.Command, h *CommandHelper, file, tasklist string, dryRun bool) error {
+	// Read the file, if there is one yet, and the state of the last sync
+	var items []export.Item
+	f, err := os.Open(file)
+	switch {
+	case err == nil:
+		defer f.Close()
+		if items, err = export.ReadTodoTxt(f); err != nil {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	state, err := readSyncFileState(file)
+	if err != nil {
+		return fmt.Errorf("error reading sync state: %w", err)
+	}
+
+	// Sync the file with the task list
+	opts := gtasks.SyncFileOptions{TaskListID: tasklist, Items: items, State: state, DryRun: dryRun}
+	result, err := gtasks.SyncFile(cmd.Context(), h.Client, opts)
+	if err != nil {
+		return fmt.Errorf("error syncing file: %w", err)
+	}
+	if dryRun {
+		printImportActions(cmd, result.Actions)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Would delete %d task(s) and drop %d line(s)\n", result.Deleted, result.Dropped)
+		return nil
+	}
+
+	// Rewrite the file and then the state, which only matches the new file.
+	var buf bytes.Buffer
+	if err := export.WriteTodoTxt(&buf, result.Items); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err := lockedfile.Write(file, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err := writeSyncFileState(file, result.State); err != nil {
+		return fmt.Errorf("error writing sync state: %w", err)
+	}
+
+	return h.Printer.PrintSuccess(fmt.Sprintf("Synced %d task(s): %d created, %d updated, %d deleted, %d line(s) dropped",
+		len(result.Items), result.Created, result.Updated, result.Deleted, result.Dropped))
+}
+
+// syncFileStatePath returns the path of the file that holds the state of
+// the last sync of a file, a hidden file next to it.
+func syncFileStatePath(file string) string {
+	return filepath.Join(filepath.Dir(file), "."+filepath.Base(file)+".gtasks-sync")
+}
+
+// readSyncFileState reads the state of the last sync of a file. It returns
+// nil if the file was never synced.
+func readSyncFileState(file string) (*gtasks.SyncFileState, error) {
+	data, err := os.ReadFile(syncFileStatePath(file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state gtasks.SyncFileState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// writeSyncFileState saves the state of a sync of a file.
+func writeSyncFileState(file string, state *gtasks.SyncFileState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return lockedfile.Write(syncFileStatePath(file), data, 0644)
+}
+
+// printImportActions prints what a dry run would do.
+func printImportActions(cmd *cobra.Command, actions []gtasks.ImportAction) {
+	for _, action := range actions {
+		if action.Action != gtasks.ImportUnchanged {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Would %s task: %s\n", action.Action, action.Task.Title)
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().String("format", "", "The import format (ics, todotxt, markdown); derived from the file extension if empty")
+	importCmd.Flags().String("tasklist", "@default", "The ID of the task list to import into")
+	importCmd.Flags().Bool("dry-run", false, "Print what would be imported without changing anything")
+	importCmd.Flags().String("sync-file", "", "Keep this todo.txt file and the task list in step")
+}
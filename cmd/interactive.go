@@ -7,6 +7,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
 	"github.com/yanicksenn/gtasks/internal/tui"
 )
 
@@ -21,10 +22,10 @@ var interactiveCmd = &cobra.Command{
 
 		tasklist, _ := cmd.Flags().GetString("tasklist")
 		if tasklist == "" {
-			return fmt.Errorf("the --tasklist flag is required")
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "the --tasklist flag is required")
 		}
 
-		m, err := tui.New(h.Client, tasklist)
+		m, err := tui.New(cmd.Context(), h.Client, tasklist)
 		if err != nil {
 			return fmt.Errorf("error creating new model: %w", err)
 		}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/auth"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+)
+
+// allAccounts is the value of --accounts that selects every logged-in
+// account.
+const allAccounts = "all"
+
+// selectedAccounts returns the accounts given by the --accounts flag, or nil
+// if the flag is not set.
+func selectedAccounts(cmd *cobra.Command) ([]string, error) {
+	names, _ := cmd.Flags().GetStringSlice("accounts")
+	if !cmd.Flags().Changed("accounts") {
+		return nil, nil
+	}
+	if account, _ := cmd.Flags().GetString("account"); account != "" {
+		return nil, usageError(errors.New("--account and --accounts cannot be used together"))
+	}
+	if offline, _ := cmd.Flags().GetBool("offline"); offline {
+		return nil, usageError(errors.New("--accounts is not supported in offline mode, which has a single store"))
+	}
+
+	loggedIn, err := auth.ListAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("error listing accounts: %w", err)
+	}
+	slices.Sort(loggedIn)
+
+	var accounts []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == allAccounts:
+			accounts = append(accounts, loggedIn...)
+		case name == "":
+			continue
+		case !slices.Contains(loggedIn, name):
+			return nil, gtasks.NewError(gtasks.ErrNotFound, "account %s not found. Please log in first", name)
+		default:
+			accounts = append(accounts, name)
+		}
+	}
+
+	// Keep the first occurrence of each account.
+	seen := make(map[string]bool)
+	accounts = slices.DeleteFunc(accounts, func(account string) bool {
+		duplicate := seen[account]
+		seen[account] = true
+		return duplicate
+	})
+	if len(accounts) == 0 {
+		return nil, gtasks.NewError(gtasks.ErrNotFound, "no accounts selected. Please log in first")
+	}
+	return accounts, nil
+}
+
+// forEachAccount calls fn concurrently with a client for each account. The
+// clients are created one after another, because creating a client may
+// refresh and save the token of its account. The errors are joined and name
+// their account.
+func forEachAccount(cmd *cobra.Command, accounts []string, fn func(i int, client gtasks.Client) error) error {
+	endpoint, _ := cmd.Flags().GetString("api-endpoint")
+	clients := make([]gtasks.Client, len(accounts))
+	for i, account := range accounts {
+		client, err := gtasks.NewClient(cmd.Context(), gtasks.ClientOptions{APIEndpoint: endpoint, Account: account})
+		if err != nil {
+			return fmt.Errorf("account %s: %w", account, err)
+		}
+		clients[i] = client
+	}
+
+	errs := make([]error, len(accounts))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(i, client); err != nil {
+				errs[i] = fmt.Errorf("account %s: %w", accounts[i], err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
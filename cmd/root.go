@@ -1,6 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
 	"github.com/spf13/cobra"
 	"github.com/yanicksenn/gtasks/internal/version"
 )
@@ -9,6 +15,28 @@ var RootCmd = &cobra.Command{
 	Use:   "gtasks",
 	Short: "A CLI for managing your Google Tasks",
 	Long:  `gtasks is a powerful command-line interface that helps you manage your Google Tasks directly from the terminal.`,
+	// Errors are printed by PrintError.
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Validate the flags before cobra does, so that their errors are
+		// invalid arguments. Later errors are not caused by the usage.
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return usageError(err)
+		}
+		if err := cmd.ValidateFlagGroups(); err != nil {
+			return usageError(err)
+		}
+		cmd.SilenceUsage = true
+
+		// Bound the whole command, including all API calls, by the timeout.
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cancelTimeout = cancel
+			cmd.SetContext(ctx)
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		v, _ := cmd.Flags().GetBool("version")
 		if v {
@@ -23,13 +51,42 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// markUsageErrorsOnce marks the argument errors of all commands once they
+// are registered.
+var markUsageErrorsOnce sync.Once
+
+// cancelTimeout releases the resources of the --timeout context.
+var cancelTimeout context.CancelFunc
+
 func init() {
 	RootCmd.PersistentFlags().Bool("offline", false, "Enable offline mode")
 	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "Disable output")
-	RootCmd.PersistentFlags().StringP("output", "o", "table", "Output format (table, json, yaml)")
+	RootCmd.PersistentFlags().StringP("output", "o", "table", "Output format (table, json, yaml, markdown, csv, tsv, template=TEMPLATE, jsonpath=TEMPLATE)")
+	RootCmd.PersistentFlags().StringSlice("columns", nil, "Columns of the csv and tsv output (e.g. id,title,due,status,list)")
+	RootCmd.PersistentFlags().String("template-file", "", "Render the output with the Go template in this file")
+	RootCmd.PersistentFlags().Bool("no-headers", false, "Omit the header row of the csv and tsv output")
+	RootCmd.PersistentFlags().Duration("timeout", 0, "Abort the command if it takes longer than this duration (e.g. 30s, 2m; 0 for no timeout)")
+	RootCmd.PersistentFlags().String("account", "", "Act for this account instead of the active account, without switching to it")
+	RootCmd.PersistentFlags().String("api-endpoint", "", "Use another URL for the Google Tasks API, e.g. a local fake, without authentication")
+	RootCmd.PersistentFlags().MarkHidden("api-endpoint")
 	RootCmd.Flags().BoolP("version", "v", false, "Print the version number")
+	RootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError(err)
+	})
+	cobra.OnInitialize(func() {
+		markUsageErrorsOnce.Do(func() { markUsageErrors(RootCmd) })
+	})
 }
 
+// Execute runs the root command. The command is cancelled on an interrupt
+// or termination signal. Use ExitCode and PrintError to report the error.
 func Execute() error {
-	return RootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer func() {
+		if cancelTimeout != nil {
+			cancelTimeout()
+		}
+	}()
+	return RootCmd.ExecuteContext(ctx)
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/ui"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [TEXT...]",
+	Short: "Search tasks across all task lists",
+	Long: `Searches the tasks of all task lists. The text, if given, must appear in the
+title or the notes of a task. It can be combined with the filter flags.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
+		}
+
+		listOpts, filterOpts := getListTasksOptions(cmd)
+		if len(args) > 0 {
+			filterOpts.Query = textQuery(strings.Join(args, " "), filterOpts.Query)
+		}
+		workers, _ := cmd.Flags().GetInt("workers")
+
+		// Search the task lists
+		listed, err := searchTasks(cmd, h.Client, listOpts, filterOpts, workers)
+		if err != nil {
+			return fmt.Errorf("error searching tasks: %w", err)
+		}
+
+		return h.Printer.PrintListedTasks(listed)
+	},
+}
+
+// searchTasks searches all task lists and returns the matching tasks with
+// the task list they belong to, limited to listOpts.MaxResults.
+func searchTasks(cmd *cobra.Command, client gtasks.Client, listOpts gtasks.ListTasksOptions, filterOpts gtasks.FilterOptions, workers int) (*ui.ListedTasks, error) {
+	opts := gtasks.SearchTasksOptions{
+		ShowCompleted: listOpts.ShowCompleted,
+		ShowHidden:    listOpts.ShowHidden,
+		SortBy:        listOpts.SortBy,
+		Filter:        filterOpts,
+		Workers:       workers,
+	}
+	results, err := gtasks.SearchTasks(cmd.Context(), client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := &ui.ListedTasks{}
+	for _, result := range results {
+		for _, task := range result.Tasks {
+			listed.Items = append(listed.Items, ui.ListedTask{
+				Task:          task,
+				TaskListID:    result.TaskList.Id,
+				TaskListTitle: result.TaskList.Title,
+			})
+		}
+	}
+	if listOpts.MaxResults > 0 && int64(len(listed.Items)) > listOpts.MaxResults {
+		listed.Items = listed.Items[:listOpts.MaxResults]
+	}
+	return listed, nil
+}
+
+// textQuery returns a query that matches the text in the title or notes,
+// combined with the given query if there is one.
+func textQuery(text, query string) string {
+	quoted := `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+	if query == "" {
+		return quoted
+	}
+	return quoted + " AND (" + query + ")"
+}
+
+func init() {
+	RootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().Bool("show-completed", false, "Include completed tasks in the output")
+	searchCmd.Flags().Bool("show-hidden", false, "Include hidden tasks in the output")
+	searchCmd.Flags().String("title-contains", "", "Filter tasks by title (case-insensitive)")
+	searchCmd.Flags().String("notes-contains", "", "Filter tasks by notes (case-insensitive)")
+	searchCmd.Flags().String("due-before", "", "Filter tasks with a due date before the specified date (e.g., '2025-12-31' or 'next friday')")
+	searchCmd.Flags().String("due-after", "", "Filter tasks with a due date after the specified date (e.g., '2025-12-31' or 'today')")
+	searchCmd.Flags().String("query", "", `Filter tasks by a query (e.g., 'status:needsAction AND due<today+7d')`)
+	searchCmd.Flags().String("sort-by", "alphabetical", "Sort tasks within each task list by (alphabetical, last-modified, due-date, position)")
+	searchCmd.Flags().Int64("max-results", 0, "Maximum number of tasks to list (0 for no limit)")
+	searchCmd.Flags().Int("workers", gtasks.DefaultWorkers, "Number of task lists fetched concurrently")
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/gtasks"
+)
+
+func getConflictPolicy(cmd *cobra.Command) (gtasks.ConflictPolicy, error) {
+	onConflict, _ := cmd.Flags().GetString("on-conflict")
+	resolve, _ := cmd.Flags().GetStringToString("resolve")
+
+	policy := gtasks.ConflictPolicy{Fields: make(map[string]gtasks.Resolution)}
+	if onConflict != "" {
+		r, err := gtasks.ParseResolution(onConflict)
+		if err != nil {
+			return policy, err
+		}
+		policy.Default = r
+	}
+	for field, value := range resolve {
+		r, err := gtasks.ParseResolution(value)
+		if err != nil {
+			return policy, err
+		}
+		policy.Fields[field] = r
+	}
+	return policy, nil
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Synchronize the offline store with Google Tasks",
+	Long: `Replays the changes made in offline mode against Google Tasks and then pulls
+all task lists and tasks from Google Tasks into the offline store.
+
+Fields that were changed both offline and on the server are reported as
+conflicts unless a resolution is given with --on-conflict or --resolve.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		offline, _ := cmd.Flags().GetBool("offline")
+		if offline {
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "sync requires an online connection and cannot be used with --offline")
+		}
+
+		policy, err := getConflictPolicy(cmd)
+		if err != nil {
+			return err
+		}
+
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
+		}
+
+		local, err := gtasks.OpenOfflineStore()
+		if err != nil {
+			return fmt.Errorf("error opening offline store: %w", err)
+		}
+
+		result, err := gtasks.Sync(cmd.Context(), h.Client, local, gtasks.SyncOptions{Policy: policy})
+		if errors.Is(err, gtasks.ErrUnresolvedConflicts) {
+			for _, conflict := range result.Conflicts {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Conflict: %s\n", conflict)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("error syncing: %w", err)
+		}
+
+		return h.Printer.PrintSuccess(fmt.Sprintf(
+			"Successfully synced: %d created, %d updated, %d deleted on the server, %d conflicts resolved; pulled %d task lists and %d tasks.",
+			result.Created, result.Updated, result.Deleted, result.Resolved, result.PulledTaskLists, result.PulledTasks))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().String("on-conflict", "", "Resolve all conflicting fields (local, remote, merge)")
+	syncCmd.Flags().StringToString("resolve", nil, "Resolve conflicts per field, e.g. 'title=local,notes=merge,deleted=remote'")
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/ui"
+	"google.golang.org/api/tasks/v1"
 )
 
 var tasklistsCmd = &cobra.Command{
@@ -17,19 +19,47 @@ var listTasklistsCmd = &cobra.Command{
 	Use:	 "list",
 	Short:	 "List all your task lists",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		h, err := NewCommandHelper(cmd)
-		if err != nil {
-			return err
-		}
-
 		// Get the sort-by flag value
 		sortBy, _ := cmd.Flags().GetString("sort-by")
 		opts := gtasks.ListTaskListsOptions{
 			SortBy: sortBy,
 		}
 
+		// List the task lists of several accounts
+		accounts, err := selectedAccounts(cmd)
+		if err != nil {
+			return err
+		}
+		if accounts != nil {
+			printer, err := newPrinter(cmd)
+			if err != nil {
+				return err
+			}
+			results := make([]*tasks.TaskLists, len(accounts))
+			err = forEachAccount(cmd, accounts, func(i int, client gtasks.Client) error {
+				lists, err := client.ListTaskLists(cmd.Context(), opts)
+				results[i] = lists
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("error listing task lists: %w", err)
+			}
+			listed := &ui.ListedTaskLists{}
+			for i, lists := range results {
+				for _, list := range lists.Items {
+					listed.Items = append(listed.Items, ui.ListedTaskList{TaskList: list, Account: accounts[i]})
+				}
+			}
+			return printer.PrintListedTaskLists(listed)
+		}
+
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
+		}
+
 		// List the task lists
-		lists, err := h.Client.ListTaskLists(opts)
+		lists, err := h.Client.ListTaskLists(cmd.Context(), opts)
 		if err != nil {
 			return fmt.Errorf("error listing task lists: %w", err)
 		}
@@ -55,7 +85,7 @@ var getTasklistCmd = &cobra.Command{
 		}
 
 		// Get the task list
-		list, err := h.Client.GetTaskList(opts)
+		list, err := h.Client.GetTaskList(cmd.Context(), opts)
 		if err != nil {
 			return fmt.Errorf("error getting task list: %w", err)
 		}
@@ -81,7 +111,7 @@ var createTasklistCmd = &cobra.Command{
 		}
 
 		// Create the task list
-		createdList, err := h.Client.CreateTaskList(opts)
+		createdList, err := h.Client.CreateTaskList(cmd.Context(), opts)
 		if err != nil {
 			return fmt.Errorf("error creating task list: %w", err)
 		}
@@ -109,7 +139,7 @@ var updateTasklistCmd = &cobra.Command{
 		}
 
 		// Update the task list
-		updatedList, err := h.Client.UpdateTaskList(opts)
+		updatedList, err := h.Client.UpdateTaskList(cmd.Context(), opts)
 		if err != nil {
 			return fmt.Errorf("error updating task list: %w", err)
 		}
@@ -135,7 +165,7 @@ var deleteTasklistCmd = &cobra.Command{
 		}
 
 		// Delete the task list
-		err = h.Client.DeleteTaskList(opts)
+		err = h.Client.DeleteTaskList(cmd.Context(), opts)
 		if err != nil {
 			return fmt.Errorf("error deleting task list: %w", err)
 		}
@@ -154,6 +184,7 @@ func init() {
 	tasklistsCmd.AddCommand(deleteTasklistCmd)
 
 	listTasklistsCmd.Flags().String("sort-by", "alphabetical", "Sort task lists by (alphabetical, last-modified, uncompleted-tasks)")
+	listTasklistsCmd.Flags().StringSlice("accounts", nil, "List the task lists of these accounts concurrently, tagged with their account (comma-separated, or 'all')")
 
 	createTasklistCmd.Flags().String("title", "", "The title of the new task list")
 	createTasklistCmd.MarkFlagRequired("title")
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/yanicksenn/gtasks/internal/dates"
 	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/ui"
+	"google.golang.org/api/tasks/v1"
 )
 
 var tasksCmd = &cobra.Command{
@@ -21,13 +26,18 @@ func getListTasksOptions(cmd *cobra.Command) (gtasks.ListTasksOptions, gtasks.Fi
 	notesContains, _ := cmd.Flags().GetString("notes-contains")
 	dueBefore, _ := cmd.Flags().GetString("due-before")
 	dueAfter, _ := cmd.Flags().GetString("due-after")
+	query, _ := cmd.Flags().GetString("query")
 	sortBy, _ := cmd.Flags().GetString("sort-by")
+	maxResults, _ := cmd.Flags().GetInt64("max-results")
+	pageSize, _ := cmd.Flags().GetInt64("page-size")
 
 	listOpts := gtasks.ListTasksOptions{
 		TaskListID:    tasklist,
 		ShowCompleted: showCompleted,
 		ShowHidden:    showHidden,
 		SortBy:        sortBy,
+		MaxResults:    maxResults,
+		PageSize:      pageSize,
 	}
 
 	filterOpts := gtasks.FilterOptions{
@@ -35,6 +45,7 @@ func getListTasksOptions(cmd *cobra.Command) (gtasks.ListTasksOptions, gtasks.Fi
 		NotesContains: notesContains,
 		DueBefore:     dueBefore,
 		DueAfter:      dueAfter,
+		Query:         query,
 	}
 
 	return listOpts, filterOpts
@@ -44,28 +55,101 @@ var listTasksCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all tasks in a task list",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		listOpts, filterOpts := getListTasksOptions(cmd)
+		allLists, _ := cmd.Flags().GetBool("all-lists")
+		workers, _ := cmd.Flags().GetInt("workers")
+
+		// List the tasks of several accounts
+		accounts, err := selectedAccounts(cmd)
+		if err != nil {
+			return err
+		}
+		if accounts != nil {
+			printer, err := newPrinter(cmd)
+			if err != nil {
+				return err
+			}
+			results := make([]*ui.ListedTasks, len(accounts))
+			err = forEachAccount(cmd, accounts, func(i int, client gtasks.Client) error {
+				listed, err := listAccountTasks(cmd, client, listOpts, filterOpts, allLists, workers)
+				if err != nil {
+					return err
+				}
+				for j := range listed.Items {
+					listed.Items[j].Account = accounts[i]
+				}
+				results[i] = listed
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+			merged := &ui.ListedTasks{}
+			for _, listed := range results {
+				merged.Items = append(merged.Items, listed.Items...)
+			}
+			return printer.PrintListedTasks(merged)
+		}
+
 		h, err := NewCommandHelper(cmd)
 		if err != nil {
 			return err
 		}
 
-		listOpts, filterOpts := getListTasksOptions(cmd)
+		// List the tasks of all task lists
+		if allLists {
+			listed, err := searchTasks(cmd, h.Client, listOpts, filterOpts, workers)
+			if err != nil {
+				return fmt.Errorf("error listing tasks: %w", err)
+			}
+			return h.Printer.PrintListedTasks(listed)
+		}
 
-		tasks, err := h.Client.ListTasks(listOpts)
+		tasks, err := gtasks.ListFilteredTasks(cmd.Context(), h.Client, listOpts, filterOpts)
 		if err != nil {
 			return fmt.Errorf("error listing tasks: %w", err)
 		}
 
-		filteredTasks, err := gtasks.FilterTasks(tasks.Items, filterOpts)
-		if err != nil {
-			return fmt.Errorf("error filtering tasks: %w", err)
+		// Rows of columns can name the task list of the tasks
+		if h.Printer.Tabular() {
+			listed, err := withTaskList(cmd, h.Client, listOpts.TaskListID, tasks)
+			if err != nil {
+				return err
+			}
+			return h.Printer.PrintListedTasks(listed)
 		}
-		tasks.Items = filteredTasks
 
 		return h.Printer.PrintTasks(tasks)
 	},
 }
 
+// listAccountTasks lists the tasks of one account with the task list they
+// belong to, from one task list or from all of them.
+func listAccountTasks(cmd *cobra.Command, client gtasks.Client, listOpts gtasks.ListTasksOptions, filterOpts gtasks.FilterOptions, allLists bool, workers int) (*ui.ListedTasks, error) {
+	if allLists {
+		return searchTasks(cmd, client, listOpts, filterOpts, workers)
+	}
+	tasks, err := gtasks.ListFilteredTasks(cmd.Context(), client, listOpts, filterOpts)
+	if err != nil {
+		return nil, err
+	}
+	return withTaskList(cmd, client, listOpts.TaskListID, tasks)
+}
+
+// withTaskList returns the tasks of a task list together with the task
+// list.
+func withTaskList(cmd *cobra.Command, client gtasks.Client, taskListID string, items *tasks.Tasks) (*ui.ListedTasks, error) {
+	list, err := client.GetTaskList(cmd.Context(), gtasks.GetTaskListOptions{TaskListID: taskListID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting task list: %w", err)
+	}
+	listed := &ui.ListedTasks{}
+	for _, task := range items.Items {
+		listed.Items = append(listed.Items, ui.ListedTask{Task: task, TaskListID: list.Id, TaskListTitle: list.Title})
+	}
+	return listed, nil
+}
+
 var getTaskCmd = &cobra.Command{
 	Use:   "get [ID]",
 	Short: "Get details for a specific task",
@@ -84,7 +168,7 @@ var getTaskCmd = &cobra.Command{
 		}
 
 		// Get the task
-		task, err := h.Client.GetTask(opts)
+		task, err := h.Client.GetTask(cmd.Context(), opts)
 		if err != nil {
 			return fmt.Errorf("error getting task: %w", err)
 		}
@@ -108,16 +192,26 @@ var createTaskCmd = &cobra.Command{
 		title, _ := cmd.Flags().GetString("title")
 		notes, _ := cmd.Flags().GetString("notes")
 		due, _ := cmd.Flags().GetString("due")
+		parent, _ := cmd.Flags().GetString("parent")
+		previous, _ := cmd.Flags().GetString("previous")
+
+		// Normalise the due date
+		due, err = dates.ParseDue(due, time.Now())
+		if err != nil {
+			return fmt.Errorf("error parsing due date: %w", err)
+		}
 
 		opts := gtasks.CreateTaskOptions{
 			TaskListID: tasklist,
 			Title:      title,
 			Notes:      notes,
 			Due:        due,
+			Parent:     parent,
+			Previous:   previous,
 		}
 
 		// Create the task
-		createdTask, err := h.Client.CreateTask(opts)
+		createdTask, err := h.Client.CreateTask(cmd.Context(), opts)
 		if err != nil {
 			return fmt.Errorf("error creating task: %w", err)
 		}
@@ -128,125 +222,214 @@ var createTaskCmd = &cobra.Command{
 }
 
 var updateTaskCmd = &cobra.Command{
-	Use:   "update [ID]",
-	Short: "Update a task",
-	Args:  cobra.ExactArgs(1),
+	Use:   "update [ID...]",
+	Short: "Update one or more tasks",
+	Long: `Updates the tasks given by ID, by '-' to read IDs from stdin, or selected
+with the filter flags. Only the given fields are changed. Updating more than
+one task asks for confirmation unless --yes is given.`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		h, err := NewCommandHelper(cmd)
-		if err != nil {
-			return err
-		}
-
 		// Get the flag values
-		tasklist, _ := cmd.Flags().GetString("tasklist")
 		title, _ := cmd.Flags().GetString("title")
 		notes, _ := cmd.Flags().GetString("notes")
 		due, _ := cmd.Flags().GetString("due")
+		if title == "" && notes == "" && due == "" {
+			return gtasks.NewError(gtasks.ErrInvalidArgument, "at least one of --title, --notes or --due is required")
+		}
 
-		opts := gtasks.UpdateTaskOptions{
-			TaskListID: tasklist,
-			TaskID:     args[0],
-			Title:      title,
-			Notes:      notes,
-			Due:        due,
+		h, err := NewCommandHelper(cmd)
+		if err != nil {
+			return err
 		}
 
-		// Update the task
-		updatedTask, err := h.Client.UpdateTask(opts)
+		// Normalise the due date
+		due, err = dates.ParseDue(due, time.Now())
 		if err != nil {
-			return fmt.Errorf("error updating task: %w", err)
+			return fmt.Errorf("error parsing due date: %w", err)
 		}
 
-		// Print a success message
-		return h.Printer.PrintSuccess(fmt.Sprintf("Successfully updated task: %s (%s)", updatedTask.Title, updatedTask.Id))
+		// Select the tasks
+		selection, err := selectTasks(cmd, h, args, false)
+		if err != nil {
+			return err
+		}
+		if dryRun, err := printDryRun(cmd, h, selection, "update"); dryRun {
+			return err
+		}
+		if len(selection.Tasks) > 0 {
+			confirmed, err := confirmBulk(cmd, selection, "update")
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return fmt.Errorf("aborted")
+			}
+		}
+
+		// Update the tasks
+		return runBulk(cmd, selection, "updating", func(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+			opts := gtasks.UpdateTaskOptions{
+				TaskListID: selection.TaskListID,
+				TaskID:     task.Id,
+				Title:      title,
+				Notes:      notes,
+				Due:        due,
+			}
+			return h.Client.UpdateTask(ctx, opts)
+		}, func(task *tasks.Task) error {
+			return h.Printer.PrintSuccess(fmt.Sprintf("Successfully updated task: %s (%s)", task.Title, task.Id))
+		})
 	},
 }
 
 var completeTaskCmd = &cobra.Command{
-	Use:   "complete [ID]",
-	Short: "Mark a task as complete",
-	Args:  cobra.ExactArgs(1),
+	Use:   "complete [ID...]",
+	Short: "Mark one or more tasks as complete",
+	Long: `Completes the tasks given by ID, by '-' to read IDs from stdin, or selected
+with the filter flags.`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		h, err := NewCommandHelper(cmd)
 		if err != nil {
 			return err
 		}
 
-		// Get the tasklist ID from the flags
-		tasklist, _ := cmd.Flags().GetString("tasklist")
-		opts := gtasks.CompleteTaskOptions{
-			TaskListID: tasklist,
-			TaskID:     args[0],
+		// Select the tasks
+		selection, err := selectTasks(cmd, h, args, false)
+		if err != nil {
+			return err
 		}
+		if dryRun, err := printDryRun(cmd, h, selection, "complete"); dryRun {
+			return err
+		}
+
+		// Complete the tasks
+		return runBulk(cmd, selection, "completing", func(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+			opts := gtasks.CompleteTaskOptions{
+				TaskListID: selection.TaskListID,
+				TaskID:     task.Id,
+			}
+			return h.Client.CompleteTask(ctx, opts)
+		}, func(task *tasks.Task) error {
+			return h.Printer.PrintSuccess(fmt.Sprintf("Successfully completed task: %s (%s)", task.Title, task.Id))
+		})
+	},
+}
 
-		// Complete the task
-		completedTask, err := h.Client.CompleteTask(opts)
+var uncompleteTaskCmd = &cobra.Command{
+	Use:   "uncomplete [ID...]",
+	Short: "Mark one or more tasks as not complete",
+	Long: `Uncompletes the tasks given by ID, by '-' to read IDs from stdin, or selected
+with the filter flags. Completed and hidden tasks are always selected.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, err := NewCommandHelper(cmd)
 		if err != nil {
-			return fmt.Errorf("error completing task: %w", err)
+			return err
 		}
 
-		// Print a success message
-		return h.Printer.PrintSuccess(fmt.Sprintf("Successfully completed task: %s (%s)", completedTask.Title, completedTask.Id))
+		// Select the tasks
+		selection, err := selectTasks(cmd, h, args, true)
+		if err != nil {
+			return err
+		}
+		if dryRun, err := printDryRun(cmd, h, selection, "uncomplete"); dryRun {
+			return err
+		}
+
+		// Uncomplete the tasks
+		return runBulk(cmd, selection, "uncompleting", func(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+			opts := gtasks.UncompleteTaskOptions{
+				TaskListID: selection.TaskListID,
+				TaskID:     task.Id,
+			}
+			return h.Client.UncompleteTask(ctx, opts)
+		}, func(task *tasks.Task) error {
+			return h.Printer.PrintSuccess(fmt.Sprintf("Successfully uncompleted task: %s (%s)", task.Title, task.Id))
+		})
 	},
 }
 
-var uncompleteTaskCmd = &cobra.Command{
-	Use:   "uncomplete [ID]",
-	Short: "Mark a task as not complete",
-	Args:  cobra.ExactArgs(1),
+var moveTaskCmd = &cobra.Command{
+	Use:   "move [ID]",
+	Short: "Move a task to another parent or position",
+	Long: `Moves a task within its task list. Without --parent, the task is moved to
+the top level. Without --previous, the task is moved to the first position
+among its new siblings.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		h, err := NewCommandHelper(cmd)
 		if err != nil {
 			return err
 		}
 
-		// Get the tasklist ID from the flags
+		// Get the flag values
 		tasklist, _ := cmd.Flags().GetString("tasklist")
-		opts := gtasks.UncompleteTaskOptions{
+		parent, _ := cmd.Flags().GetString("parent")
+		previous, _ := cmd.Flags().GetString("previous")
+
+		opts := gtasks.MoveTaskOptions{
 			TaskListID: tasklist,
 			TaskID:     args[0],
+			Parent:     parent,
+			Previous:   previous,
 		}
 
-		// Uncomplete the task
-		uncompletedTask, err := h.Client.UncompleteTask(opts)
+		// Move the task
+		movedTask, err := h.Client.MoveTask(cmd.Context(), opts)
 		if err != nil {
-			return fmt.Errorf("error uncompleting task: %w", err)
+			return fmt.Errorf("error moving task: %w", err)
 		}
 
 		// Print a success message
-		return h.Printer.PrintSuccess(fmt.Sprintf("Successfully uncompleted task: %s (%s)", uncompletedTask.Title, uncompletedTask.Id))
+		return h.Printer.PrintSuccess(fmt.Sprintf("Successfully moved task: %s (%s)", movedTask.Title, movedTask.Id))
 	},
 }
 
 var deleteTaskCmd = &cobra.Command{
-	Use:   "delete [ID]",
-	Short: "Delete a task",
-	Args:  cobra.ExactArgs(1),
+	Use:   "delete [ID...]",
+	Short: "Delete one or more tasks",
+	Long: `Deletes the tasks given by ID, by '-' to read IDs from stdin, or selected
+with the filter flags. Deleting more than one task asks for confirmation
+unless --yes is given.`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		h, err := NewCommandHelper(cmd)
 		if err != nil {
 			return err
 		}
 
-		// Get the tasklist ID from the flags
-		tasklist, _ := cmd.Flags().GetString("tasklist")
-		opts := gtasks.DeleteTaskOptions{
-			TaskListID: tasklist,
-			TaskID:     args[0],
+		// Select the tasks
+		selection, err := selectTasks(cmd, h, args, false)
+		if err != nil {
+			return err
 		}
-
-		// Delete the task
-		if err := h.Client.DeleteTask(opts); err != nil {
-			return fmt.Errorf("error deleting task: %w", err)
+		if dryRun, err := printDryRun(cmd, h, selection, "delete"); dryRun {
+			return err
+		}
+		if len(selection.Tasks) > 0 {
+			confirmed, err := confirmBulk(cmd, selection, "delete")
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return fmt.Errorf("aborted")
+			}
 		}
 
-		// Print a success message
-		return h.Printer.PrintDelete("task", args[0])
+		// Delete the tasks
+		return runBulk(cmd, selection, "deleting", func(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+			opts := gtasks.DeleteTaskOptions{
+				TaskListID: selection.TaskListID,
+				TaskID:     task.Id,
+			}
+			return nil, h.Client.DeleteTask(ctx, opts)
+		}, func(task *tasks.Task) error {
+			return h.Printer.PrintDelete("task", task.Id)
+		})
 	},
 }
 
-
-
 func init() {
 	RootCmd.AddCommand(tasksCmd)
 	tasksCmd.AddCommand(listTasksCmd)
@@ -255,6 +438,7 @@ func init() {
 	tasksCmd.AddCommand(updateTaskCmd)
 	tasksCmd.AddCommand(completeTaskCmd)
 	tasksCmd.AddCommand(uncompleteTaskCmd)
+	tasksCmd.AddCommand(moveTaskCmd)
 	tasksCmd.AddCommand(deleteTaskCmd)
 
 	listTasksCmd.Flags().String("tasklist", "@default", "The ID of the task list")
@@ -262,9 +446,16 @@ func init() {
 	listTasksCmd.Flags().Bool("show-hidden", false, "Include hidden tasks in the output")
 	listTasksCmd.Flags().String("title-contains", "", "Filter tasks by title (case-insensitive)")
 	listTasksCmd.Flags().String("notes-contains", "", "Filter tasks by notes (case-insensitive)")
-	listTasksCmd.Flags().String("due-before", "", "Filter tasks with a due date before the specified date (e.g., '2025-12-31')")
-	listTasksCmd.Flags().String("due-after", "", "Filter tasks with a due date after the specified date (e.g., '2025-12-31')")
-	listTasksCmd.Flags().String("sort-by", "alphabetical", "Sort tasks by (alphabetical, last-modified, due-date)")
+	listTasksCmd.Flags().String("due-before", "", "Filter tasks with a due date before the specified date (e.g., '2025-12-31' or 'next friday')")
+	listTasksCmd.Flags().String("due-after", "", "Filter tasks with a due date after the specified date (e.g., '2025-12-31' or 'today')")
+	listTasksCmd.Flags().String("query", "", `Filter tasks by a query (e.g., 'status:needsAction AND due<today+7d')`)
+	listTasksCmd.Flags().String("sort-by", "alphabetical", "Sort tasks by (alphabetical, last-modified, due-date, position)")
+	listTasksCmd.Flags().Int64("max-results", 0, "Maximum number of tasks to list (0 for no limit)")
+	listTasksCmd.Flags().Bool("all-lists", false, "List the tasks of all task lists")
+	listTasksCmd.Flags().Int("workers", gtasks.DefaultWorkers, "Number of task lists fetched concurrently with --all-lists")
+	listTasksCmd.Flags().StringSlice("accounts", nil, "List the tasks of these accounts concurrently, tagged with their account (comma-separated, or 'all')")
+	listTasksCmd.MarkFlagsMutuallyExclusive("tasklist", "all-lists")
+	listTasksCmd.Flags().Int64("page-size", 0, "Number of tasks to fetch per API request (at most 100, 0 for the API default)")
 
 	getTaskCmd.Flags().String("tasklist", "@default", "The ID of the task list")
 
@@ -272,16 +463,24 @@ func init() {
 	createTaskCmd.Flags().String("title", "", "The title of the new task")
 	createTaskCmd.MarkFlagRequired("title")
 	createTaskCmd.Flags().String("notes", "", "The notes for the new task")
-	createTaskCmd.Flags().String("due", "", "The due date for the new task (RFC3339 format)")
+	createTaskCmd.Flags().String("due", "", "The due date for the new task (e.g., '2026-11-01', 'tomorrow', 'next friday', '+3d' or 'in 2 weeks')")
+	createTaskCmd.Flags().String("parent", "", "The ID of the parent task to create a subtask")
+	createTaskCmd.Flags().String("previous", "", "The ID of the sibling task after which to create the task")
 
-	updateTaskCmd.Flags().String("tasklist", "@default", "The ID of the task list")
+	addSelectionFlags(updateTaskCmd)
 	updateTaskCmd.Flags().String("title", "", "The new title for the task")
 	updateTaskCmd.Flags().String("notes", "", "The new notes for the task")
-	updateTaskCmd.Flags().String("due", "", "The new due date for the task (RFC3339 format)")
+	updateTaskCmd.Flags().String("due", "", "The new due date for the task (e.g., '2026-11-01', 'tomorrow', 'next friday', '+3d' or 'in 2 weeks')")
+	updateTaskCmd.Flags().BoolP("yes", "y", false, "Update without asking for confirmation")
+
+	addSelectionFlags(completeTaskCmd)
 
-	completeTaskCmd.Flags().String("tasklist", "@default", "The ID of the task list")
+	addSelectionFlags(uncompleteTaskCmd)
 
-	uncompleteTaskCmd.Flags().String("tasklist", "@default", "The ID of the task list")
+	moveTaskCmd.Flags().String("tasklist", "@default", "The ID of the task list")
+	moveTaskCmd.Flags().String("parent", "", "The ID of the new parent task (top level if empty)")
+	moveTaskCmd.Flags().String("previous", "", "The ID of the new previous sibling (first position if empty)")
 
-	deleteTaskCmd.Flags().String("tasklist", "@default", "The ID of the task list")
+	addSelectionFlags(deleteTaskCmd)
+	deleteTaskCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
 }
\ No newline at end of file
@@ -4,12 +4,19 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/spf13/pflag"
 	"github.com/yanicksenn/gtasks/cmd"
+	"github.com/yanicksenn/gtasks/internal/fakeapi"
+	"github.com/yanicksenn/gtasks/internal/store"
 	"github.com/yanicksenn/gtasks/internal/version"
+	"google.golang.org/api/tasks/v1"
 )
 
 func execute(args ...string) (string, error) {
@@ -27,9 +34,28 @@ func login() error {
 }
 
 func TestMain(m *testing.M) {
+	// Run against a local fake of the Tasks API, with a temporary home
+	// directory for the configuration and the offline store.
+	home, err := os.MkdirTemp("", "gtasks-e2e")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: failed to create home directory: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("HOME", home)
+
+	st, err := store.NewInMemoryStore("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: failed to create store: %v\n", err)
+		os.Exit(1)
+	}
+	st.CreateTaskList(&tasks.TaskList{Title: "My Tasks"})
+	server := httptest.NewServer(fakeapi.New(st))
+	cmd.RootCmd.PersistentFlags().Set("api-endpoint", server.URL)
 
 	exitVal := m.Run()
 
+	server.Close()
+	os.RemoveAll(home)
 	os.Exit(exitVal)
 }
 
@@ -273,3 +299,291 @@ func TestOutputFlag(t *testing.T) {
 		t.Errorf(`expected json output to contain '"id":"%s"', got '%s'`, listID, output)
 	}
 }
+
+func TestErrors(t *testing.T) {
+	t.Cleanup(func() { cmd.RootCmd.PersistentFlags().Set("output", "table") })
+
+	// A missing task exits with the not found code
+	output, err := execute("tasks", "get", "missing", "--output", "json")
+	if err == nil {
+		t.Fatalf("expected an error for a missing task\nOutput: %s", output)
+	}
+	if code := cmd.ExitCode(err); code != cmd.ExitNotFound {
+		t.Errorf("expected exit code %d, got %d", cmd.ExitNotFound, code)
+	}
+
+	// With --output json, the error is printed as a JSON object
+	buf := new(bytes.Buffer)
+	cmd.PrintError(buf, err)
+	var printed struct {
+		Error struct {
+			Code     string `json:"code"`
+			Message  string `json:"message"`
+			ExitCode int    `json:"exitCode"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &printed); err != nil {
+		t.Fatalf("failed to unmarshal json: %v\nOutput: %s", err, buf)
+	}
+	if printed.Error.Code != "not_found" || printed.Error.ExitCode != cmd.ExitNotFound || printed.Error.Message == "" {
+		t.Errorf("unexpected error object: %+v", printed.Error)
+	}
+
+	// A missing argument exits with the invalid argument code
+	_, err = execute("tasks", "get", "--output", "table")
+	if code := cmd.ExitCode(err); code != cmd.ExitInvalidArgument {
+		t.Errorf("expected exit code %d, got %d (%v)", cmd.ExitInvalidArgument, code, err)
+	}
+
+	// So do an unknown output format and a template for another format
+	for _, format := range []string{"jsn", "json=x"} {
+		output, err = execute("tasklists", "list", "--output", format)
+		if code := cmd.ExitCode(err); code != cmd.ExitInvalidArgument {
+			t.Errorf("expected exit code %d for %q, got %d (%v)\nOutput: %s", cmd.ExitInvalidArgument, format, code, err, output)
+		}
+	}
+}
+
+func TestExportWithoutTaskLists(t *testing.T) {
+	// An empty offline store has no task lists to export
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() {
+		cmd.RootCmd.PersistentFlags().Set("offline", "false")
+		exportCmd, _, _ := cmd.RootCmd.Find([]string{"export"})
+		exportCmd.Flags().Set("all-lists", "false")
+		exportCmd.Flags().Set("format", "ics")
+	})
+
+	_, err := execute("--offline", "export", "--all-lists", "--format", "todotxt")
+	if code := cmd.ExitCode(err); code != cmd.ExitNotFound {
+		t.Errorf("expected exit code %d, got %d (%v)", cmd.ExitNotFound, code, err)
+	}
+}
+
+func TestMultipleAccounts(t *testing.T) {
+	// Log in two accounts. The fake API serves the same data to both.
+	path := filepath.Join(os.Getenv("HOME"), ".config", "gtasks-token.json")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	tokens := `{"tokens":{"b@example.com":{"refresh_token":"b"},"a@example.com":{"refresh_token":"a"}}}`
+	if err := os.WriteFile(path, []byte(tokens), 0600); err != nil {
+		t.Fatalf("failed to write tokens: %v", err)
+	}
+	t.Cleanup(func() {
+		os.Remove(path)
+		cmd.RootCmd.PersistentFlags().Set("output", "table")
+		cmd.RootCmd.PersistentFlags().Set("account", "")
+		for _, args := range [][]string{{"tasks", "list"}, {"tasklists", "list"}} {
+			c, _, _ := cmd.RootCmd.Find(args)
+			c.Flags().Lookup("accounts").Value.(pflag.SliceValue).Replace(nil)
+			c.Flags().Lookup("accounts").Changed = false
+		}
+	})
+
+	// The task lists of all accounts are tagged with their account
+	output, err := execute("tasklists", "list", "--accounts", "all", "--output", "json")
+	if err != nil {
+		t.Fatalf("failed to list task lists: %v\nOutput: %s", err, output)
+	}
+	var lists struct {
+		Items []struct {
+			Title   string `json:"title"`
+			Account string `json:"account"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &lists); err != nil {
+		t.Fatalf("failed to unmarshal json: %v\nOutput: %s", err, output)
+	}
+	if len(lists.Items) < 2 || len(lists.Items)%2 != 0 {
+		t.Fatalf("expected the task lists of both accounts, got %+v", lists.Items)
+	}
+	half := len(lists.Items) / 2
+	for i, list := range lists.Items {
+		expected := "a@example.com"
+		if i >= half {
+			expected = "b@example.com"
+		}
+		if list.Account != expected {
+			t.Errorf("expected task list %d to belong to %s, got %+v", i, expected, list)
+		}
+	}
+
+	// The tasks of the selected accounts name their account in a column
+	output, err = execute("tasklists", "create", "--title", "Shared List", "--output", "json")
+	if err != nil {
+		t.Fatalf("failed to create task list: %v\nOutput: %s", err, output)
+	}
+	var list tasks.TaskList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		t.Fatalf("failed to unmarshal json: %v\nOutput: %s", err, output)
+	}
+	output, err = execute("tasks", "create", "--tasklist", list.Id, "--title", "Shared Task", "--output", "json")
+	if err != nil {
+		t.Fatalf("failed to create task: %v\nOutput: %s", err, output)
+	}
+	output, err = execute("tasks", "list", "--tasklist", list.Id, "--accounts", "b@example.com,a@example.com", "--output", "csv", "--title-contains", "Shared")
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v\nOutput: %s", err, output)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], ",account") || !strings.HasSuffix(lines[1], ",b@example.com") || !strings.HasSuffix(lines[2], ",a@example.com") {
+		t.Errorf("expected a row for each account, got\n%s", output)
+	}
+
+	// An account that is not logged in is not found
+	_, err = execute("tasks", "list", "--accounts", "c@example.com", "--output", "table")
+	if code := cmd.ExitCode(err); code != cmd.ExitNotFound {
+		t.Errorf("expected exit code %d, got %d (%v)", cmd.ExitNotFound, code, err)
+	}
+
+	// --account selects a single account
+	_, err = execute("tasks", "list", "--accounts", "all", "--account", "a@example.com")
+	if code := cmd.ExitCode(err); code != cmd.ExitInvalidArgument {
+		t.Errorf("expected exit code %d, got %d (%v)", cmd.ExitInvalidArgument, code, err)
+	}
+}
+
+func TestAccountStatusAndRevocation(t *testing.T) {
+	// Fake token and revocation endpoints, named by a custom OAuth client
+	var revoked []string
+	revokeFails := true
+	oauthServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"new","token_type":"Bearer","expires_in":3600}`)
+			return
+		}
+		if revokeFails && r.Form.Get("token") == "refresh-a" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		revoked = append(revoked, r.Form.Get("token"))
+	}))
+	defer oauthServer.Close()
+	home := os.Getenv("HOME")
+	clientFile := filepath.Join(home, "client.json")
+	client := fmt.Sprintf(`{"installed":{"client_id":"e2e-client","client_secret":"secret","auth_uri":"%[1]s/auth","token_uri":"%[1]s/token","revoke_uri":"%[1]s/revoke","redirect_uris":["http://localhost"]}}`, oauthServer.URL)
+	os.WriteFile(clientFile, []byte(client), 0600)
+	t.Setenv("GTASKS_OAUTH_CLIENT_FILE", clientFile)
+
+	// Log in two accounts, the first one active
+	tokenFile := filepath.Join(home, ".config", "gtasks-token.json")
+	configFile := filepath.Join(home, ".config", "gtasks.yml")
+	os.MkdirAll(filepath.Dir(tokenFile), 0755)
+	tokens := `{"tokens":{
+		"a@example.com":{"access_token":"a","refresh_token":"refresh-a","expiry":"2026-10-17T13:00:00Z","client_id":"e2e-client"},
+		"b@example.com":{"access_token":"b","refresh_token":"refresh-b","client_id":"e2e-client","scopes":["https://www.googleapis.com/auth/tasks.readonly"]}}}`
+	os.WriteFile(tokenFile, []byte(tokens), 0600)
+	os.WriteFile(configFile, []byte("active_account: a@example.com\n"), 0644)
+	t.Cleanup(func() {
+		os.Remove(tokenFile)
+		os.Remove(configFile)
+		cmd.RootCmd.PersistentFlags().Set("output", "table")
+		cmd.RootCmd.PersistentFlags().Set("offline", "false")
+		statusCmd, _, _ := cmd.RootCmd.Find([]string{"accounts", "status"})
+		statusCmd.Flags().Set("check-refresh", "false")
+		for _, args := range [][]string{{"accounts", "logout"}, {"accounts", "remove"}} {
+			c, _, _ := cmd.RootCmd.Find(args)
+			c.Flags().Set("revoke", "false")
+		}
+	})
+
+	// The status shows the stored tokens without refreshing them
+	output, err := execute("accounts", "status", "--output", "json")
+	if err != nil {
+		t.Fatalf("failed to get the status: %v\nOutput: %s", err, output)
+	}
+	var statuses struct {
+		Items []struct {
+			Account string   `json:"account"`
+			Active  bool     `json:"active"`
+			Scopes  []string `json:"scopes"`
+			Expiry  string   `json:"expiry"`
+			Refresh string   `json:"refresh"`
+			Error   string   `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &statuses); err != nil {
+		t.Fatalf("failed to unmarshal json: %v\nOutput: %s", err, output)
+	}
+	if len(statuses.Items) != 2 {
+		t.Fatalf("expected 2 accounts, got %+v", statuses.Items)
+	}
+	a, b := statuses.Items[0], statuses.Items[1]
+	if a.Account != "a@example.com" || !a.Active || a.Expiry != "2026-10-17T13:00:00Z" || a.Refresh != "unchecked" {
+		t.Errorf("unexpected status of the active account: %+v", a)
+	}
+	// The token of b has other scopes than the configured ones
+	if b.Active || b.Refresh != "failed" || !strings.Contains(b.Error, "tasks.readonly") {
+		t.Errorf("unexpected status of the other account: %+v", b)
+	}
+
+	// --check-refresh refreshes the tokens, which needs the network
+	_, err = execute("accounts", "status", "--check-refresh", "--offline")
+	if code := cmd.ExitCode(err); code != cmd.ExitInvalidArgument {
+		t.Errorf("expected exit code %d, got %d (%v)", cmd.ExitInvalidArgument, code, err)
+	}
+	output, err = execute("accounts", "status", "--check-refresh", "--offline=false", "--output", "json")
+	if err != nil {
+		t.Fatalf("failed to check the tokens: %v\nOutput: %s", err, output)
+	}
+	if err := json.Unmarshal([]byte(output), &statuses); err != nil {
+		t.Fatalf("failed to unmarshal json: %v\nOutput: %s", err, output)
+	}
+	if a := statuses.Items[0]; a.Refresh != "ok" || a.Expiry == "2026-10-17T13:00:00Z" {
+		t.Errorf("expected the token of a to be refreshed, got %+v", a)
+	}
+
+	// The active account cannot be removed
+	_, err = execute("accounts", "remove", "a@example.com", "--output", "table")
+	if code := cmd.ExitCode(err); code != cmd.ExitInvalidArgument {
+		t.Errorf("expected exit code %d, got %d (%v)", cmd.ExitInvalidArgument, code, err)
+	}
+
+	// Removing another account revokes its token
+	output, err = execute("accounts", "remove", "b@example.com", "--revoke")
+	if err != nil {
+		t.Fatalf("failed to remove the account: %v\nOutput: %s", err, output)
+	}
+	if len(revoked) != 1 || revoked[0] != "refresh-b" {
+		t.Errorf("expected the token of b to be revoked, got %v", revoked)
+	}
+
+	// Logging out of all accounts revokes the remaining tokens, even if one
+	// of them cannot be revoked
+	var cache map[string]map[string]json.RawMessage
+	data, _ := os.ReadFile(tokenFile)
+	if err := json.Unmarshal(data, &cache); err != nil {
+		t.Fatalf("failed to read the tokens: %v", err)
+	}
+	cache["tokens"]["c@example.com"] = json.RawMessage(`{"access_token":"c","refresh_token":"refresh-c","client_id":"e2e-client"}`)
+	data, _ = json.Marshal(cache)
+	os.WriteFile(tokenFile, data, 0600)
+	output, err = execute("accounts", "logout", "--all")
+	if err == nil || !strings.Contains(err.Error(), "a@example.com") {
+		t.Errorf("expected the logout of a to fail, got %v\nOutput: %s", err, output)
+	}
+	if len(revoked) != 2 || revoked[1] != "refresh-c" {
+		t.Errorf("expected the token of c to be revoked, got %v", revoked)
+	}
+	output, err = execute("accounts", "status", "--check-refresh=false", "--quiet=false", "--output", "json")
+	if err := json.Unmarshal([]byte(output), &statuses); err != nil {
+		t.Fatalf("failed to unmarshal json: %v\nOutput: %s", err, output)
+	}
+	if len(statuses.Items) != 1 || statuses.Items[0].Account != "a@example.com" || !statuses.Items[0].Active {
+		t.Errorf("expected only a to stay logged in, got %+v", statuses.Items)
+	}
+
+	// Once its token can be revoked, the active account is cleared
+	revokeFails = false
+	output, err = execute("accounts", "logout", "--all")
+	if err != nil {
+		t.Fatalf("failed to log out: %v\nOutput: %s", err, output)
+	}
+	if len(revoked) != 3 || revoked[2] != "refresh-a" {
+		t.Errorf("expected the token of a to be revoked, got %v", revoked)
+	}
+	if data, _ := os.ReadFile(configFile); strings.Contains(string(data), "a@example.com") {
+		t.Errorf("expected the active account to be cleared, got %s", data)
+	}
+}
@@ -4,31 +4,28 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
+	"github.com/yanicksenn/gtasks/internal/config"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 // ErrCredentialsNotFound is returned when the user's credentials are not found.
 var ErrCredentialsNotFound = errors.New("credentials not found. Please run 'gtasks login'")
 var ErrTokenRefreshFailed = errors.New("token refresh failed")
 
-
-// getOAuthConfig returns the OAuth2 configuration for the application.
-func getOAuthConfig() *oauth2.Config {
-	return &oauth2.Config{
-		ClientID:     "1021942592516-ddskqoqs4d752kpmrak83vmsq05k5n07.apps.googleusercontent.com",
-		ClientSecret: "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",
-		RedirectURL:  "http://localhost:8080/callback",
-		Scopes:       []string{"https://www.googleapis.com/auth/tasks", "https://www.googleapis.com/auth/tasks.readonly", "https://www.googleapis.com/auth/userinfo.email"},
-		Endpoint:     google.Endpoint,
-	}
-}
+// ErrTokenMismatch is returned when a token was issued for another OAuth
+// client or other scopes than the configured ones.
+var ErrTokenMismatch = errors.New("token does not match the configuration")
 
 // GetClient returns an authenticated HTTP client for the given user.
 // It retrieves the user's token from the cache, refreshes it if necessary,
 // and returns an HTTP client configured with the token.
 func GetClient(ctx context.Context, user string) (*http.Client, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
 	cache, err := loadTokenCache()
 	if err != nil {
 		return nil, err
@@ -39,15 +36,23 @@ func GetClient(ctx context.Context, user string) (*http.Client, error) {
 		return nil, ErrCredentialsNotFound
 	}
 
-	conf := getOAuthConfig()
-	ts := conf.TokenSource(ctx, token)
+	conf, err := loadOAuthConfig(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkToken(user, token, conf.ClientID, cfg.AccountScopes(user)); err != nil {
+		return nil, err
+	}
+
+	ts := conf.TokenSource(ctx, &token.Token)
 	newToken, err := ts.Token()
 	if err != nil {
 		return nil, ErrTokenRefreshFailed
 	}
 
 	if newToken.AccessToken != token.AccessToken {
-		if err := cache.save(user, newToken); err != nil {
+		refreshed := &StoredToken{Token: *newToken, ClientID: token.ClientID, Scopes: token.Scopes, Refreshed: time.Now()}
+		if err := saveToken(user, refreshed); err != nil {
 			return nil, err
 		}
 	}
@@ -57,14 +62,7 @@ func GetClient(ctx context.Context, user string) (*http.Client, error) {
 
 // Logout removes the token for the given user from the cache.
 func Logout(user string) error {
-	cache, err := loadTokenCache()
-	if err != nil {
-		return err
-	}
-
-	delete(cache.Tokens, user)
-
-	return cache.saveAll()
+	return deleteToken(user)
 }
 
 // ListAccounts lists all the accounts in the token cache.
@@ -2,18 +2,139 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/yanicksenn/gtasks/internal/config"
+	"github.com/yanicksenn/gtasks/internal/lockedfile"
 	"golang.org/x/oauth2"
 )
 
 // TokenFile is the name of the file where the OAuth2 tokens are stored.
 const TokenFile = "gtasks-token.json"
 
+// The names of the credential store backends, as used in the
+// credential_store setting of the configuration.
+const (
+	// PlaintextStore keeps the tokens in a JSON file readable by the user.
+	PlaintextStore = "plaintext"
+	// EncryptedFileStore keeps the tokens in a file encrypted with a
+	// passphrase.
+	EncryptedFileStore = "encrypted-file"
+	// SecretServiceStore keeps the tokens in the secret service of the
+	// operating system.
+	SecretServiceStore = "secret-service"
+)
+
 // TokenCache represents the structure of the credentials file.
 type TokenCache struct {
-	Tokens map[string]*oauth2.Token `json:"tokens"`
+	Tokens map[string]*StoredToken `json:"tokens"`
+}
+
+// StoredToken is an OAuth2 token together with the OAuth client and the
+// scopes it was issued for. Tokens saved by older versions have neither.
+type StoredToken struct {
+	oauth2.Token
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	// Refreshed is when the token was last issued or refreshed.
+	Refreshed time.Time `json:"refreshed,omitzero"`
+}
+
+// CredentialStore stores the OAuth2 tokens of all accounts.
+type CredentialStore interface {
+	// Name returns the name of the backend.
+	Name() string
+	// Load returns the stored tokens, or an empty cache if there are none.
+	Load() (*TokenCache, error)
+	// Save replaces the stored tokens.
+	Save(cache *TokenCache) error
+	// Delete removes all stored tokens.
+	Delete() error
+}
+
+// NewCredentialStore returns the credential store backend with the given
+// name. An empty name selects the plaintext file.
+func NewCredentialStore(name string) (CredentialStore, error) {
+	switch name {
+	case PlaintextStore, "":
+		path, err := getTokenCachePath()
+		if err != nil {
+			return nil, err
+		}
+		return &fileStore{path: path}, nil
+	case EncryptedFileStore:
+		path, err := getEncryptedTokenCachePath()
+		if err != nil {
+			return nil, err
+		}
+		return &encryptedFileStore{path: path, passphrase: promptPassphrase}, nil
+	case SecretServiceStore:
+		store := newSecretServiceStore()
+		if !store.available() {
+			return nil, errors.New("the secret service is not available on this system; it requires secret-tool on Linux or security on macOS")
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store: %s. Available stores: %s, %s, %s", name, PlaintextStore, EncryptedFileStore, SecretServiceStore)
+	}
+}
+
+// MigrateCredentials moves all tokens from one credential store to another,
+// makes it the configured store and returns the number of accounts moved.
+// Tokens in the destination are kept unless the source has a token for the
+// same account. The source is only deleted once the configuration selects
+// the destination, so that the tokens are never only in a store that is not
+// used.
+func MigrateCredentials(from, to CredentialStore) (int, error) {
+	if from.Name() == to.Name() {
+		return 0, fmt.Errorf("the credentials are already stored in %s", to.Name())
+	}
+	unlock, err := lockCredentials()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	source, err := from.Load()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load the credentials from %s: %w", from.Name(), err)
+	}
+	destination, err := to.Load()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load the credentials from %s: %w", to.Name(), err)
+	}
+	for user, token := range source.Tokens {
+		destination.Tokens[user] = token
+	}
+	if err := to.Save(destination); err != nil {
+		return 0, fmt.Errorf("failed to save the credentials to %s: %w", to.Name(), err)
+	}
+	err = config.Update(func(cfg *config.Config) error {
+		cfg.CredentialStore = to.Name()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to select %s in the configuration: %w", to.Name(), err)
+	}
+	if err := from.Delete(); err != nil {
+		return 0, fmt.Errorf("failed to delete the credentials from %s: %w", from.Name(), err)
+	}
+	return len(source.Tokens), nil
+}
+
+// configuredStore returns the credential store selected in the
+// configuration.
+func configuredStore() (CredentialStore, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return NewCredentialStore(cfg.CredentialStore)
 }
 
 // getTokenCachePath returns the path to the token cache file.
@@ -25,50 +146,129 @@ func getTokenCachePath() (string, error) {
 	return filepath.Join(home, ".config", TokenFile), nil
 }
 
-// loadTokenCache loads the token cache from the file system.
+// loadTokenCache loads the token cache from the configured credential store.
 func loadTokenCache() (*TokenCache, error) {
-	path, err := getTokenCachePath()
+	store, err := configuredStore()
 	if err != nil {
 		return nil, err
 	}
+	return store.Load()
+}
+
+// saveToken saves the token of the given user.
+func saveToken(user string, token *StoredToken) error {
+	return saveTokens(map[string]*StoredToken{user: token})
+}
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return &TokenCache{Tokens: make(map[string]*oauth2.Token)}, nil
+// saveTokens saves the given tokens, keeping the tokens of other users.
+func saveTokens(tokens map[string]*StoredToken) error {
+	return updateTokenCache(func(cache *TokenCache) {
+		maps.Copy(cache.Tokens, tokens)
+	})
+}
+
+// deleteToken removes the token of the given user.
+func deleteToken(user string) error {
+	return updateTokenCache(func(cache *TokenCache) {
+		delete(cache.Tokens, user)
+	})
+}
+
+// updateTokenCache applies fn to the tokens in the configured credential
+// store and saves them. The tokens are loaded again under the credentials
+// lock, so that tokens saved by other processes meanwhile are kept.
+func updateTokenCache(fn func(cache *TokenCache)) error {
+	store, err := configuredStore()
+	if err != nil {
+		return err
 	}
+	unlock, err := lockCredentials()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	data, err := os.ReadFile(path)
+	cache, err := store.Load()
+	if err != nil {
+		return err
+	}
+	fn(cache)
+	return store.Save(cache)
+}
+
+// lockCredentials locks the credentials against changes by other processes
+// and returns a function that unlocks them. A single lock, next to the
+// plaintext token file, covers all credential stores.
+func lockCredentials() (func(), error) {
+	path, err := getTokenCachePath()
 	if err != nil {
 		return nil, err
 	}
+	return lockedfile.Lock(path)
+}
+
+// newTokenCache returns an empty token cache.
+func newTokenCache() *TokenCache {
+	return &TokenCache{Tokens: make(map[string]*StoredToken)}
+}
 
-	var cache TokenCache
-	if err := json.Unmarshal(data, &cache); err != nil {
+// decodeTokenCache decodes a token cache from JSON.
+func decodeTokenCache(data []byte) (*TokenCache, error) {
+	cache := newTokenCache()
+	if err := json.Unmarshal(data, cache); err != nil {
 		return nil, err
 	}
-	return &cache, nil
+	if cache.Tokens == nil {
+		cache.Tokens = make(map[string]*StoredToken)
+	}
+	return cache, nil
 }
 
-// save saves the token for the given user to the cache.
-func (tc *TokenCache) save(user string, token *oauth2.Token) error {
-	tc.Tokens[user] = token
-	return tc.saveAll()
+// fileStore keeps the tokens in a plaintext JSON file.
+type fileStore struct {
+	path string
 }
 
-// saveAll saves the entire token cache to the file system.
-func (tc *TokenCache) saveAll() error {
-	path, err := getTokenCachePath()
+// Name returns the name of the backend.
+func (s *fileStore) Name() string {
+	return PlaintextStore
+}
+
+// Load reads the tokens from the file.
+func (s *fileStore) Load() (*TokenCache, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return newTokenCache(), nil
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return decodeTokenCache(data)
+}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+// Save writes the tokens to the file.
+func (s *fileStore) Save(cache *TokenCache) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
 		return err
 	}
+	return writePrivateFile(s.path, data)
+}
 
-	data, err := json.MarshalIndent(tc, "", "  ")
-	if err != nil {
+// Delete removes the file.
+func (s *fileStore) Delete() error {
+	return removeFile(s.path)
+}
+
+// writePrivateFile atomically replaces a file that only the user can read.
+func writePrivateFile(path string, data []byte) error {
+	return lockedfile.Write(path, data, 0600)
+}
+
+// removeFile removes a file if it exists.
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return err
 	}
-
-	return os.WriteFile(path, data, 0600)
+	return nil
 }
@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/yanicksenn/gtasks/internal/config"
 	"golang.org/x/oauth2"
 )
 
@@ -13,14 +17,13 @@ func TestTokenCache(t *testing.T) {
 	os.Setenv("HOME", tempDir)
 
 	// Test saving a token.
-	cache := &TokenCache{Tokens: make(map[string]*oauth2.Token)}
-	token1 := &oauth2.Token{AccessToken: "test-token-1"}
-	token2 := &oauth2.Token{AccessToken: "test-token-2"}
-	err := cache.save("test1@example.com", token1)
+	token1 := &StoredToken{Token: oauth2.Token{AccessToken: "test-token-1"}}
+	token2 := &StoredToken{Token: oauth2.Token{AccessToken: "test-token-2"}}
+	err := saveToken("test1@example.com", token1)
 	if err != nil {
 		t.Fatalf("failed to save token cache: %v", err)
 	}
-	err = cache.save("test2@example.com", token2)
+	err = saveToken("test2@example.com", token2)
 	if err != nil {
 		t.Fatalf("failed to save token cache: %v", err)
 	}
@@ -58,3 +61,230 @@ func TestTokenCache(t *testing.T) {
 		t.Fatalf("account was not removed")
 	}
 }
+
+// fixedPassphrase returns a passphrase function that always returns the
+// given passphrase.
+func fixedPassphrase(passphrase string) func(bool) ([]byte, error) {
+	return func(bool) ([]byte, error) {
+		return []byte(passphrase), nil
+	}
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), EncryptedTokenFile)
+	store := &encryptedFileStore{path: path, passphrase: fixedPassphrase("correct horse")}
+
+	cache, err := store.Load()
+	if err != nil || len(cache.Tokens) != 0 {
+		t.Fatalf("expected an empty cache, got %v, %v", cache, err)
+	}
+	cache.Tokens["user@example.com"] = &StoredToken{Token: oauth2.Token{RefreshToken: "secret-refresh-token"}}
+	if err := store.Save(cache); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read the file: %v", err)
+	}
+	if strings.Contains(string(data), "secret-refresh-token") || strings.Contains(string(data), "user@example.com") {
+		t.Errorf("expected the tokens to be encrypted, got %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if token := loaded.Tokens["user@example.com"]; token == nil || token.RefreshToken != "secret-refresh-token" {
+		t.Errorf("unexpected token: %+v", token)
+	}
+
+	wrong := &encryptedFileStore{path: path, passphrase: fixedPassphrase("wrong")}
+	if _, err := wrong.Load(); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("expected a wrong passphrase error, got %v", err)
+	}
+
+	// Changing the authenticated header makes the file invalid.
+	tampered := strings.Replace(string(data), `"p": 1`, `"p": 2`, 1)
+	os.WriteFile(path, []byte(tampered), 0600)
+	if _, err := store.Load(); err == nil {
+		t.Error("expected an error for a tampered file")
+	}
+}
+
+// fakeSecretTool stores a single secret like secret-tool.
+type fakeSecretTool struct {
+	secret []byte
+}
+
+func (f *fakeSecretTool) run(stdin []byte, name string, args ...string) ([]byte, error) {
+	switch args[0] {
+	case "store":
+		f.secret = stdin
+	case "lookup":
+		if f.secret == nil {
+			return nil, &commandError{name: name, code: 1}
+		}
+		return append(f.secret, '\n'), nil
+	case "clear":
+		f.secret = nil
+	}
+	return nil, nil
+}
+
+func TestSecretServiceStore(t *testing.T) {
+	tool := &fakeSecretTool{}
+	store := &secretServiceStore{goos: "linux", run: tool.run, lookPath: func(string) (string, error) { return "/usr/bin/secret-tool", nil }}
+	if !store.available() {
+		t.Fatal("expected the secret service to be available")
+	}
+
+	cache, err := store.Load()
+	if err != nil || len(cache.Tokens) != 0 {
+		t.Fatalf("expected an empty cache, got %v, %v", cache, err)
+	}
+	cache.Tokens["user@example.com"] = &StoredToken{Token: oauth2.Token{RefreshToken: "refresh"}}
+	if err := store.Save(cache); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	loaded, err := store.Load()
+	if err != nil || loaded.Tokens["user@example.com"].RefreshToken != "refresh" {
+		t.Fatalf("unexpected cache: %v, %v", loaded, err)
+	}
+	if err := store.Delete(); err != nil || tool.secret != nil {
+		t.Errorf("expected the secret to be deleted, got %v", err)
+	}
+
+	unsupported := &secretServiceStore{goos: "plan9", lookPath: store.lookPath}
+	if unsupported.available() {
+		t.Error("expected the secret service to be unavailable")
+	}
+}
+
+func TestMigrateCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	plaintext := &fileStore{path: filepath.Join(dir, TokenFile)}
+	encrypted := &encryptedFileStore{path: filepath.Join(dir, EncryptedTokenFile), passphrase: fixedPassphrase("passphrase")}
+
+	plaintext.Save(&TokenCache{Tokens: map[string]*StoredToken{
+		"a@example.com": {Token: oauth2.Token{RefreshToken: "a"}},
+		"b@example.com": {Token: oauth2.Token{RefreshToken: "b"}},
+	}})
+	encrypted.Save(&TokenCache{Tokens: map[string]*StoredToken{
+		"b@example.com": {Token: oauth2.Token{RefreshToken: "old"}},
+		"c@example.com": {Token: oauth2.Token{RefreshToken: "c"}},
+	}})
+
+	moved, err := MigrateCredentials(plaintext, encrypted)
+	if err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("expected 2 accounts to be moved, got %d", moved)
+	}
+	if _, err := os.Stat(plaintext.path); !os.IsNotExist(err) {
+		t.Errorf("expected the plaintext file to be deleted, got %v", err)
+	}
+	cache, err := encrypted.Load()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	for user, expected := range map[string]string{"a@example.com": "a", "b@example.com": "b", "c@example.com": "c"} {
+		if token := cache.Tokens[user]; token == nil || token.RefreshToken != expected {
+			t.Errorf("expected token %s for %s, got %+v", expected, user, token)
+		}
+	}
+
+	if cfg, err := config.Load(); err != nil || cfg.CredentialStore != EncryptedFileStore {
+		t.Errorf("expected %s to be configured, got %+v (%v)", EncryptedFileStore, cfg, err)
+	}
+
+	if _, err := MigrateCredentials(encrypted, encrypted); err == nil {
+		t.Error("expected an error when migrating to the same store")
+	}
+}
+
+func TestMigrateCredentials_ConfigFails(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	plaintext := &fileStore{path: filepath.Join(dir, TokenFile)}
+	encrypted := &encryptedFileStore{path: filepath.Join(dir, EncryptedTokenFile), passphrase: fixedPassphrase("passphrase")}
+	plaintext.Save(&TokenCache{Tokens: map[string]*StoredToken{"a@example.com": {Token: oauth2.Token{RefreshToken: "a"}}}})
+
+	// The configuration cannot be written, so the source is kept
+	path, _ := config.GetConfigPath()
+	os.MkdirAll(path, 0755)
+	if _, err := MigrateCredentials(plaintext, encrypted); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+	if cache, err := plaintext.Load(); err != nil || cache.Tokens["a@example.com"] == nil {
+		t.Errorf("expected the source to keep the token, got %+v (%v)", cache, err)
+	}
+}
+
+func TestSaveToken_ConcurrentWriters(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Each writer saves the token of another account, and none of them may
+	// be lost.
+	const writers = 10
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token := &StoredToken{Token: oauth2.Token{RefreshToken: fmt.Sprint(i)}}
+			if err := saveToken(fmt.Sprintf("user%d@example.com", i), token); err != nil {
+				t.Errorf("failed to save the token: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	accounts, err := ListAccounts()
+	if err != nil {
+		t.Fatalf("failed to list accounts: %v", err)
+	}
+	if len(accounts) != writers {
+		t.Errorf("expected %d accounts, got %v", writers, accounts)
+	}
+}
+
+func TestSecretServiceStore_Failures(t *testing.T) {
+	lookPath := func(string) (string, error) { return "/usr/bin/tool", nil }
+	failing := func(err error) func([]byte, string, ...string) ([]byte, error) {
+		return func([]byte, string, ...string) ([]byte, error) { return nil, err }
+	}
+
+	testCases := []struct {
+		name  string
+		goos  string
+		err   error
+		empty bool
+	}{
+		{"secret-tool without secret", "linux", &commandError{name: "secret-tool", code: 1}, true},
+		{"locked keyring", "linux", &commandError{name: "secret-tool", code: 1, stderr: "Cannot create an item in a locked collection"}, false},
+		{"no D-Bus", "linux", &commandError{name: "secret-tool", code: 1, stderr: "Cannot autolaunch D-Bus without X11 $DISPLAY"}, false},
+		{"security without secret", "darwin", &commandError{name: "security", code: 44}, true},
+		{"cancelled unlock", "darwin", &commandError{name: "security", code: 128}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &secretServiceStore{goos: tc.goos, run: failing(tc.err), lookPath: lookPath}
+			cache, err := store.Load()
+			if tc.empty && (err != nil || len(cache.Tokens) != 0) {
+				t.Errorf("expected an empty cache, got %v, %v", cache, err)
+			}
+			if !tc.empty && err == nil {
+				t.Error("expected the failure to be returned")
+			}
+			if err := store.Delete(); (err == nil) != tc.empty {
+				t.Errorf("unexpected error from Delete: %v", err)
+			}
+		})
+	}
+}
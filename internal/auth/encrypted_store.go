@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/crypto/scrypt"
+)
+
+// EncryptedTokenFile is the name of the file where the encrypted-file
+// backend stores the OAuth2 tokens.
+const EncryptedTokenFile = "gtasks-token.enc"
+
+// PassphraseEnv is the environment variable that holds the passphrase of the
+// encrypted-file backend. Without it, the passphrase is read from the
+// terminal.
+const PassphraseEnv = "GTASKS_PASSPHRASE"
+
+// The parameters of the key derivation. N is the recommended cost for
+// interactive logins as of 2017.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+)
+
+// encryptedFileFormat is the version of the encrypted file format.
+const encryptedFileFormat = 1
+
+// encryptedFile is the content of the encrypted token file. The tokens are
+// encrypted with AES-256-GCM and a key derived from the passphrase with
+// scrypt. The header fields are authenticated as additional data.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// additionalData returns the header fields that are authenticated along
+// with the ciphertext.
+func (f *encryptedFile) additionalData() []byte {
+	return fmt.Appendf(nil, "gtasks-credentials:%d:%s:%d:%d:%d", f.Version, f.KDF, f.N, f.R, f.P)
+}
+
+// encryptedFileStore keeps the tokens in a file encrypted with a
+// passphrase.
+type encryptedFileStore struct {
+	path string
+	// passphrase returns the passphrase. confirm is set when a new file is
+	// created, so that a mistyped passphrase can be caught.
+	passphrase func(confirm bool) ([]byte, error)
+}
+
+// getEncryptedTokenCachePath returns the path to the encrypted token file.
+func getEncryptedTokenCachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", EncryptedTokenFile), nil
+}
+
+// Name returns the name of the backend.
+func (s *encryptedFileStore) Name() string {
+	return EncryptedFileStore
+}
+
+// Load decrypts the tokens from the file.
+func (s *encryptedFileStore) Load() (*TokenCache, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return newTokenCache(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid encrypted credential file: %w", err)
+	}
+	if file.Version != encryptedFileFormat || file.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported encrypted credential file: version %d, kdf %q", file.Version, file.KDF)
+	}
+
+	passphrase, err := s.passphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, file.additionalData())
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted credential file")
+	}
+	return decodeTokenCache(plaintext)
+}
+
+// Save encrypts the tokens to the file with a new salt and nonce.
+func (s *encryptedFileStore) Save(cache *TokenCache) error {
+	_, statErr := os.Stat(s.path)
+	passphrase, err := s.passphrase(os.IsNotExist(statErr))
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	file := encryptedFile{
+		Version: encryptedFileFormat,
+		KDF:     "scrypt",
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltLen),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	aead, err := newAEAD(passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, file.additionalData())
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(s.path, data)
+}
+
+// Delete removes the file.
+func (s *encryptedFileStore) Delete() error {
+	return removeFile(s.path)
+}
+
+// newAEAD derives a key from the passphrase and returns an AES-256-GCM
+// cipher for it.
+func newAEAD(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive the key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// passphraseCache holds the passphrase read from the terminal, so that it is
+// only asked for once per command.
+var passphraseCache struct {
+	mu    sync.Mutex
+	value []byte
+}
+
+// promptPassphrase returns the passphrase from the environment, or reads it
+// from the terminal without echo.
+func promptPassphrase(confirm bool) ([]byte, error) {
+	if value := os.Getenv(PassphraseEnv); value != "" {
+		return []byte(value), nil
+	}
+
+	passphraseCache.mu.Lock()
+	defer passphraseCache.mu.Unlock()
+	if passphraseCache.value != nil {
+		return passphraseCache.value, nil
+	}
+
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, fmt.Errorf("the credentials are encrypted: set %s or run gtasks in a terminal", PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, "Passphrase for the gtasks credentials: ")
+	value, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the passphrase: %w", err)
+	}
+	if len(value) == 0 {
+		return nil, errors.New("the passphrase must not be empty")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat the passphrase: ")
+		repeated, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the passphrase: %w", err)
+		}
+		if !bytes.Equal(value, repeated) {
+			return nil, errors.New("the passphrases do not match")
+		}
+	}
+	passphraseCache.value = value
+	return value, nil
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yanicksenn/gtasks/internal/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// ClientFileEnv is the environment variable that holds the path to the JSON
+// file of an OAuth client. It takes precedence over oauth_client_file in the
+// configuration.
+const ClientFileEnv = "GTASKS_OAUTH_CLIENT_FILE"
+
+// The built-in OAuth client, a "Desktop app" client of the gtasks project.
+const (
+	defaultClientID     = "1021942592516-ddskqoqs4d752kpmrak83vmsq05k5n07.apps.googleusercontent.com"
+	defaultClientSecret = "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"
+)
+
+// scopePrefix is the common prefix of Google's OAuth scopes. Scopes can be
+// configured without it, e.g. tasks.readonly.
+const scopePrefix = "https://www.googleapis.com/auth/"
+
+// userInfoScope is always requested, to find out which account logged in.
+const userInfoScope = scopePrefix + "userinfo.email"
+
+// DefaultScopes are the scopes requested by a login unless other scopes are
+// configured.
+var DefaultScopes = []string{scopePrefix + "tasks", scopePrefix + "tasks.readonly"}
+
+// ExpandScopes returns the URLs of scopes given as URLs or as short names
+// such as tasks.readonly.
+func ExpandScopes(scopes []string) []string {
+	expanded := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		switch {
+		case scope == "":
+			continue
+		case strings.Contains(scope, "://"), scope == "openid", scope == "email", scope == "profile":
+			expanded = append(expanded, scope)
+		default:
+			expanded = append(expanded, scopePrefix+scope)
+		}
+	}
+	return expanded
+}
+
+// loginScopes returns the scopes requested by a login: the given scopes, or
+// the default scopes if there are none, plus the user info scope.
+func loginScopes(scopes []string) []string {
+	scopes = ExpandScopes(scopes)
+	if len(scopes) == 0 {
+		scopes = slices.Clone(DefaultScopes)
+	}
+	if !slices.Contains(scopes, userInfoScope) {
+		scopes = append(scopes, userInfoScope)
+	}
+	return scopes
+}
+
+// sameScopes reports whether two sets of scopes grant the same access to
+// tasks. Scopes that only identify the user are ignored.
+func sameScopes(a, b []string) bool {
+	normalize := func(scopes []string) []string {
+		scopes = slices.DeleteFunc(ExpandScopes(scopes), func(scope string) bool {
+			return scope == userInfoScope || scope == "openid" || scope == "email" || scope == "profile"
+		})
+		slices.Sort(scopes)
+		return slices.Compact(scopes)
+	}
+	return slices.Equal(normalize(a), normalize(b))
+}
+
+// missingScopes returns the requested scopes that were not granted. Scopes
+// that only identify the user are ignored.
+func missingScopes(granted, requested []string) []string {
+	granted = ExpandScopes(granted)
+	var missing []string
+	for _, scope := range ExpandScopes(requested) {
+		if scope == userInfoScope || scope == "openid" || scope == "email" || scope == "profile" {
+			continue
+		}
+		if !slices.Contains(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// googleRevokeURL is Google's OAuth token revocation endpoint.
+const googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// loadOAuthConfig returns the OAuth2 configuration of the configured client
+// for the given scopes. The client is read from the file named by
+// ClientFileEnv or by the configuration, or else the built-in client is
+// used.
+func loadOAuthConfig(cfg *config.Config, scopes []string) (*oauth2.Config, error) {
+	data, path, err := readClientFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return &oauth2.Config{
+			ClientID:     defaultClientID,
+			ClientSecret: defaultClientSecret,
+			Scopes:       scopes,
+			Endpoint:     google.Endpoint,
+		}, nil
+	}
+
+	conf, err := google.ConfigFromJSON(data, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OAuth client file %s: %w", path, err)
+	}
+	// The redirect URL is set by the login, and the client file does not
+	// name the device authorization endpoint.
+	conf.RedirectURL = ""
+	if conf.Endpoint.DeviceAuthURL == "" {
+		conf.Endpoint.DeviceAuthURL = google.Endpoint.DeviceAuthURL
+	}
+	return conf, nil
+}
+
+// loadRevokeURL returns the token revocation endpoint of the configured
+// client. Client files downloaded from Google do not name it, so it is
+// Google's endpoint unless the file sets revoke_uri next to token_uri.
+func loadRevokeURL(cfg *config.Config) (string, error) {
+	data, path, err := readClientFile(cfg)
+	if err != nil || data == nil {
+		return googleRevokeURL, err
+	}
+	var file map[string]struct {
+		RevokeURI string `json:"revoke_uri"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", fmt.Errorf("invalid OAuth client file %s: %w", path, err)
+	}
+	for _, client := range file {
+		if client.RevokeURI != "" {
+			return client.RevokeURI, nil
+		}
+	}
+	return googleRevokeURL, nil
+}
+
+// readClientFile reads the client file named by ClientFileEnv or by the
+// configuration. It returns no data if there is none.
+func readClientFile(cfg *config.Config) ([]byte, string, error) {
+	path := os.Getenv(ClientFileEnv)
+	if path == "" {
+		path = cfg.OAuthClientFile
+	}
+	if path == "" {
+		return nil, "", nil
+	}
+
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the OAuth client file: %w", err)
+	}
+	return data, path, nil
+}
+
+// grantedScopes returns the scopes granted with a token, which may be fewer
+// than the requested scopes if the user unchecked some of them.
+func grantedScopes(token *oauth2.Token, requested []string) []string {
+	if scope, ok := token.Extra("scope").(string); ok && scope != "" {
+		return strings.Fields(scope)
+	}
+	return requested
+}
+
+// checkToken reports an error if a stored token was issued for another
+// client or other scopes than the configured ones. Tokens saved before the
+// client and scopes were recorded belong to the built-in client and the
+// default scopes.
+func checkToken(user string, token *StoredToken, clientID string, scopes []string) error {
+	issuedClient := token.ClientID
+	if issuedClient == "" {
+		issuedClient = defaultClientID
+	}
+	if issuedClient != clientID {
+		return fmt.Errorf("%w: the token of %s was issued for the OAuth client %s, but %s is configured. Please log in again", ErrTokenMismatch, user, issuedClient, clientID)
+	}
+
+	issuedScopes := token.Scopes
+	if len(issuedScopes) == 0 {
+		issuedScopes = DefaultScopes
+	}
+	if !sameScopes(issuedScopes, loginScopes(scopes)) {
+		return fmt.Errorf("%w: the token of %s was issued for the scopes %s, but %s are configured. Please log in again with 'gtasks accounts login --account %s'",
+			ErrTokenMismatch, user, strings.Join(issuedScopes, " "), strings.Join(loginScopes(scopes), " "), user)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/config"
+	"golang.org/x/oauth2"
+)
+
+const clientJSON = `{"installed":{"client_id":"internal.apps.googleusercontent.com","client_secret":"internal-secret","auth_uri":"https://accounts.google.com/o/oauth2/auth","token_uri":"https://oauth2.googleapis.com/token","redirect_uris":["http://localhost"]}}`
+
+func TestLoadOAuthConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	path := filepath.Join(dir, "client.json")
+	os.WriteFile(path, []byte(clientJSON), 0600)
+
+	t.Run("built-in client", func(t *testing.T) {
+		conf, err := loadOAuthConfig(&config.Config{}, loginScopes(nil))
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if conf.ClientID != defaultClientID {
+			t.Errorf("expected the built-in client, got %s", conf.ClientID)
+		}
+		expected := []string{scopePrefix + "tasks", scopePrefix + "tasks.readonly", userInfoScope}
+		if !slices.Equal(conf.Scopes, expected) {
+			t.Errorf("expected scopes %v, got %v", expected, conf.Scopes)
+		}
+	})
+
+	t.Run("configured client", func(t *testing.T) {
+		conf, err := loadOAuthConfig(&config.Config{OAuthClientFile: "~/client.json"}, loginScopes([]string{"tasks.readonly"}))
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if conf.ClientID != "internal.apps.googleusercontent.com" || conf.ClientSecret != "internal-secret" {
+			t.Errorf("expected the configured client, got %s", conf.ClientID)
+		}
+		if conf.RedirectURL != "" || conf.Endpoint.DeviceAuthURL == "" {
+			t.Errorf("unexpected redirect URL %q or device endpoint %q", conf.RedirectURL, conf.Endpoint.DeviceAuthURL)
+		}
+		if !slices.Equal(conf.Scopes, []string{scopePrefix + "tasks.readonly", userInfoScope}) {
+			t.Errorf("unexpected scopes: %v", conf.Scopes)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv(ClientFileEnv, path)
+		conf, err := loadOAuthConfig(&config.Config{OAuthClientFile: "missing.json"}, nil)
+		if err != nil || conf.ClientID != "internal.apps.googleusercontent.com" {
+			t.Errorf("expected the client of the environment, got %v", err)
+		}
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.json")
+		os.WriteFile(invalid, []byte(`{"other":{}}`), 0600)
+		if _, err := loadOAuthConfig(&config.Config{OAuthClientFile: invalid}, nil); err == nil {
+			t.Error("expected an error for an invalid client file")
+		}
+	})
+}
+
+func TestGetClient_TokenMismatch(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	path := filepath.Join(dir, "client.json")
+	os.WriteFile(path, []byte(clientJSON), 0600)
+
+	valid := oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)}
+	cache := newTokenCache()
+	cache.Tokens["legacy@example.com"] = &StoredToken{Token: valid}
+	cache.Tokens["internal@example.com"] = &StoredToken{Token: valid, ClientID: "internal.apps.googleusercontent.com", Scopes: []string{scopePrefix + "tasks", userInfoScope}}
+	cache.Tokens["reader@example.com"] = &StoredToken{Token: valid, Scopes: []string{scopePrefix + "tasks.readonly"}}
+	if err := saveTokens(cache.Tokens); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		cfg      config.Config
+		user     string
+		mismatch bool
+	}{
+		{"legacy token", config.Config{}, "legacy@example.com", false},
+		{"legacy token with another client", config.Config{OAuthClientFile: path}, "legacy@example.com", true},
+		{"configured client", config.Config{OAuthClientFile: path, Scopes: []string{"tasks"}}, "internal@example.com", false},
+		{"built-in client", config.Config{Scopes: []string{"tasks"}}, "internal@example.com", true},
+		{"account scopes", config.Config{Accounts: map[string]config.AccountConfig{"reader@example.com": {Scopes: []string{"tasks.readonly"}}}}, "reader@example.com", false},
+		{"other scopes", config.Config{}, "reader@example.com", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.cfg.Save(); err != nil {
+				t.Fatalf("failed to save config: %v", err)
+			}
+			_, err := GetClient(context.Background(), tc.user)
+			if errors.Is(err, ErrTokenMismatch) != tc.mismatch {
+				t.Errorf("expected mismatch %v, got %v", tc.mismatch, err)
+			}
+			if !tc.mismatch && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestExpandScopes(t *testing.T) {
+	scopes := ExpandScopes([]string{"tasks.readonly", " ", "openid", "https://www.googleapis.com/auth/tasks"})
+	expected := []string{scopePrefix + "tasks.readonly", "openid", scopePrefix + "tasks"}
+	if !slices.Equal(scopes, expected) {
+		t.Errorf("expected %v, got %v", expected, scopes)
+	}
+	if !sameScopes([]string{"tasks", userInfoScope}, []string{scopePrefix + "tasks", "tasks"}) {
+		t.Error("expected the scopes to be the same")
+	}
+	if sameScopes([]string{"tasks"}, []string{"tasks.readonly"}) {
+		t.Error("expected the scopes to differ")
+	}
+}
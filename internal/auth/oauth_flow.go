@@ -1,124 +1,342 @@
 package auth
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/pkg/browser"
+	"github.com/yanicksenn/gtasks/internal/config"
 	"golang.org/x/oauth2"
-	"google.golang.org/api/option"
 	oauth2_v2 "google.golang.org/api/oauth2/v2"
+	"google.golang.org/api/option"
 )
 
+// LoginFlow selects how the user grants gtasks access to their account.
+type LoginFlow string
+
 const (
-	authRedirectURI = "http://localhost:8080/callback"
+	// BrowserFlow opens the browser on the consent page and receives the
+	// authorization code on a loopback listener.
+	BrowserFlow LoginFlow = "browser"
+	// ManualFlow prints the URL of the consent page and reads the redirect
+	// URL or the authorization code pasted by the user. It works on machines
+	// without a browser, e.g. over SSH.
+	ManualFlow LoginFlow = "manual"
+	// DeviceFlow shows a code that the user enters on another device, using
+	// the OAuth 2.0 device authorization grant.
+	DeviceFlow LoginFlow = "device"
 )
 
-// LoginViaWebFlow orchestrates the web-based authentication process.
-// It starts a local server to handle the OAuth2 callback, opens the user's
-// browser to the authentication URL, and waits for the user to grant
-// permission. Once the user has granted permission, the function exchanges
-// the authorization code for a token, retrieves the user's email address,
-// and saves the token to the cache.
-func LoginViaWebFlow(ctx context.Context) (string, error) {
-	conf := getOAuthConfig()
+// LoginOptions holds the parameters for a login.
+type LoginOptions struct {
+	// Flow selects the login flow. The default is BrowserFlow.
+	Flow LoginFlow
+	// In is read for the redirect URL or code of the manual flow. The
+	// default is standard input.
+	In io.Reader
+	// Out receives the instructions for the user. The default is standard
+	// output.
+	Out io.Writer
+	// Scopes are the requested scopes, as URLs or short names. The default
+	// is the scopes configured for Account.
+	Scopes []string
+	// Account is the email address of the account to log in, if known. It
+	// is suggested on the consent page, and logging in to another account
+	// fails.
+	Account string
+}
 
-	tokenChan := make(chan *oauth2.Token)
-	errChan := make(chan error)
+// login holds the OAuth endpoints and the terminal of a login. Tests replace
+// the endpoints with fakes.
+type login struct {
+	conf *oauth2.Config
+	// account is the expected account, if any.
+	account string
+	// userInfoEndpoint replaces the base URL of the user info API if set.
+	userInfoEndpoint string
+	openURL          func(url string) error
+	in               io.Reader
+	out              io.Writer
+}
 
-	// Generate state
-	stateBytes := make([]byte, 16)
-	if _, err := rand.Read(stateBytes); err != nil {
-		return "", fmt.Errorf("failed to generate state: %w", err)
+// callbackResult is the authorization code, or the error, delivered by the
+// OAuth callback or pasted by the user.
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// Login authenticates a user with the given flow. Once the user has granted
+// permission, it retrieves the user's email address, saves the token to the
+// cache and returns the email address.
+func Login(ctx context.Context, opts LoginOptions) (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	scopes := opts.Scopes
+	if len(scopes) == 0 {
+		scopes = cfg.AccountScopes(opts.Account)
+	}
+	conf, err := loadOAuthConfig(cfg, loginScopes(scopes))
+	if err != nil {
+		return "", err
 	}
-	state := hex.EncodeToString(stateBytes)
 
-	server := &http.Server{Addr: ":8080"}
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query()
-		if query.Get("state") != state {
-			http.Error(w, "Invalid state parameter", http.StatusBadRequest)
-			errChan <- fmt.Errorf("invalid state parameter")
-			return
-		}
-		if errStr := query.Get("error"); errStr != "" {
-			errDesc := query.Get("error_description")
-			http.Error(w, fmt.Sprintf("Authentication failed: %s - %s", errStr, errDesc), http.StatusBadRequest)
-			errChan <- fmt.Errorf("authentication service returned an error: %s (%s)", errStr, errDesc)
-			return
-		}
+	l := &login{conf: conf, account: opts.Account, openURL: browser.OpenURL, in: opts.In, out: opts.Out}
+	if l.in == nil {
+		l.in = os.Stdin
+	}
+	if l.out == nil {
+		l.out = os.Stdout
+	}
+	return l.run(ctx, opts.Flow)
+}
 
-		authCode := query.Get("code")
-		if authCode == "" {
-			http.Error(w, "Missing authorization code", http.StatusBadRequest)
-			errChan <- fmt.Errorf("callback did not return authorization code")
-			return
-		}
+// LoginViaWebFlow authenticates a user with the browser flow and returns
+// the user's email address.
+func LoginViaWebFlow(ctx context.Context) (string, error) {
+	return Login(ctx, LoginOptions{Flow: BrowserFlow})
+}
 
-		token, err := conf.Exchange(ctx, authCode)
-		if err != nil {
-			http.Error(w, "Failed to exchange authorization code for token", http.StatusInternalServerError)
-			errChan <- fmt.Errorf("failed to exchange authorization code for token: %w", err)
-			return
-		}
+// run gets a token with the given flow and saves it for its user.
+func (l *login) run(ctx context.Context, flow LoginFlow) (string, error) {
+	var token *oauth2.Token
+	var err error
+	switch flow {
+	case BrowserFlow, "":
+		token, err = l.authCode(ctx, true)
+	case ManualFlow:
+		token, err = l.authCode(ctx, false)
+	case DeviceFlow:
+		token, err = l.device(ctx)
+	default:
+		return "", fmt.Errorf("unknown login flow: %s", flow)
+	}
+	if err != nil {
+		return "", err
+	}
+	return l.save(ctx, token)
+}
 
-		fmt.Fprintln(w, "Authentication successful! You can close this window.")
-		tokenChan <- token
-	})
+// authCode gets a token with the authorization code flow. The code is
+// received by a loopback listener on an ephemeral port. Unless the browser
+// is opened, the user can also paste the redirect URL or the code, e.g. if
+// the browser runs on another machine.
+func (l *login) authCode(ctx context.Context, openBrowser bool) (*oauth2.Token, error) {
+	state, err := newState()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
 
-	go func() {
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			errChan <- fmt.Errorf("failed to start local server: %w", err)
-		}
-	}()
-	defer func() {
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		server.Shutdown(shutdownCtx)
-	}()
+	results := make(chan callbackResult, 1)
+	redirectURL, shutdown, err := listen(state, results)
+	if err != nil {
+		return nil, err
+	}
+	defer shutdown()
 
-	authURL := conf.AuthCodeURL(state, oauth2.AccessTypeOffline)
+	conf := *l.conf
+	conf.RedirectURL = redirectURL
+	authOpts := []oauth2.AuthCodeOption{oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier)}
+	if l.account != "" {
+		authOpts = append(authOpts, oauth2.SetAuthURLParam("login_hint", l.account))
+	}
+	authURL := conf.AuthCodeURL(state, authOpts...)
 
-	fmt.Printf("Your browser should open automatically. If not, please visit:\n%s\n", authURL)
-	if err := browser.OpenURL(authURL); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not open browser: %v\n", err)
+	if openBrowser {
+		fmt.Fprintf(l.out, "Your browser should open automatically. If not, please visit:\n%s\n", authURL)
+		if err := l.openURL(authURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not open browser: %v\n", err)
+		}
+	} else {
+		fmt.Fprintf(l.out, "Visit this URL in a browser on any machine and grant access:\n%s\n\n", authURL)
+		fmt.Fprintf(l.out, "The browser is then redirected to %s, which may fail to load.\nPaste the URL of that page, or the code it contains: ", redirectURL)
+		go readPasted(l.in, state, results)
 	}
 
-	var token *oauth2.Token
+	var result callbackResult
 	select {
-	case token = <-tokenChan:
-	case err := <-errChan:
-		return "", err
+	case result = <-results:
 	case <-ctx.Done():
-		return "", ctx.Err()
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	token, err := conf.Exchange(ctx, result.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code for token: %w", err)
+	}
+	return token, nil
+}
+
+// device gets a token with the device authorization flow. Google only
+// offers it to clients of the type "TVs and Limited Input devices", which
+// the built-in client is not.
+func (l *login) device(ctx context.Context) (*oauth2.Token, error) {
+	if l.conf.ClientID == defaultClientID {
+		return nil, fmt.Errorf("the device flow needs an OAuth client of the type \"TVs and Limited Input devices\", which the built-in client is not. Set oauth_client_file in the configuration or %s to the JSON file of such a client", ClientFileEnv)
+	}
+	auth, err := l.conf.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the device authorization: %w", err)
+	}
+	fmt.Fprintf(l.out, "On any device, visit %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+
+	token, err := l.conf.DeviceAccessToken(ctx, auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a token for the device code: %w", err)
+	}
+	return token, nil
+}
+
+// save retrieves the email address of the token's user and saves the token
+// to the cache, together with the client and scopes it was issued for. A
+// token without all requested scopes is not saved, since it would not match
+// the configuration.
+func (l *login) save(ctx context.Context, token *oauth2.Token) (string, error) {
+	scopes := grantedScopes(token, l.conf.Scopes)
+	if missing := missingScopes(scopes, l.conf.Scopes); len(missing) > 0 {
+		return "", fmt.Errorf("the permissions for %s were not granted. Please log in again and allow all requested permissions", strings.Join(missing, " "))
 	}
 
 	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(token))
+	opts := []option.ClientOption{option.WithHTTPClient(client)}
+	if l.userInfoEndpoint != "" {
+		opts = append(opts, option.WithEndpoint(l.userInfoEndpoint))
+	}
 
-	svc, err := oauth2_v2.NewService(ctx, option.WithHTTPClient(client))
+	svc, err := oauth2_v2.NewService(ctx, opts...)
 	if err != nil {
 		return "", fmt.Errorf("unable to create oauth2 service: %w", err)
 	}
 
-	userInfo, err := svc.Userinfo.Get().Do()
+	userInfo, err := svc.Userinfo.Get().Context(ctx).Do()
 	if err != nil {
 		return "", fmt.Errorf("unable to retrieve user info: %w", err)
 	}
 
-	cache, err := loadTokenCache()
-	if err != nil {
-		return "", err
+	if l.account != "" && userInfo.Email != l.account {
+		return "", fmt.Errorf("logged in as %s instead of %s", userInfo.Email, l.account)
 	}
-	if err := cache.save(userInfo.Email, token); err != nil {
+
+	stored := &StoredToken{Token: *token, ClientID: l.conf.ClientID, Scopes: scopes, Refreshed: time.Now()}
+	if err := saveToken(userInfo.Email, stored); err != nil {
 		return "", err
 	}
 
 	return userInfo.Email, nil
 }
 
+// listen starts a loopback listener on an ephemeral port that delivers the
+// result of the OAuth callback. It returns the redirect URL and a function
+// that stops the listener.
+func listen(state string, results chan<- callbackResult) (string, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to start local server: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		code, err := checkCallback(r.URL.Query(), state)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Authentication failed: %v", err), http.StatusBadRequest)
+			deliver(results, callbackResult{err: err})
+			return
+		}
+		fmt.Fprintln(w, "Authentication successful! You can close this window.")
+		deliver(results, callbackResult{code: code})
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+
+	shutdown := func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}
+	return fmt.Sprintf("http://%s/callback", listener.Addr()), shutdown, nil
+}
+
+// readPasted reads lines from r until one contains a redirect URL or an
+// authorization code and delivers it.
+func readPasted(r io.Reader, state string, results chan<- callbackResult) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		code, err := parsePasted(scanner.Text(), state)
+		deliver(results, callbackResult{code: code, err: err})
+		return
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	deliver(results, callbackResult{err: fmt.Errorf("failed to read the authorization code: %w", err)})
+}
+
+// parsePasted returns the authorization code from a pasted redirect URL, or
+// the pasted text itself if it is not a URL.
+func parsePasted(text, state string) (string, error) {
+	text = strings.TrimSpace(text)
+	if !strings.Contains(text, "://") {
+		return text, nil
+	}
+	u, err := url.Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect URL: %w", err)
+	}
+	return checkCallback(u.Query(), state)
+}
 
+// checkCallback validates the query of an OAuth callback and returns its
+// authorization code.
+func checkCallback(query url.Values, state string) (string, error) {
+	if errStr := query.Get("error"); errStr != "" {
+		return "", fmt.Errorf("authentication service returned an error: %s (%s)", errStr, query.Get("error_description"))
+	}
+	if query.Get("state") != state {
+		return "", errors.New("invalid state parameter")
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", errors.New("callback did not return authorization code")
+	}
+	return code, nil
+}
+
+// deliver sends a result unless one has already been delivered.
+func deliver(results chan<- callbackResult, result callbackResult) {
+	select {
+	case results <- result:
+	default:
+	}
+}
+
+// newState generates a random state parameter to protect the callback
+// against forged requests.
+func newState() (string, error) {
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	return hex.EncodeToString(stateBytes), nil
+}
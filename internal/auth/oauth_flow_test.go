@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// fakeOAuthServer is a fake of Google's OAuth token, device authorization
+// and user info endpoints.
+type fakeOAuthServer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu sync.Mutex
+	// challenges maps the issued authorization codes to their PKCE
+	// challenges.
+	challenges map[string]string
+	// revoked holds the revoked tokens.
+	revoked []string
+	// scope is the scope granted with a token.
+	scope string
+}
+
+func newFakeOAuthServer(t *testing.T) *fakeOAuthServer {
+	f := &fakeOAuthServer{t: t, challenges: make(map[string]string), scope: scopePrefix + "tasks.readonly " + userInfoScope}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", f.token)
+	mux.HandleFunc("POST /revoke", f.revoke)
+	mux.HandleFunc("POST /device/code", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_url": "https://www.google.com/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("GET /oauth2/v2/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": map[string]any{"code": 401, "message": "Invalid Credentials"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"email": "user@example.com"})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// authorize issues an authorization code for the PKCE challenge of an
+// authorization URL, as if the user had granted access.
+func (f *fakeOAuthServer) authorize(authURL string) url.Values {
+	f.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatalf("invalid authorization URL %q: %v", authURL, err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("access_type") != "offline" {
+		f.t.Errorf("unexpected authorization URL: %s", authURL)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.challenges["auth-code"] = query.Get("code_challenge")
+	return url.Values{"code": {"auth-code"}, "state": {query.Get("state")}}
+}
+
+// token serves the token endpoint for authorization and device codes.
+func (f *fakeOAuthServer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+		challenge, ok := f.challenges[r.Form.Get("code")]
+		if !ok || oauth2.S256ChallengeFromVerifier(r.Form.Get("code_verifier")) != challenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		if !strings.HasPrefix(r.Form.Get("redirect_uri"), "http://127.0.0.1:") {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "redirect_uri_mismatch"})
+			return
+		}
+	case "refresh_token":
+		if r.Form.Get("refresh_token") != "refresh-token" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "Token has been expired or revoked."})
+			return
+		}
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		if r.Form.Get("device_code") != "device-code" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  "access-token",
+		"refresh_token": "refresh-token",
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+		"scope":         f.scope,
+	})
+}
+
+// revoke serves the revocation endpoint. Tokens other than the refresh
+// token are unknown, and "unavailable" cannot be revoked at the moment.
+func (f *fakeOAuthServer) revoke(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	token := r.Form.Get("token")
+	switch token {
+	case "refresh-token":
+		f.mu.Lock()
+		f.revoked = append(f.revoked, token)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	case "unavailable":
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "temporarily_unavailable", "error_description": "Try again later."})
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_token", "error_description": "Token expired or revoked"})
+	}
+}
+
+// useClientFile configures a client whose endpoints are those of the fake
+// server.
+func (f *fakeOAuthServer) useClientFile() {
+	path := filepath.Join(f.t.TempDir(), "client.json")
+	client := map[string]any{"installed": map[string]any{
+		"client_id":     "fake-client",
+		"client_secret": "fake-secret",
+		"auth_uri":      "https://accounts.example.com/auth",
+		"token_uri":     f.server.URL + "/token",
+		"revoke_uri":    f.server.URL + "/revoke",
+		"redirect_uris": []string{"http://localhost"},
+	}}
+	data, _ := json.Marshal(client)
+	os.WriteFile(path, data, 0600)
+	f.t.Setenv(ClientFileEnv, path)
+}
+
+// login returns a login against the fake server.
+// fakeClientID is the custom OAuth client of the fake server.
+const fakeClientID = "fake-client.apps.googleusercontent.com"
+
+func (f *fakeOAuthServer) login(in io.Reader, out io.Writer) *login {
+	conf, err := loadOAuthConfig(&config.Config{}, loginScopes([]string{"tasks.readonly"}))
+	if err != nil {
+		f.t.Fatalf("failed to load the OAuth config: %v", err)
+	}
+	conf.ClientID = fakeClientID
+	conf.Endpoint = oauth2.Endpoint{
+		AuthURL:       "https://accounts.example.com/auth",
+		TokenURL:      f.server.URL + "/token",
+		DeviceAuthURL: f.server.URL + "/device/code",
+		AuthStyle:     oauth2.AuthStyleInParams,
+	}
+	return &login{
+		conf:             conf,
+		userInfoEndpoint: f.server.URL + "/",
+		openURL: func(string) error {
+			return fmt.Errorf("no browser in tests")
+		},
+		in:  in,
+		out: out,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// checkSaved verifies that the token of the user was saved to the cache.
+func checkSaved(t *testing.T, user string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if user != "user@example.com" {
+		t.Errorf("expected user@example.com, got %s", user)
+	}
+	cache, err := loadTokenCache()
+	if err != nil {
+		t.Fatalf("failed to load token cache: %v", err)
+	}
+	token := cache.Tokens[user]
+	if token == nil || token.RefreshToken != "refresh-token" {
+		t.Fatalf("expected the token to be saved, got %+v", token)
+	}
+	if token.ClientID != fakeClientID || !sameScopes(token.Scopes, []string{"tasks.readonly"}) {
+		t.Errorf("expected the client and the granted scopes to be recorded, got %s and %v", token.ClientID, token.Scopes)
+	}
+}
+
+func TestLogin_Browser(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	l := fake.login(strings.NewReader(""), io.Discard)
+
+	// The browser is redirected to the loopback listener after consent.
+	l.openURL = func(authURL string) error {
+		u, _ := url.Parse(authURL)
+		callback := u.Query().Get("redirect_uri") + "?" + fake.authorize(authURL).Encode()
+		go func() {
+			resp, err := http.Get(callback)
+			if err != nil {
+				t.Errorf("callback failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+
+	user, err := l.run(context.Background(), BrowserFlow)
+	checkSaved(t, user, err)
+}
+
+// pasteWriter pastes the redirect URL of the first authorization URL that
+// is written to it, like a user of the manual flow.
+type pasteWriter struct {
+	fake  *fakeOAuthServer
+	paste io.WriteCloser
+	buf   bytes.Buffer
+	done  bool
+}
+
+func (w *pasteWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	match := regexp.MustCompile(`https://accounts\.example\.com/auth\S+`).Find(w.buf.Bytes())
+	if match != nil && !w.done {
+		w.done = true
+		authURL := string(match)
+		u, _ := url.Parse(authURL)
+		redirect := u.Query().Get("redirect_uri") + "?" + w.fake.authorize(authURL).Encode()
+		go func() {
+			fmt.Fprintf(w.paste, "\n  %s  \n", redirect)
+			w.paste.Close()
+		}()
+	}
+	return len(p), nil
+}
+
+func TestLogin_Manual(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	in, paste := io.Pipe()
+	out := &pasteWriter{fake: fake, paste: paste}
+
+	user, err := fake.login(in, out).run(context.Background(), ManualFlow)
+	checkSaved(t, user, err)
+	if !strings.Contains(out.buf.String(), "Paste the URL") {
+		t.Errorf("expected instructions, got %q", out.buf.String())
+	}
+}
+
+func TestLogin_ManualEOF(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+
+	_, err := fake.login(strings.NewReader(""), io.Discard).run(context.Background(), ManualFlow)
+	if err == nil || !strings.Contains(err.Error(), "failed to read the authorization code") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+}
+
+func TestLogin_Device(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	var out bytes.Buffer
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	user, err := fake.login(strings.NewReader(""), &out).run(ctx, DeviceFlow)
+	checkSaved(t, user, err)
+	if !strings.Contains(out.String(), "https://www.google.com/device") || !strings.Contains(out.String(), "ABCD-EFGH") {
+		t.Errorf("expected the verification URL and user code, got %q", out.String())
+	}
+}
+
+func TestLogin_DeviceWithBuiltInClient(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	l := fake.login(strings.NewReader(""), io.Discard)
+	l.conf.ClientID = defaultClientID
+
+	_, err := l.run(context.Background(), DeviceFlow)
+	if err == nil || !strings.Contains(err.Error(), "TVs and Limited Input devices") {
+		t.Errorf("expected the device flow to be refused, got %v", err)
+	}
+}
+
+func TestLogin_ScopesNotGranted(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	// The user unticked the Tasks scope on the consent screen
+	fake.scope = userInfoScope
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := fake.login(strings.NewReader(""), io.Discard).run(ctx, DeviceFlow)
+	if err == nil || !strings.Contains(err.Error(), scopePrefix+"tasks.readonly") {
+		t.Errorf("expected the missing scope to be named, got %v", err)
+	}
+	if accounts, _ := ListAccounts(); len(accounts) != 0 {
+		t.Errorf("expected no token to be saved, got %v", accounts)
+	}
+}
+
+func TestParsePasted(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+		hasError bool
+	}{
+		{"code", "  4/0AbCd  ", "4/0AbCd", false},
+		{"redirect URL", "http://127.0.0.1:4242/callback?state=s&code=4%2F0AbCd&scope=x", "4/0AbCd", false},
+		{"wrong state", "http://127.0.0.1:4242/callback?state=other&code=c", "", true},
+		{"denied", "http://127.0.0.1:4242/callback?state=s&error=access_denied", "", true},
+		{"missing code", "http://127.0.0.1:4242/callback?state=s", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := parsePasted(tc.text, "s")
+			if (err != nil) != tc.hasError {
+				t.Fatalf("expected error %v, got %v", tc.hasError, err)
+			}
+			if code != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, code)
+			}
+		})
+	}
+}
+
+func TestLogin_OtherAccount(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	l := fake.login(strings.NewReader(""), io.Discard)
+	l.account = "other@example.com"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := l.run(ctx, DeviceFlow)
+	if err == nil || !strings.Contains(err.Error(), "logged in as user@example.com instead of other@example.com") {
+		t.Errorf("expected an account mismatch, got %v", err)
+	}
+	if accounts, _ := ListAccounts(); len(accounts) != 0 {
+		t.Errorf("expected no token to be saved, got %v", accounts)
+	}
+}
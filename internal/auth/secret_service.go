@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The attributes of the secret that holds the tokens.
+const (
+	secretService = "gtasks"
+	secretAccount = "tokens"
+)
+
+// secretServiceStore keeps the tokens in the secret service of the operating
+// system: the Secret Service API (e.g. GNOME Keyring or KWallet) on Linux
+// through secret-tool, and the login keychain on macOS through security. The
+// whole token cache is stored as a single secret.
+type secretServiceStore struct {
+	goos string
+	// run runs a command with the given standard input and returns its
+	// standard output.
+	run func(stdin []byte, name string, args ...string) ([]byte, error)
+	// lookPath reports whether a command is installed.
+	lookPath func(name string) (string, error)
+}
+
+// newSecretServiceStore returns a secret service store for the current
+// operating system.
+func newSecretServiceStore() *secretServiceStore {
+	return &secretServiceStore{goos: runtime.GOOS, run: runCommand, lookPath: exec.LookPath}
+}
+
+// command returns the name of the command that talks to the secret service,
+// or an empty string if the operating system is not supported.
+func (s *secretServiceStore) command() string {
+	switch s.goos {
+	case "linux", "freebsd", "openbsd":
+		return "secret-tool"
+	case "darwin":
+		return "security"
+	}
+	return ""
+}
+
+// available reports whether the secret service can be used.
+func (s *secretServiceStore) available() bool {
+	command := s.command()
+	if command == "" {
+		return false
+	}
+	_, err := s.lookPath(command)
+	return err == nil
+}
+
+// Name returns the name of the backend.
+func (s *secretServiceStore) Name() string {
+	return SecretServiceStore
+}
+
+// Load reads the tokens from the secret service.
+func (s *secretServiceStore) Load() (*TokenCache, error) {
+	var out []byte
+	var err error
+	if s.command() == "security" {
+		out, err = s.run(nil, "security", "find-generic-password", "-s", secretService, "-a", secretAccount, "-w")
+	} else {
+		out, err = s.run(nil, "secret-tool", "lookup", "service", secretService, "account", secretAccount)
+	}
+	if isNotFound(err) {
+		return newTokenCache(), nil // No secret stored yet.
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from the secret service: %w", err)
+	}
+	return decodeTokenCache(bytes.TrimSpace(out))
+}
+
+// Save writes the tokens to the secret service, replacing the previous
+// secret. The secret is passed on standard input so that it does not show
+// up in the process list.
+func (s *secretServiceStore) Save(cache *TokenCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if s.command() == "security" {
+		// In interactive mode, security reads the command from standard
+		// input. -X takes the password in hex.
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -l %q -X %s\n", secretService, secretAccount, "gtasks credentials", hex.EncodeToString(data))
+		_, err = s.run([]byte(command), "security", "-i")
+	} else {
+		_, err = s.run(data, "secret-tool", "store", "--label=gtasks credentials", "service", secretService, "account", secretAccount)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write to the secret service: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the tokens from the secret service.
+func (s *secretServiceStore) Delete() error {
+	var err error
+	if s.command() == "security" {
+		_, err = s.run(nil, "security", "delete-generic-password", "-s", secretService, "-a", secretAccount)
+	} else {
+		_, err = s.run(nil, "secret-tool", "clear", "service", secretService, "account", secretAccount)
+	}
+	// Both commands fail if there is no secret to delete.
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete from the secret service: %w", err)
+	}
+	return nil
+}
+
+// commandError is returned for a command that ran but failed.
+type commandError struct {
+	name   string
+	code   int
+	stderr string
+}
+
+// Error returns the exit code and the standard error of the command.
+func (e *commandError) Error() string {
+	return fmt.Sprintf("%s exited with code %d: %s", e.name, e.code, e.stderr)
+}
+
+// isNotFound reports whether a command failed because there is no secret.
+// Other failures, such as a locked keyring, a cancelled unlock prompt or an
+// unreachable D-Bus, must not be mistaken for an empty store, since saving
+// over it would drop the tokens of all other accounts.
+func isNotFound(err error) bool {
+	var failed *commandError
+	if !errors.As(err, &failed) {
+		return false
+	}
+	switch failed.name {
+	case "security":
+		// errSecItemNotFound
+		return failed.code == 44
+	case "secret-tool":
+		// secret-tool fails silently if there is no matching item, and
+		// prints an error for everything else.
+		return failed.code == 1 && failed.stderr == ""
+	}
+	return false
+}
+
+// runCommand runs a command with the given standard input and returns its
+// standard output.
+func runCommand(stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out, &commandError{name: name, code: exitErr.ExitCode(), stderr: strings.TrimSpace(stderr.String())}
+	}
+	return out, err
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// TokenStatus describes the stored token of an account.
+type TokenStatus struct {
+	Account  string
+	ClientID string
+	Scopes   []string
+	// Expiry is when the access token expires.
+	Expiry time.Time
+	// Refreshed is when the token was last issued or refreshed. It is zero
+	// for tokens saved by older versions.
+	Refreshed time.Time
+	// Checked reports whether the token was refreshed to check that
+	// refreshing works.
+	Checked bool
+	// RefreshErr is the error of the refresh, or of the check against the
+	// configuration.
+	RefreshErr error
+}
+
+// StatusOptions holds the parameters for Status.
+type StatusOptions struct {
+	// CheckRefresh refreshes every token to check that refreshing works.
+	// The refreshed tokens are saved.
+	CheckRefresh bool
+}
+
+// Status returns the status of the tokens of all accounts, sorted by
+// account.
+func Status(ctx context.Context, opts StatusOptions) ([]TokenStatus, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	cache, err := loadTokenCache()
+	if err != nil {
+		return nil, err
+	}
+	conf, err := loadOAuthConfig(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []TokenStatus
+	refreshed := make(map[string]*StoredToken)
+	for user, token := range cache.Tokens {
+		status := TokenStatus{Account: user, ClientID: token.ClientID, Scopes: token.Scopes}
+		if status.ClientID == "" {
+			status.ClientID = defaultClientID
+		}
+		if len(status.Scopes) == 0 {
+			status.Scopes = DefaultScopes
+		}
+
+		status.RefreshErr = checkToken(user, token, conf.ClientID, cfg.AccountScopes(user))
+		if status.RefreshErr == nil && opts.CheckRefresh {
+			// Without an access token, the token source always refreshes.
+			status.Checked = true
+			newToken, err := conf.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+			if err != nil {
+				status.RefreshErr = fmt.Errorf("%w: %w", ErrTokenRefreshFailed, err)
+			} else {
+				if newToken.RefreshToken == "" {
+					newToken.RefreshToken = token.RefreshToken
+				}
+				token = &StoredToken{Token: *newToken, ClientID: token.ClientID, Scopes: token.Scopes, Refreshed: time.Now()}
+				refreshed[user] = token
+			}
+		}
+		status.Expiry = token.Expiry
+		status.Refreshed = token.Refreshed
+		statuses = append(statuses, status)
+	}
+
+	if len(refreshed) > 0 {
+		if err := saveTokens(refreshed); err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(statuses, func(a, b TokenStatus) int {
+		return strings.Compare(a.Account, b.Account)
+	})
+	return statuses, nil
+}
+
+// Revoke revokes the token of the given user at the revocation endpoint of
+// the OAuth client and removes it from the cache. Revoking the refresh token
+// also invalidates the access tokens issued for it. The token is kept if it
+// cannot be revoked.
+func Revoke(ctx context.Context, user string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cache, err := loadTokenCache()
+	if err != nil {
+		return err
+	}
+	token, ok := cache.Tokens[user]
+	if !ok {
+		return ErrCredentialsNotFound
+	}
+	revokeURL, err := loadRevokeURL(cfg)
+	if err != nil {
+		return err
+	}
+
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+	if err := revoke(ctx, revokeURL, value); err != nil {
+		return err
+	}
+
+	return deleteToken(user)
+}
+
+// revoke revokes a token at the revocation endpoint. A token that is
+// already invalid counts as revoked.
+func revoke(ctx context.Context, revokeURL, token string) error {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke the token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	var oauthErr struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	json.Unmarshal(body, &oauthErr)
+	if oauthErr.Error == "invalid_token" {
+		return nil
+	}
+	if oauthErr.Error != "" {
+		return fmt.Errorf("failed to revoke the token: %s (%s)", oauthErr.Error, oauthErr.Description)
+	}
+	return errors.New("failed to revoke the token: " + resp.Status)
+}
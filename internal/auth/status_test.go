@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// saveFakeTokens saves tokens of the fake client for the given users and
+// refresh tokens.
+func saveFakeTokens(t *testing.T, refreshTokens map[string]string) {
+	t.Helper()
+	cache := newTokenCache()
+	for user, refreshToken := range refreshTokens {
+		cache.Tokens[user] = &StoredToken{
+			Token:    oauth2.Token{AccessToken: "old-access-token", RefreshToken: refreshToken, Expiry: time.Now().Add(-time.Hour)},
+			ClientID: "fake-client",
+			Scopes:   DefaultScopes,
+		}
+	}
+	if err := saveTokens(cache.Tokens); err != nil {
+		t.Fatalf("failed to save tokens: %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	fake.useClientFile()
+	saveFakeTokens(t, map[string]string{"valid@example.com": "refresh-token", "revoked@example.com": "revoked-token"})
+	cache, _ := loadTokenCache()
+	cache.Tokens["legacy@example.com"] = &StoredToken{Token: oauth2.Token{RefreshToken: "refresh-token"}}
+	saveTokens(cache.Tokens)
+
+	t.Run("without refresh", func(t *testing.T) {
+		statuses, err := Status(context.Background(), StatusOptions{})
+		if err != nil {
+			t.Fatalf("failed to get the status: %v", err)
+		}
+		accounts := []string{statuses[0].Account, statuses[1].Account, statuses[2].Account}
+		if !slices.Equal(accounts, []string{"legacy@example.com", "revoked@example.com", "valid@example.com"}) {
+			t.Fatalf("expected the accounts in order, got %v", accounts)
+		}
+		if statuses[2].Checked || statuses[2].RefreshErr != nil {
+			t.Errorf("expected no refresh, got %+v", statuses[2])
+		}
+		// The legacy token belongs to the built-in client.
+		if statuses[0].ClientID != defaultClientID || !errors.Is(statuses[0].RefreshErr, ErrTokenMismatch) {
+			t.Errorf("expected a client mismatch, got %+v", statuses[0])
+		}
+	})
+
+	t.Run("with refresh", func(t *testing.T) {
+		statuses, err := Status(context.Background(), StatusOptions{CheckRefresh: true})
+		if err != nil {
+			t.Fatalf("failed to get the status: %v", err)
+		}
+		revoked, valid := statuses[1], statuses[2]
+		if !revoked.Checked || !errors.Is(revoked.RefreshErr, ErrTokenRefreshFailed) {
+			t.Errorf("expected the refresh to fail, got %+v", revoked)
+		}
+		if !valid.Checked || valid.RefreshErr != nil {
+			t.Errorf("expected the refresh to work, got %+v", valid)
+		}
+		if time.Until(valid.Expiry) < 30*time.Minute || time.Since(valid.Refreshed) > time.Minute {
+			t.Errorf("expected a new expiry and refresh time, got %v and %v", valid.Expiry, valid.Refreshed)
+		}
+
+		// The refreshed token is saved with its client and scopes.
+		cache, _ := loadTokenCache()
+		token := cache.Tokens["valid@example.com"]
+		if token.AccessToken != "access-token" || token.RefreshToken != "refresh-token" || token.ClientID != "fake-client" {
+			t.Errorf("expected the refreshed token to be saved, got %+v", token)
+		}
+	})
+}
+
+func TestRevoke(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fake := newFakeOAuthServer(t)
+	fake.useClientFile()
+	saveFakeTokens(t, map[string]string{"valid@example.com": "refresh-token", "expired@example.com": "expired-token", "busy@example.com": "unavailable"})
+
+	if err := Revoke(context.Background(), "valid@example.com"); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if !slices.Equal(fake.revoked, []string{"refresh-token"}) {
+		t.Errorf("expected the refresh token to be revoked, got %v", fake.revoked)
+	}
+
+	// A token that is already invalid counts as revoked.
+	if err := Revoke(context.Background(), "expired@example.com"); err != nil {
+		t.Errorf("expected an invalid token to count as revoked, got %v", err)
+	}
+
+	// A token that cannot be revoked is kept.
+	if err := Revoke(context.Background(), "busy@example.com"); err == nil {
+		t.Error("expected an error for a failed revocation")
+	}
+	accounts, _ := ListAccounts()
+	if !slices.Equal(accounts, []string{"busy@example.com"}) {
+		t.Errorf("expected only the token that could not be revoked to be kept, got %v", accounts)
+	}
+
+	if err := Revoke(context.Background(), "missing@example.com"); !errors.Is(err, ErrCredentialsNotFound) {
+		t.Errorf("expected ErrCredentialsNotFound, got %v", err)
+	}
+}
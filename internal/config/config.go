@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/yanicksenn/gtasks/internal/lockedfile"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,7 +16,62 @@ const (
 
 // Config represents the application's configuration.
 type Config struct {
-	ActiveAccount string `yaml:"active_account"`
+	ActiveAccount string      `yaml:"active_account"`
+	Retry         RetryConfig `yaml:"retry,omitempty"`
+	// CredentialStore selects where the OAuth tokens are stored:
+	// plaintext (the default), encrypted-file or secret-service.
+	CredentialStore string `yaml:"credential_store,omitempty"`
+	// OAuthClientFile is the path to the JSON file of an OAuth client, in
+	// the format downloaded from the Google Cloud console. The built-in
+	// client is used if it is empty.
+	OAuthClientFile string `yaml:"oauth_client_file,omitempty"`
+	// Scopes are the OAuth scopes requested when logging in, as URLs or
+	// short names such as tasks.readonly. The default scopes are used if
+	// it is empty.
+	Scopes []string `yaml:"scopes,omitempty"`
+	// Accounts holds the settings of individual accounts.
+	Accounts map[string]AccountConfig `yaml:"accounts,omitempty"`
+}
+
+// AccountConfig holds the settings of an account.
+type AccountConfig struct {
+	// Scopes replaces the scopes of the configuration for this account.
+	Scopes []string `yaml:"scopes,omitempty"`
+}
+
+// AccountScopes returns the scopes configured for an account, falling back
+// to the scopes of the configuration.
+func (c *Config) AccountScopes(account string) []string {
+	if scopes := c.Accounts[account].Scopes; len(scopes) > 0 {
+		return scopes
+	}
+	return c.Scopes
+}
+
+// SetAccountScopes sets the scopes of an account.
+func (c *Config) SetAccountScopes(account string, scopes []string) {
+	if c.Accounts == nil {
+		c.Accounts = make(map[string]AccountConfig)
+	}
+	settings := c.Accounts[account]
+	settings.Scopes = scopes
+	c.Accounts[account] = settings
+}
+
+// RetryConfig configures how failed API calls are retried. Zero values
+// select the defaults.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts per API call,
+	// including the first one. Set it to 1 to disable retries.
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// InitialDelay is the delay before the first retry. It doubles with
+	// every further retry.
+	InitialDelay time.Duration `yaml:"initial_delay,omitempty"`
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration `yaml:"max_delay,omitempty"`
+	// RetryNonIdempotent also retries calls that are not idempotent, such
+	// as creating a task. This may create duplicates.
+	RetryNonIdempotent bool `yaml:"retry_non_idempotent,omitempty"`
 }
 
 // GetConfigPath returns the path to the configuration file.
@@ -34,11 +91,10 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
 		return &Config{}, nil // Return a default config if file doesn't exist
 	}
-
-	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -51,21 +107,43 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
-// Save writes the configuration to the config file.
+// Save writes the configuration to the config file, replacing the settings
+// saved by other processes since it was loaded. Use Update to change
+// individual settings.
 func (c *Config) Save() error {
 	path, err := GetConfigPath()
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	data, err := yaml.Marshal(c)
+	if err != nil {
 		return err
 	}
 
-	data, err := yaml.Marshal(c)
+	return lockedfile.Transform(path, 0644, func([]byte) ([]byte, error) {
+		return data, nil
+	})
+}
+
+// Update applies fn to the latest configuration and saves it. The config
+// file is locked and read again, so that settings changed by other
+// processes since the configuration was loaded are kept. Nothing is saved if
+// fn returns an error.
+func Update(fn func(cfg *Config) error) error {
+	path, err := GetConfigPath()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	return lockedfile.Transform(path, 0644, func(data []byte) ([]byte, error) {
+		var cfg Config
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		if err := fn(&cfg); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(&cfg)
+	})
 }
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -34,3 +37,59 @@ func TestConfig(t *testing.T) {
 		t.Errorf("expected active account to be 'test@example.com', got '%s'", loadedCfg.ActiveAccount)
 	}
 }
+
+func TestAccountScopes(t *testing.T) {
+	cfg := &Config{Scopes: []string{"tasks"}}
+	cfg.SetAccountScopes("reader@example.com", []string{"tasks.readonly"})
+
+	if scopes := cfg.AccountScopes("reader@example.com"); len(scopes) != 1 || scopes[0] != "tasks.readonly" {
+		t.Errorf("expected the scopes of the account, got %v", scopes)
+	}
+	if scopes := cfg.AccountScopes("other@example.com"); len(scopes) != 1 || scopes[0] != "tasks" {
+		t.Errorf("expected the scopes of the configuration, got %v", scopes)
+	}
+}
+
+func TestUpdate_ConcurrentWriters(t *testing.T) {
+	os.Setenv("HOME", t.TempDir())
+
+	// Each writer changes its own setting, which none of the others may
+	// overwrite.
+	const writers = 10
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := Update(func(cfg *Config) error {
+				cfg.SetAccountScopes(fmt.Sprintf("user%d@example.com", i), []string{"tasks"})
+				return nil
+			})
+			if err != nil {
+				t.Errorf("failed to update the config: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if len(cfg.Accounts) != writers {
+		t.Errorf("expected the settings of %d accounts, got %v", writers, cfg.Accounts)
+	}
+
+	// A failing update leaves the config unchanged.
+	failure := errors.New("failure")
+	err = Update(func(cfg *Config) error {
+		cfg.ActiveAccount = "other@example.com"
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of the update, got %v", err)
+	}
+	if cfg, _ := Load(); cfg.ActiveAccount != "" {
+		t.Errorf("expected no active account, got %s", cfg.ActiveAccount)
+	}
+}
@@ -0,0 +1,175 @@
+// Package dates parses the due dates accepted on the command line, such as
+// "tomorrow", "next friday", "+3d" or "in 2 weeks".
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dueFormat is the format of due dates in the Google Tasks API. The API only
+// keeps the date, so the time is always midnight UTC.
+const dueFormat = "2006-01-02T00:00:00.000Z"
+
+// weekdays maps weekday names in several languages to weekdays.
+var weekdays = map[string]time.Weekday{
+	// English
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	// German
+	"sonntag": time.Sunday, "montag": time.Monday, "dienstag": time.Tuesday, "mittwoch": time.Wednesday,
+	"donnerstag": time.Thursday, "freitag": time.Friday, "samstag": time.Saturday, "sonnabend": time.Saturday,
+	// French
+	"dimanche": time.Sunday, "lundi": time.Monday, "mardi": time.Tuesday, "mercredi": time.Wednesday,
+	"jeudi": time.Thursday, "vendredi": time.Friday, "samedi": time.Saturday,
+	// Spanish
+	"domingo": time.Sunday, "lunes": time.Monday, "martes": time.Tuesday, "miércoles": time.Wednesday,
+	"miercoles": time.Wednesday, "jueves": time.Thursday, "viernes": time.Friday, "sábado": time.Saturday,
+	"sabado": time.Saturday,
+	// Italian
+	"domenica": time.Sunday, "lunedì": time.Monday, "lunedi": time.Monday, "martedì": time.Tuesday,
+	"martedi": time.Tuesday, "mercoledì": time.Wednesday, "mercoledi": time.Wednesday, "giovedì": time.Thursday,
+	"giovedi": time.Thursday, "venerdì": time.Friday, "venerdi": time.Friday, "sabato": time.Saturday,
+	// Dutch
+	"zondag": time.Sunday, "maandag": time.Monday, "dinsdag": time.Tuesday, "woensdag": time.Wednesday,
+	"donderdag": time.Thursday, "vrijdag": time.Friday, "zaterdag": time.Saturday,
+	// Portuguese
+	"segunda": time.Monday, "segunda-feira": time.Monday, "terça": time.Tuesday, "terça-feira": time.Tuesday,
+	"terca": time.Tuesday, "terca-feira": time.Tuesday, "quarta": time.Wednesday, "quarta-feira": time.Wednesday,
+	"quinta": time.Thursday, "quinta-feira": time.Thursday, "sexta": time.Friday, "sexta-feira": time.Friday,
+}
+
+// offsetPattern matches a relative offset such as +3d or -2w at the end of
+// a date.
+var offsetPattern = regexp.MustCompile(`^(.*?)([+-]\d+)([dwmy])$`)
+
+// inPattern matches a relative date such as "in 2 weeks" or "in a month".
+var inPattern = regexp.MustCompile(`^in (\d+|an?) (day|week|month|year)s?$`)
+
+// Parse parses a date relative to now and returns it as midnight UTC of that
+// date. Relative dates are resolved in the location of now. It understands
+//
+//	2026-11-01, RFC3339 timestamps
+//	today, tomorrow, yesterday
+//	friday, next friday, this friday (also in German, French, Spanish,
+//	Italian, Dutch and Portuguese)
+//	next week, next month, next year
+//	+3d, -1w, +2m, +1y, today+3d, friday+1w
+//	in 3 days, in 2 weeks, in a month, in 1 year
+//
+// Weekdays refer to the next such day after today; "this friday" is today if
+// today is a Friday.
+func Parse(s string, now time.Time) (time.Time, error) {
+	input := strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	if input == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	if date, err := time.Parse("2006-01-02", input); err == nil {
+		return date, nil
+	}
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(input)); err == nil {
+		// Keep the date as written, whatever its offset.
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if m := inPattern.FindStringSubmatch(input); m != nil {
+		n := 1
+		if m[1] != "a" && m[1] != "an" {
+			n, _ = strconv.Atoi(m[1])
+		}
+		return addUnit(today, n, m[2][0]), nil
+	}
+
+	base, n, unit := input, 0, byte(0)
+	if m := offsetPattern.FindStringSubmatch(input); m != nil {
+		base, unit = strings.TrimSpace(m[1]), m[3][0]
+		n, _ = strconv.Atoi(m[2])
+	}
+
+	date, ok := parseBase(base, today)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid date %q (expected e.g. today, tomorrow, next friday, +3d, in 2 weeks or 2026-11-01)", s)
+	}
+	if unit != 0 {
+		date = addUnit(date, n, unit)
+	}
+	return date, nil
+}
+
+// parseBase parses a date without an offset. An empty base is today.
+func parseBase(base string, today time.Time) (time.Time, bool) {
+	switch base {
+	case "", "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "next week":
+		return today.AddDate(0, 0, 7), true
+	case "next month":
+		return today.AddDate(0, 1, 0), true
+	case "next year":
+		return today.AddDate(1, 0, 0), true
+	}
+	if date, err := time.Parse("2006-01-02", base); err == nil {
+		return date, true
+	}
+
+	name, includeToday := base, false
+	if rest, ok := strings.CutPrefix(base, "next "); ok {
+		name = rest
+	} else if rest, ok := strings.CutPrefix(base, "this "); ok {
+		name, includeToday = rest, true
+	}
+	weekday, ok := weekdays[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if days == 0 && !includeToday {
+		days = 7
+	}
+	return today.AddDate(0, 0, days), true
+}
+
+// addUnit adds n days (d), weeks (w), months (m) or years (y) to the date.
+func addUnit(date time.Time, n int, unit byte) time.Time {
+	switch unit {
+	case 'w':
+		return date.AddDate(0, 0, 7*n)
+	case 'm':
+		return date.AddDate(0, n, 0)
+	case 'y':
+		return date.AddDate(n, 0, 0)
+	}
+	return date.AddDate(0, 0, n)
+}
+
+// Format formats a date the way the Google Tasks API expects due dates.
+func Format(date time.Time) string {
+	return date.Format(dueFormat)
+}
+
+// ParseDue parses a date relative to now and formats it as a due date. An
+// empty string is returned unchanged.
+func ParseDue(s string, now time.Time) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	date, err := Parse(s, now)
+	if err != nil {
+		return "", err
+	}
+	return Format(date), nil
+}
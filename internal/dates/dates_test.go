@@ -0,0 +1,83 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// Wednesday, late in the evening in a zone ahead of UTC.
+	now := time.Date(2026, 10, 14, 23, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"2026-11-01", "2026-11-01"},
+		{"2026-11-01T15:00:00Z", "2026-11-01"},
+		{"2026-11-01T00:30:00+02:00", "2026-11-01"},
+		{"today", "2026-10-14"},
+		{"Today", "2026-10-14"},
+		{"tomorrow", "2026-10-15"},
+		{"yesterday", "2026-10-13"},
+		{"friday", "2026-10-16"},
+		{"next friday", "2026-10-16"},
+		{"fri", "2026-10-16"},
+		{"wednesday", "2026-10-21"},
+		{"this wednesday", "2026-10-14"},
+		{"next  Monday", "2026-10-19"},
+		{"freitag", "2026-10-16"},
+		{"vendredi", "2026-10-16"},
+		{"miércoles", "2026-10-21"},
+		{"sábado", "2026-10-17"},
+		{"giovedì", "2026-10-15"},
+		{"zondag", "2026-10-18"},
+		{"segunda-feira", "2026-10-19"},
+		{"next week", "2026-10-21"},
+		{"next month", "2026-11-14"},
+		{"next year", "2027-10-14"},
+		{"+3d", "2026-10-17"},
+		{"-1w", "2026-10-07"},
+		{"+1m", "2026-11-14"},
+		{"+1y", "2027-10-14"},
+		{"today+7d", "2026-10-21"},
+		{"friday+1w", "2026-10-23"},
+		{"2026-12-24-2d", "2026-12-22"},
+		{"in 3 days", "2026-10-17"},
+		{"in 2 weeks", "2026-10-28"},
+		{"in a month", "2026-11-14"},
+		{"in 1 year", "2027-10-14"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			date, err := Parse(tc.input, now)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			expected := tc.expected + "T00:00:00.000Z"
+			if Format(date) != expected {
+				t.Errorf("expected %s, got %s", expected, Format(date))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	for _, input := range []string{"", "soon", "next", "in weeks", "+3q", "2026-13-01", "last friday"} {
+		if _, err := Parse(input, now); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	if due, err := ParseDue("", now); err != nil || due != "" {
+		t.Errorf("expected an empty due date to be kept, got %q (%v)", due, err)
+	}
+	if due, err := ParseDue("tomorrow", now); err != nil || due != "2026-10-15T00:00:00.000Z" {
+		t.Errorf("expected 2026-10-15T00:00:00.000Z, got %q (%v)", due, err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package export converts tasks to and from file formats used by other
+// tools, such as iCalendar.
+package export
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yanicksenn/gtasks/internal/ui"
+	"google.golang.org/api/tasks/v1"
+)
+
+// Item is a task read from or written to a file. UID identifies the task
+// across exports and imports, and ParentUID links it to its parent.
+type Item struct {
+	UID       string
+	ParentUID string
+	Task      *tasks.Task
+	// TaskList is the title of the task list the task belongs to, for
+	// formats that record it. Empty means the list being imported into.
+	TaskList string
+	// Attributes are tokens of formats such as todo.txt that Google Tasks
+	// has no field for. They are kept on a line of their own in the notes
+	// (see SetAttributes). Nil means the format has no attributes, and the
+	// notes are taken from Task.
+	Attributes []string
+	// LocalUID marks UIDs that only link the items of one file, for
+	// formats without identifiers such as markdown. They are neither
+	// matched against existing tasks nor recorded in the notes.
+	LocalUID bool
+}
+
+// uidLine matches the line that records the UID of an imported task at the
+// end of its notes.
+var uidLine = regexp.MustCompile(`(?:^|\n)\[uid:([^\]\n]+)\]$`)
+
+// SplitUID splits the UID recorded by SetUID off the notes. It returns the
+// notes unchanged and an empty UID if there is none.
+func SplitUID(notes string) (string, string) {
+	loc := uidLine.FindStringSubmatchIndex(notes)
+	if loc == nil {
+		return notes, ""
+	}
+	return notes[:loc[0]], notes[loc[2]:loc[3]]
+}
+
+// SetUID records the UID on its own line at the end of the notes, replacing
+// a UID that was recorded before. Google Tasks has no field for foreign
+// identifiers, so this is what makes imports idempotent.
+func SetUID(notes, uid string) string {
+	notes, _ = SplitUID(notes)
+	line := "[uid:" + uid + "]"
+	if notes == "" {
+		return line
+	}
+	return notes + "\n" + line
+}
+
+// SplitAttributes splits the attributes line recorded by SetAttributes off
+// the notes. A recorded UID is dropped as well.
+func SplitAttributes(notes string) (string, []string) {
+	notes, _ = SplitUID(notes)
+	body, last := "", notes
+	if i := strings.LastIndexByte(notes, '\n'); i >= 0 {
+		body, last = notes[:i], notes[i+1:]
+	}
+	fields := strings.Fields(last)
+	if len(fields) == 0 {
+		return notes, nil
+	}
+	for _, field := range fields {
+		if !isAttribute(field) {
+			return notes, nil
+		}
+	}
+	return body, fields
+}
+
+// SetAttributes records the attributes on the last line of the notes before
+// a recorded UID, replacing attributes that were recorded before. The rest
+// of the notes is kept.
+func SetAttributes(notes string, attributes []string) string {
+	_, uid := SplitUID(notes)
+	body, _ := SplitAttributes(notes)
+	if len(attributes) > 0 {
+		line := strings.Join(attributes, " ")
+		if body == "" {
+			body = line
+		} else {
+			body += "\n" + line
+		}
+	}
+	if uid != "" {
+		return SetUID(body, uid)
+	}
+	return body
+}
+
+// ItemsFromTasks converts the tasks of a list into items, parents before
+// their subtasks. A task keeps the UID it was imported with; otherwise its
+// ID is used.
+func ItemsFromTasks(items []*tasks.Task) []Item {
+	uids := make(map[string]string, len(items))
+	for _, task := range items {
+		_, uid := SplitUID(task.Notes)
+		if uid == "" {
+			uid = task.Id
+		}
+		uids[task.Id] = uid
+	}
+
+	var result []Item
+	for _, node := range ui.TaskTree(items) {
+		task := *node.Task
+		task.Notes, _ = SplitUID(task.Notes)
+		result = append(result, Item{
+			UID:       uids[task.Id],
+			ParentUID: uids[task.Parent],
+			Task:      &task,
+		})
+	}
+	return result
+}
+
+// DateOnly returns the date part of an RFC3339 timestamp. Due dates only
+// carry a date, so it is used to compare them.
+func DateOnly(s string) string {
+	date, _, _ := strings.Cut(s, "T")
+	return date
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"testing"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestUID(t *testing.T) {
+	testCases := []struct {
+		notes         string
+		uid           string
+		expectedNotes string
+	}{
+		{"", "a", "[uid:a]"},
+		{"Some notes", "a", "Some notes\n[uid:a]"},
+		{"Some notes\n[uid:old]", "new", "Some notes\n[uid:new]"},
+	}
+	for _, tc := range testCases {
+		notes := SetUID(tc.notes, tc.uid)
+		if notes != tc.expectedNotes {
+			t.Errorf("SetUID(%q, %q): expected %q, got %q", tc.notes, tc.uid, tc.expectedNotes, notes)
+		}
+		rest, uid := SplitUID(notes)
+		if uid != tc.uid {
+			t.Errorf("SplitUID(%q): expected UID %q, got %q", notes, tc.uid, uid)
+		}
+		if expected, _ := SplitUID(tc.notes); rest != expected {
+			t.Errorf("SplitUID(%q): expected notes %q, got %q", notes, expected, rest)
+		}
+	}
+
+	if notes, uid := SplitUID("Mentions [uid:x] inline"); uid != "" || notes != "Mentions [uid:x] inline" {
+		t.Errorf("expected an inline marker to be ignored, got %q, %q", notes, uid)
+	}
+}
+
+func TestItemsFromTasks(t *testing.T) {
+	items := ItemsFromTasks([]*tasks.Task{
+		{Id: "child", Title: "Child", Parent: "parent"},
+		{Id: "parent", Title: "Parent", Notes: "Imported\n[uid:foreign]"},
+	})
+
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].UID != "foreign" || items[0].Task.Notes != "Imported" {
+		t.Errorf("expected the parent first with its imported UID, got %+v", items[0])
+	}
+	if items[1].UID != "child" || items[1].ParentUID != "foreign" {
+		t.Errorf("expected the child to refer to its parent's UID, got %+v", items[1])
+	}
+}
@@ -0,0 +1,277 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+const (
+	icsDateFormat     = "20060102"
+	icsDateTimeFormat = "20060102T150405Z"
+	// icsLineLength is the maximum length of a line in octets, excluding
+	// the line break.
+	icsLineLength = 75
+)
+
+// ICSOptions holds the parameters for writing an iCalendar file.
+type ICSOptions struct {
+	// Name is the name of the calendar, usually the task list's title.
+	Name string
+	// Now is used as the timestamp of tasks without an update time.
+	Now time.Time
+}
+
+// WriteICS writes the items as VTODO components of an iCalendar file.
+func WriteICS(w io.Writer, items []Item, opts ICSOptions) error {
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//gtasks//gtasks-cli//EN")
+	if opts.Name != "" {
+		iw.line("X-WR-CALNAME", escapeText(opts.Name))
+	}
+
+	for _, item := range items {
+		task := item.Task
+		stamp := opts.Now.UTC()
+		if updated, err := time.Parse(time.RFC3339, task.Updated); err == nil {
+			stamp = updated.UTC()
+		}
+
+		iw.line("BEGIN", "VTODO")
+		iw.line("UID", escapeText(item.UID))
+		iw.line("DTSTAMP", stamp.Format(icsDateTimeFormat))
+		iw.line("SUMMARY", escapeText(task.Title))
+		if task.Notes != "" {
+			iw.line("DESCRIPTION", escapeText(task.Notes))
+		}
+		if due, err := time.Parse("2006-01-02", DateOnly(task.Due)); err == nil {
+			iw.line("DUE;VALUE=DATE", due.Format(icsDateFormat))
+		}
+		if task.Status == "completed" {
+			iw.line("STATUS", "COMPLETED")
+			if task.Completed != nil {
+				if completed, err := time.Parse(time.RFC3339, *task.Completed); err == nil {
+					iw.line("COMPLETED", completed.UTC().Format(icsDateTimeFormat))
+				}
+			}
+		} else {
+			iw.line("STATUS", "NEEDS-ACTION")
+		}
+		if item.ParentUID != "" {
+			iw.line("RELATED-TO;RELTYPE=PARENT", escapeText(item.ParentUID))
+		}
+		iw.line("END", "VTODO")
+	}
+
+	iw.line("END", "VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// icsWriter writes content lines, folding them at the maximum line length.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line with the given name (including parameters)
+// and value.
+func (iw *icsWriter) line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	s := name + ":" + value
+	for first := true; ; first = false {
+		limit := icsLineLength
+		if !first {
+			limit-- // The leading space of a continuation line counts.
+			iw.w.WriteByte(' ')
+		}
+		if len(s) <= limit {
+			_, iw.err = iw.w.WriteString(s + "\r\n")
+			return
+		}
+		// Do not split multi-byte characters.
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		iw.w.WriteString(s[:cut] + "\r\n")
+		s = s[cut:]
+	}
+}
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// unescapeText reverses escapeText.
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// icsProperty is a parsed content line.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseProperty parses a content line such as DUE;VALUE=DATE:20261101.
+func parseProperty(line string) (icsProperty, error) {
+	// The value starts at the first colon outside of a quoted parameter.
+	inQuotes, colon := false, -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icsProperty{}, fmt.Errorf("invalid content line %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+// ReadICS reads the VTODO components of an iCalendar file. Other components
+// are ignored.
+func ReadICS(r io.Reader) ([]Item, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	var current *Item
+	depth := 0 // Nesting depth of components inside the current VTODO.
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VTODO") && current == nil:
+			current = &Item{Task: &tasks.Task{Status: "needsAction"}}
+			continue
+		case current == nil:
+			continue
+		case prop.name == "BEGIN":
+			depth++ // E.g. a VALARM inside the VTODO.
+			continue
+		case prop.name == "END" && depth > 0:
+			depth--
+			continue
+		case prop.name == "END":
+			items = append(items, *current)
+			current = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		task := current.Task
+		switch prop.name {
+		case "UID":
+			current.UID = unescapeText(prop.value)
+		case "SUMMARY":
+			task.Title = unescapeText(prop.value)
+		case "DESCRIPTION":
+			task.Notes = unescapeText(prop.value)
+		case "DUE":
+			due, err := parseICSTime(prop.value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid DUE: %w", n+1, err)
+			}
+			task.Due = due.Format("2006-01-02") + "T00:00:00.000Z"
+		case "STATUS":
+			if strings.EqualFold(prop.value, "COMPLETED") {
+				task.Status = "completed"
+			}
+		case "COMPLETED":
+			completed, err := parseICSTime(prop.value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid COMPLETED: %w", n+1, err)
+			}
+			s := completed.UTC().Format(time.RFC3339)
+			task.Completed = &s
+			task.Status = "completed"
+		case "RELATED-TO":
+			if reltype := prop.params["RELTYPE"]; reltype == "" || strings.EqualFold(reltype, "PARENT") {
+				current.ParentUID = unescapeText(prop.value)
+			}
+		case "LAST-MODIFIED":
+			if updated, err := parseICSTime(prop.value); err == nil {
+				task.Updated = updated.UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("unterminated VTODO %q", current.Task.Title)
+	}
+	return items, nil
+}
+
+// unfoldLines reads the content lines of an iCalendar file, joining folded
+// lines.
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICSTime parses a DATE or DATE-TIME value. Local and floating times
+// are taken as UTC, which is enough for due dates that only keep the date.
+func parseICSTime(value string) (time.Time, error) {
+	for _, layout := range []string{icsDateTimeFormat, "20060102T150405", icsDateFormat} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
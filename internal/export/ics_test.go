@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestICSRoundTrip(t *testing.T) {
+	completed := "2026-10-12T16:00:00Z"
+	items := []Item{
+		{
+			UID: "parent",
+			Task: &tasks.Task{
+				Title:   "Deploy, then verify; carefully",
+				Notes:   "First line\nSecond line with a backslash \\",
+				Due:     "2026-11-01T00:00:00.000Z",
+				Status:  "needsAction",
+				Updated: "2026-10-14T08:00:00.000Z",
+			},
+		},
+		{
+			UID:       "child",
+			ParentUID: "parent",
+			Task: &tasks.Task{
+				Title:     strings.Repeat("Ä long title ", 10),
+				Status:    "completed",
+				Completed: &completed,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, items, ICSOptions{Name: "Work", Now: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("WriteICS failed: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("expected lines of at most 75 octets, got %d: %q", len(line), line)
+		}
+	}
+	for _, expected := range []string{"X-WR-CALNAME:Work", "DUE;VALUE=DATE:20261101", "COMPLETED:20261012T160000Z", "RELATED-TO;RELTYPE=PARENT:parent", "DTSTAMP:20261014T080000Z"} {
+		if !strings.Contains(buf.String(), expected+"\r\n") {
+			t.Errorf("expected output to contain %q, got\n%s", expected, buf.String())
+		}
+	}
+
+	decoded, err := ReadICS(&buf)
+	if err != nil {
+		t.Fatalf("ReadICS failed: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(decoded))
+	}
+	for i, item := range decoded {
+		want := items[i]
+		if item.UID != want.UID || item.ParentUID != want.ParentUID {
+			t.Errorf("item %d: expected UIDs %q/%q, got %q/%q", i, want.UID, want.ParentUID, item.UID, item.ParentUID)
+		}
+		if item.Task.Title != want.Task.Title || item.Task.Notes != want.Task.Notes || item.Task.Due != want.Task.Due || item.Task.Status != want.Task.Status {
+			t.Errorf("item %d: expected %+v, got %+v", i, want.Task, item.Task)
+		}
+	}
+	if decoded[1].Task.Completed == nil || *decoded[1].Task.Completed != completed {
+		t.Errorf("expected completion time %s, got %v", completed, decoded[1].Task.Completed)
+	}
+}
+
+func TestReadICS(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:event\r\nSUMMARY:Not a task\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:todo-1\r\n" +
+		"SUMMARY:Folded\r\n  summary\r\n" +
+		"DUE;TZID=Europe/Zurich:20261101T090000\r\n" +
+		"BEGIN:VALARM\r\nDESCRIPTION:Alarm text\r\nEND:VALARM\r\n" +
+		"RELATED-TO;RELTYPE=SIBLING:todo-2\r\n" +
+		"END:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	items, err := ReadICS(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadICS failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	item := items[0]
+	if item.UID != "todo-1" || item.Task.Title != "Folded summary" || item.Task.Due != "2026-11-01T00:00:00.000Z" {
+		t.Errorf("unexpected item: %+v %+v", item, item.Task)
+	}
+	if item.Task.Notes != "" {
+		t.Errorf("expected the alarm description to be ignored, got %q", item.Task.Notes)
+	}
+	if item.ParentUID != "" {
+		t.Errorf("expected sibling relations to be ignored, got %q", item.ParentUID)
+	}
+
+	if _, err := ReadICS(strings.NewReader("BEGIN:VTODO\r\nSUMMARY:x\r\n")); err == nil {
+		t.Error("expected an error for an unterminated VTODO")
+	}
+	if _, err := ReadICS(strings.NewReader("BEGIN:VTODO\r\nDUE:tomorrow\r\nEND:VTODO\r\n")); err == nil {
+		t.Error("expected an error for an invalid DUE")
+	}
+}
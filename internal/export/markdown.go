@@ -0,0 +1,128 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/ui"
+	"google.golang.org/api/tasks/v1"
+)
+
+// checklistItem matches a markdown checklist item such as "- [x] Title" or
+// "1. [ ] Title".
+var checklistItem = regexp.MustCompile(`^([-*+]|\d+[.)])\s+\[([ xX])\]\s*(.*)$`)
+
+// WriteMarkdown writes the items as a markdown checklist (see
+// ui.WriteChecklist).
+func WriteMarkdown(w io.Writer, items []Item) error {
+	list := make([]*tasks.Task, len(items))
+	for i, item := range items {
+		task := *item.Task
+		task.Id, task.Parent = item.UID, item.ParentUID
+		list[i] = &task
+	}
+	return ui.WriteChecklist(w, list)
+}
+
+// ReadMarkdown reads the checklist items of a markdown document. Items
+// indented under another item become its subtasks. Text indented under an
+// item is part of it: a "Due: YYYY-MM-DD" line sets the due date, and other
+// lines, quoted or not, become the notes. Everything else, such as headings
+// and prose, is skipped. The items get local UIDs, as markdown has no
+// identifiers.
+func ReadMarkdown(r io.Reader) ([]Item, error) {
+	type open struct {
+		indent int
+		item   *Item
+	}
+	var items []*Item
+	var stack []open
+	var notes []string
+
+	// flush adds the collected notes to the innermost item.
+	flush := func() {
+		if len(stack) > 0 && len(notes) > 0 {
+			task := stack[len(stack)-1].item.Task
+			task.Notes = strings.TrimSpace(task.Notes + "\n" + strings.Join(notes, "\n"))
+		}
+		notes = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ReplaceAll(scanner.Text(), "\t", "    ")
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+		if text == "" {
+			if notes != nil {
+				notes = append(notes, "")
+			}
+			continue
+		}
+
+		if m := checklistItem.FindStringSubmatch(text); m != nil {
+			flush()
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			item := &Item{
+				UID:      strconv.Itoa(len(items) + 1),
+				LocalUID: true,
+				Task:     &tasks.Task{Title: strings.TrimSpace(m[3]), Status: "needsAction"},
+			}
+			if m[2] != " " {
+				item.Task.Status = "completed"
+			}
+			if len(stack) > 0 {
+				item.ParentUID = stack[len(stack)-1].item.UID
+			}
+			items = append(items, item)
+			stack = append(stack, open{indent: indent, item: item})
+			continue
+		}
+
+		// Text belongs to the innermost item it is indented under. Text
+		// that is not indented under any item ends the list.
+		if len(stack) > 0 && indent <= stack[len(stack)-1].indent {
+			flush()
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		if len(stack) == 0 {
+			continue
+		}
+		if due, ok := cutPrefixFold(text, "due:"); ok && notes == nil {
+			if t, err := time.Parse("2006-01-02", strings.TrimSpace(due)); err == nil {
+				stack[len(stack)-1].item.Task.Due = t.Format("2006-01-02") + "T00:00:00.000Z"
+				continue
+			}
+		}
+		if quoted, ok := strings.CutPrefix(text, ">"); ok {
+			text = strings.TrimPrefix(quoted, " ")
+		}
+		notes = append(notes, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	result := make([]Item, len(items))
+	for i, item := range items {
+		result[i] = *item
+	}
+	return result, nil
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring case.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
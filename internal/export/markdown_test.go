@@ -0,0 +1,79 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestReadMarkdown(t *testing.T) {
+	doc := `# Release
+
+Some prose that is not a task.
+
+- [ ] Ship v2
+  Due: 2026-11-01
+  > Check the changelog
+  >
+  > and the docs
+  - [x] Write notes
+  - [ ] Tag release
+	plain note
+  more notes for Ship v2
+- [X] Announce
+* a bullet without a checkbox
+1. [ ] Numbered
+`
+	items, err := ReadMarkdown(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("ReadMarkdown failed: %v", err)
+	}
+
+	want := []struct {
+		title, status, parent, due, notes string
+	}{
+		{"Ship v2", "needsAction", "", "2026-11-01T00:00:00.000Z", "Check the changelog\n\nand the docs\nmore notes for Ship v2"},
+		{"Write notes", "completed", "1", "", ""},
+		{"Tag release", "needsAction", "1", "", "plain note"},
+		{"Announce", "completed", "", "", ""},
+		{"Numbered", "needsAction", "", "", ""},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d items, got %d: %+v", len(want), len(items), items)
+	}
+	for i, w := range want {
+		item := items[i]
+		if !item.LocalUID || item.Task.Title != w.title || item.Task.Status != w.status || item.ParentUID != w.parent ||
+			item.Task.Due != w.due || item.Task.Notes != w.notes {
+			t.Errorf("item %d: expected %+v, got %+v (%+v)", i, w, item, item.Task)
+		}
+	}
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	items := []Item{
+		{UID: "p", Task: &tasks.Task{Title: "Parent", Notes: "Line 1\nLine 2", Due: "2026-11-01T00:00:00.000Z", Status: "needsAction"}},
+		{UID: "c", ParentUID: "p", Task: &tasks.Task{Title: "Child", Status: "completed"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, items); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	read, err := ReadMarkdown(&buf)
+	if err != nil {
+		t.Fatalf("ReadMarkdown failed: %v", err)
+	}
+	if len(read) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(read))
+	}
+	parent, child := read[0].Task, read[1]
+	if parent.Title != "Parent" || parent.Notes != "Line 1\nLine 2" || parent.Due != items[0].Task.Due {
+		t.Errorf("unexpected parent: %+v", parent)
+	}
+	if child.ParentUID != read[0].UID || child.Task.Status != "completed" {
+		t.Errorf("unexpected child: %+v", child)
+	}
+}
@@ -0,0 +1,167 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// todo.txt tags with a meaning for Google Tasks.
+const (
+	todoTxtDue    = "due"
+	todoTxtID     = "id"
+	todoTxtParent = "parent"
+)
+
+var (
+	todoTxtDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoTxtPriority = regexp.MustCompile(`^\([A-Z]\)$`)
+	todoTxtTagKey   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+)
+
+// isAttribute reports whether a todo.txt token is a context, a project or a
+// key:value tag.
+func isAttribute(token string) bool {
+	if len(token) > 1 && (token[0] == '@' || token[0] == '+') {
+		return true
+	}
+	return isTag(token)
+}
+
+// isTag reports whether a todo.txt token is a key:value tag. The key must
+// start with a letter and the value must not be empty, so that times like
+// 10:30 and URLs stay part of the title.
+func isTag(token string) bool {
+	key, value, ok := strings.Cut(token, ":")
+	return ok && todoTxtTagKey.MatchString(key) && value != "" && !strings.HasPrefix(value, "//")
+}
+
+// FormatTodoTxt formats an item as a todo.txt line:
+//
+//	x 2026-10-12 Title +List @context due:2026-11-01 id:UID parent:UID
+//
+// The completion date is only written for completed tasks. Contexts and
+// other tags come from the attributes line of the notes; the rest of the
+// notes cannot be represented and is left out.
+func FormatTodoTxt(item Item) string {
+	task := item.Task
+	var parts []string
+	if task.Status == "completed" {
+		parts = append(parts, "x")
+		if task.Completed != nil {
+			if completed, err := time.Parse(time.RFC3339, *task.Completed); err == nil {
+				parts = append(parts, completed.UTC().Format("2006-01-02"))
+			}
+		}
+	}
+
+	if title := strings.Join(strings.Fields(task.Title), " "); title != "" {
+		parts = append(parts, title)
+	}
+	if item.TaskList != "" {
+		parts = append(parts, "+"+strings.ReplaceAll(item.TaskList, " ", "_"))
+	}
+	attributes := item.Attributes
+	if attributes == nil {
+		_, attributes = SplitAttributes(task.Notes)
+	}
+	parts = append(parts, attributes...)
+	if due := DateOnly(task.Due); due != "" {
+		parts = append(parts, todoTxtDue+":"+due)
+	}
+	if item.UID != "" {
+		parts = append(parts, todoTxtID+":"+item.UID)
+	}
+	if item.ParentUID != "" {
+		parts = append(parts, todoTxtParent+":"+item.ParentUID)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseTodoTxt parses a todo.txt line. The first project becomes the task
+// list. A priority, other projects, contexts and unknown tags become
+// attributes, with the priority kept as a pri: tag.
+func ParseTodoTxt(line string) (Item, error) {
+	tokens := strings.Fields(line)
+	item := Item{Task: &tasks.Task{Status: "needsAction"}, Attributes: []string{}}
+
+	if len(tokens) > 0 && tokens[0] == "x" {
+		item.Task.Status = "completed"
+		tokens = tokens[1:]
+		if len(tokens) > 0 && todoTxtDate.MatchString(tokens[0]) {
+			completed := tokens[0] + "T00:00:00Z"
+			item.Task.Completed = &completed
+			tokens = tokens[1:]
+		}
+	} else if len(tokens) > 0 && todoTxtPriority.MatchString(tokens[0]) {
+		item.Attributes = append(item.Attributes, "pri:"+tokens[0][1:2])
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && todoTxtDate.MatchString(tokens[0]) {
+		tokens = tokens[1:] // The creation date has no counterpart.
+	}
+
+	var title []string
+	for _, token := range tokens {
+		switch {
+		case token[0] == '+' && len(token) > 1 && item.TaskList == "":
+			item.TaskList = token[1:]
+		case isTag(token):
+			key, value, _ := strings.Cut(token, ":")
+			switch key {
+			case todoTxtDue:
+				due, err := time.Parse("2006-01-02", value)
+				if err != nil {
+					return Item{}, fmt.Errorf("invalid due date %q", value)
+				}
+				item.Task.Due = due.Format("2006-01-02") + "T00:00:00.000Z"
+			case todoTxtID:
+				item.UID = value
+			case todoTxtParent:
+				item.ParentUID = value
+			default:
+				item.Attributes = append(item.Attributes, token)
+			}
+		case isAttribute(token):
+			item.Attributes = append(item.Attributes, token)
+		default:
+			title = append(title, token)
+		}
+	}
+	item.Task.Title = strings.Join(title, " ")
+	return item, nil
+}
+
+// WriteTodoTxt writes the items as a todo.txt file.
+func WriteTodoTxt(w io.Writer, items []Item) error {
+	bw := bufio.NewWriter(w)
+	for _, item := range items {
+		if _, err := fmt.Fprintln(bw, FormatTodoTxt(item)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadTodoTxt reads a todo.txt file. Blank lines are skipped.
+func ReadTodoTxt(r io.Reader) ([]Item, error) {
+	var items []Item
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		item, err := ParseTodoTxt(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestParseTodoTxt(t *testing.T) {
+	item, err := ParseTodoTxt("(A) 2026-10-01 Call Bob +Work +Phone @office see:http://x.example due:2026-11-01 id:abc parent:def")
+	if err != nil {
+		t.Fatalf("ParseTodoTxt failed: %v", err)
+	}
+	if item.Task.Title != "Call Bob" || item.Task.Status != "needsAction" || item.Task.Due != "2026-11-01T00:00:00.000Z" {
+		t.Errorf("unexpected task: %+v", item.Task)
+	}
+	if item.TaskList != "Work" || item.UID != "abc" || item.ParentUID != "def" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+	if want := []string{"pri:A", "+Phone", "@office", "see:http://x.example"}; !reflect.DeepEqual(item.Attributes, want) {
+		t.Errorf("expected attributes %v, got %v", want, item.Attributes)
+	}
+
+	item, err = ParseTodoTxt("x 2026-10-12 2026-10-01 Done thing http://example.com")
+	if err != nil {
+		t.Fatalf("ParseTodoTxt failed: %v", err)
+	}
+	if item.Task.Status != "completed" || *item.Task.Completed != "2026-10-12T00:00:00Z" || item.Task.Title != "Done thing http://example.com" {
+		t.Errorf("unexpected task: %+v", item.Task)
+	}
+
+	item, err = ParseTodoTxt("Call Bob at 10:30 due:2026-10-20")
+	if err != nil {
+		t.Fatalf("ParseTodoTxt failed: %v", err)
+	}
+	if item.Task.Title != "Call Bob at 10:30" || item.Task.Due != "2026-10-20T00:00:00.000Z" {
+		t.Errorf("unexpected task: %+v", item.Task)
+	}
+
+	if _, err := ParseTodoTxt("Bad due:tomorrow"); err == nil {
+		t.Error("expected an error for an invalid due date")
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	completed := "2026-10-12T16:00:00Z"
+	items := []Item{
+		{UID: "parent", TaskList: "Home Stuff", Task: &tasks.Task{
+			Title:  "Paint the fence",
+			Notes:  "Buy paint first\n@garden pri:B\n[uid:parent]",
+			Due:    "2026-11-01T00:00:00.000Z",
+			Status: "needsAction",
+		}},
+		{UID: "child", ParentUID: "parent", TaskList: "Home Stuff", Task: &tasks.Task{
+			Title:     "Sand it",
+			Status:    "completed",
+			Completed: &completed,
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTodoTxt(&buf, items); err != nil {
+		t.Fatalf("WriteTodoTxt failed: %v", err)
+	}
+	want := "Paint the fence +Home_Stuff @garden pri:B due:2026-11-01 id:parent\n" +
+		"x 2026-10-12 Sand it +Home_Stuff id:child parent:parent\n"
+	if buf.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+
+	read, err := ReadTodoTxt(&buf)
+	if err != nil {
+		t.Fatalf("ReadTodoTxt failed: %v", err)
+	}
+	if len(read) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(read))
+	}
+	if read[0].UID != "parent" || read[0].TaskList != "Home_Stuff" || read[0].Task.Due != items[0].Task.Due ||
+		!reflect.DeepEqual(read[0].Attributes, []string{"@garden", "pri:B"}) {
+		t.Errorf("unexpected item: %+v", read[0])
+	}
+	if read[1].ParentUID != "parent" || read[1].Task.Status != "completed" {
+		t.Errorf("unexpected item: %+v", read[1])
+	}
+}
+
+func TestTodoTxtRoundTrip_Colons(t *testing.T) {
+	for _, title := range []string{
+		"Call Bob at 10:30",
+		"Read http://example.com/a:b",
+		"Ratio 3:2 and a trailing colon:",
+		"Steps: plan, build",
+		":leading colon",
+	} {
+		item := Item{Task: &tasks.Task{Title: title, Status: "needsAction", Due: "2026-10-20T00:00:00.000Z"}}
+		read, err := ParseTodoTxt(FormatTodoTxt(item))
+		if err != nil {
+			t.Fatalf("ParseTodoTxt failed: %v", err)
+		}
+		if read.Task.Title != title || read.Task.Due != item.Task.Due || len(read.Attributes) != 0 {
+			t.Errorf("expected %q to survive a round trip, got %+v", title, read)
+		}
+	}
+}
+
+func TestSetAttributes(t *testing.T) {
+	tests := []struct {
+		notes      string
+		attributes []string
+		want       string
+	}{
+		{"", []string{"@home"}, "@home"},
+		{"Body", []string{"@home"}, "Body\n@home"},
+		{"Body\n@work pri:A", []string{"@home"}, "Body\n@home"},
+		{"Body\n@work\n[uid:x]", []string{"@home"}, "Body\n@home\n[uid:x]"},
+		{"Body\n@work", nil, "Body"},
+		{"Body with a: colon", nil, "Body with a: colon"},
+	}
+	for _, tt := range tests {
+		if got := SetAttributes(tt.notes, tt.attributes); got != tt.want {
+			t.Errorf("SetAttributes(%q, %v) = %q, want %q", tt.notes, tt.attributes, got, tt.want)
+		}
+	}
+}
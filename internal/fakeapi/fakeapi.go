@@ -0,0 +1,723 @@
+// Package fakeapi implements a local fake of the Google Tasks API v1 on top
+// of the offline store. It lets tests and demos run the online client
+// without network access or credentials, e.g. with
+//
+//	server := httptest.NewServer(fakeapi.New(store))
+//	gtasks --api-endpoint server.URL tasks list
+//
+// The fake serves the tasklists and tasks endpoints including move and
+// clear, stamps items with etags and update times, honours If-Match and
+// If-None-Match, pages results with page tokens and reports errors in the
+// JSON format of Google APIs.
+package fakeapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+const (
+	// DefaultPageSize is the page size used when a request does not set
+	// maxResults. It is small, so that clients have to follow page tokens.
+	DefaultPageSize = 20
+	// maxPageSize is the largest page size the API accepts.
+	maxPageSize = 100
+)
+
+// updatedLayout is the format of update and completion times, which the API
+// reports with millisecond precision.
+const updatedLayout = "2006-01-02T15:04:05.000Z"
+
+// API is an http.Handler that serves the Google Tasks API from a store. The
+// store must not be used by anything else while the API serves it.
+type API struct {
+	mu       sync.Mutex
+	store    *store.InMemoryStore
+	mux      *http.ServeMux
+	pageSize int
+	revision int
+}
+
+// New creates a fake API that serves the task lists and tasks of the store.
+// The default task list is the one mapped to @default in the store, or the
+// first task list. New items get IDs with the prefix "fake", which differ
+// from the local IDs of the offline store.
+func New(st *store.InMemoryStore) *API {
+	st.SetIDPrefix("fake")
+	a := &API{store: st, mux: http.NewServeMux(), pageSize: DefaultPageSize}
+	a.mux.HandleFunc("GET /tasks/v1/users/@me/lists", a.listTaskLists)
+	a.mux.HandleFunc("POST /tasks/v1/users/@me/lists", a.insertTaskList)
+	a.mux.HandleFunc("GET /tasks/v1/users/@me/lists/{tasklist}", a.getTaskList)
+	a.mux.HandleFunc("PUT /tasks/v1/users/@me/lists/{tasklist}", a.updateTaskList)
+	a.mux.HandleFunc("PATCH /tasks/v1/users/@me/lists/{tasklist}", a.updateTaskList)
+	a.mux.HandleFunc("DELETE /tasks/v1/users/@me/lists/{tasklist}", a.deleteTaskList)
+	a.mux.HandleFunc("GET /tasks/v1/lists/{tasklist}/tasks", a.listTasks)
+	a.mux.HandleFunc("POST /tasks/v1/lists/{tasklist}/tasks", a.insertTask)
+	a.mux.HandleFunc("POST /tasks/v1/lists/{tasklist}/clear", a.clearTasks)
+	a.mux.HandleFunc("GET /tasks/v1/lists/{tasklist}/tasks/{task}", a.getTask)
+	a.mux.HandleFunc("PUT /tasks/v1/lists/{tasklist}/tasks/{task}", a.updateTask)
+	a.mux.HandleFunc("PATCH /tasks/v1/lists/{tasklist}/tasks/{task}", a.updateTask)
+	a.mux.HandleFunc("DELETE /tasks/v1/lists/{tasklist}/tasks/{task}", a.deleteTask)
+	a.mux.HandleFunc("POST /tasks/v1/lists/{tasklist}/tasks/{task}/move", a.moveTask)
+	a.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "notFound", fmt.Sprintf("%s %s is not part of the Tasks API", r.Method, r.URL.Path))
+	})
+	return a
+}
+
+// SetPageSize sets the page size used when a request does not set
+// maxResults.
+func (a *API) SetPageSize(size int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pageSize = size
+}
+
+// ServeHTTP serves a request. Requests are handled one at a time.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.mux.ServeHTTP(w, r)
+}
+
+// AddTaskList adds a task list as if another client had created it.
+func (a *API) AddTaskList(title string) (*tasks.TaskList, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	list, err := a.store.CreateTaskList(&tasks.TaskList{Title: title})
+	if err != nil {
+		return nil, err
+	}
+	a.touchTaskList(list)
+	return copyTaskList(list), nil
+}
+
+// AddTask adds a task as if another client had created it. Unlike the
+// API, it appends the task to its siblings, and it keeps the status, hidden
+// flag and position of the task if they are set.
+func (a *API) AddTask(listID string, task *tasks.Task) (*tasks.Task, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	listID = a.taskListID(listID)
+	previous := ""
+	for _, sibling := range a.sortedTasks(listID) {
+		if sibling.Parent == task.Parent {
+			previous = sibling.Id
+		}
+	}
+	created, err := a.store.CreateTask(listID, task, previous)
+	if err != nil {
+		return nil, err
+	}
+	a.setStatus(created, task.Status)
+	created.Hidden = task.Hidden
+	if task.Position != "" {
+		created.Position = task.Position
+	}
+	a.touchTask(created)
+	return copyTask(created), nil
+}
+
+// ModifyTask changes a task as if another client had changed it.
+func (a *API) ModifyTask(listID, taskID string, modify func(*tasks.Task)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	task, _ := a.store.GetTask(a.taskListID(listID), taskID)
+	if task == nil {
+		return fmt.Errorf("task %s not found", taskID)
+	}
+	modify(task)
+	a.touchTask(task)
+	return nil
+}
+
+// TaskLists returns the task lists, the default task list first.
+func (a *API) TaskLists() []*tasks.TaskList {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var lists []*tasks.TaskList
+	for _, list := range a.sortedTaskLists() {
+		lists = append(lists, copyTaskList(list))
+	}
+	return lists
+}
+
+func (a *API) listTaskLists(w http.ResponseWriter, r *http.Request) {
+	page, next, err := paginate(r, a.sortedTaskLists(), a.pageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	result := &tasks.TaskLists{Kind: "tasks#taskLists", Etag: a.etag(), NextPageToken: next}
+	for _, list := range page {
+		result.Items = append(result.Items, a.taskListResource(r, list))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *API) insertTaskList(w http.ResponseWriter, r *http.Request) {
+	var body tasks.TaskList
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Title == "" {
+		writeError(w, http.StatusBadRequest, "required", "Missing title.")
+		return
+	}
+	list, err := a.store.CreateTaskList(&body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "backendError", err.Error())
+		return
+	}
+	a.touchTaskList(list)
+	writeJSON(w, http.StatusOK, a.taskListResource(r, list))
+}
+
+func (a *API) getTaskList(w http.ResponseWriter, r *http.Request) {
+	list := a.findTaskList(w, r)
+	if list == nil || !checkNoneMatch(w, r, list.Etag) {
+		return
+	}
+	writeJSON(w, http.StatusOK, a.taskListResource(r, list))
+}
+
+func (a *API) updateTaskList(w http.ResponseWriter, r *http.Request) {
+	list := a.findTaskList(w, r)
+	if list == nil || !checkMatch(w, r, list.Etag) {
+		return
+	}
+	var body tasks.TaskList
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Title == "" {
+		if r.Method == http.MethodPut {
+			writeError(w, http.StatusBadRequest, "required", "Missing title.")
+			return
+		}
+		body.Title = list.Title
+	}
+	list, err := a.store.UpdateTaskList(list.Id, &body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "backendError", err.Error())
+		return
+	}
+	a.touchTaskList(list)
+	writeJSON(w, http.StatusOK, a.taskListResource(r, list))
+}
+
+func (a *API) deleteTaskList(w http.ResponseWriter, r *http.Request) {
+	list := a.findTaskList(w, r)
+	if list == nil || !checkMatch(w, r, list.Etag) {
+		return
+	}
+	if list.Id == a.taskListID("@default") {
+		writeError(w, http.StatusBadRequest, "invalid", "The default task list cannot be deleted.")
+		return
+	}
+	if err := a.store.DeleteTaskList(list.Id); err != nil {
+		writeError(w, http.StatusInternalServerError, "backendError", err.Error())
+		return
+	}
+	a.revision++
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) listTasks(w http.ResponseWriter, r *http.Request) {
+	list := a.findTaskList(w, r)
+	if list == nil {
+		return
+	}
+	filter, err := newTaskFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	var items []*tasks.Task
+	for _, task := range a.sortedTasks(list.Id) {
+		if filter.match(task) {
+			items = append(items, task)
+		}
+	}
+	page, next, err := paginate(r, items, a.pageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	result := &tasks.Tasks{Kind: "tasks#tasks", Etag: a.etag(), NextPageToken: next}
+	for _, task := range page {
+		result.Items = append(result.Items, a.taskResource(r, list.Id, task))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *API) insertTask(w http.ResponseWriter, r *http.Request) {
+	list := a.findTaskList(w, r)
+	if list == nil {
+		return
+	}
+	var body tasks.Task
+	if !decode(w, r, &body) {
+		return
+	}
+	due, err := normalizeDue(body.Due)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	body.Due, body.Parent = due, r.URL.Query().Get("parent")
+	task, err := a.store.CreateTask(list.Id, &body, r.URL.Query().Get("previous"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	a.setStatus(task, body.Status)
+	a.touchTask(task)
+	writeJSON(w, http.StatusOK, a.taskResource(r, list.Id, task))
+}
+
+func (a *API) getTask(w http.ResponseWriter, r *http.Request) {
+	listID, task := a.findTask(w, r)
+	if task == nil || !checkNoneMatch(w, r, task.Etag) {
+		return
+	}
+	writeJSON(w, http.StatusOK, a.taskResource(r, listID, task))
+}
+
+// updateTask replaces the title, notes, due date, status and hidden flag of
+// a task with PUT, and only sets the fields present in the body with PATCH,
+// where null clears a field. The completion time is kept from the body if
+// it has one.
+func (a *API) updateTask(w http.ResponseWriter, r *http.Request) {
+	listID, task := a.findTask(w, r)
+	if task == nil || !checkMatch(w, r, task.Etag) {
+		return
+	}
+	var raw json.RawMessage
+	if !decode(w, r, &raw) {
+		return
+	}
+	var body tasks.Task
+	var present map[string]json.RawMessage
+	if err := errors.Join(json.Unmarshal(raw, &body), json.Unmarshal(raw, &present)); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Invalid JSON payload: "+err.Error())
+		return
+	}
+	due, err := normalizeDue(body.Due)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	if r.Method == http.MethodPut {
+		task.Title, task.Notes, task.Due, task.Hidden = body.Title, body.Notes, due, body.Hidden
+	} else {
+		if _, ok := present["title"]; ok {
+			task.Title = body.Title
+		}
+		if _, ok := present["notes"]; ok {
+			task.Notes = body.Notes
+		}
+		if _, ok := present["due"]; ok {
+			task.Due = due
+		}
+		if _, ok := present["hidden"]; ok {
+			task.Hidden = body.Hidden
+		}
+	}
+	a.setStatus(task, body.Status)
+	if task.Status == "completed" && body.Completed != nil {
+		completed := *body.Completed
+		task.Completed = &completed
+	}
+	a.touchTask(task)
+	writeJSON(w, http.StatusOK, a.taskResource(r, listID, task))
+}
+
+func (a *API) moveTask(w http.ResponseWriter, r *http.Request) {
+	listID, task := a.findTask(w, r)
+	if task == nil || !checkMatch(w, r, task.Etag) {
+		return
+	}
+	query := r.URL.Query()
+	if destination := query.Get("destinationTasklist"); destination != "" && a.taskListID(destination) != listID {
+		writeError(w, http.StatusBadRequest, "invalid", "Moving tasks to another task list is not supported.")
+		return
+	}
+	task, err := a.store.MoveTask(listID, task.Id, query.Get("parent"), query.Get("previous"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	a.touchTask(task)
+	writeJSON(w, http.StatusOK, a.taskResource(r, listID, task))
+}
+
+func (a *API) deleteTask(w http.ResponseWriter, r *http.Request) {
+	listID, task := a.findTask(w, r)
+	if task == nil || !checkMatch(w, r, task.Etag) {
+		return
+	}
+	if err := a.store.DeleteTask(listID, task.Id); err != nil {
+		writeError(w, http.StatusInternalServerError, "backendError", err.Error())
+		return
+	}
+	a.revision++
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clearTasks hides the completed tasks of a task list.
+func (a *API) clearTasks(w http.ResponseWriter, r *http.Request) {
+	list := a.findTaskList(w, r)
+	if list == nil {
+		return
+	}
+	for _, task := range a.sortedTasks(list.Id) {
+		if task.Status == "completed" && !task.Hidden {
+			task.Hidden = true
+			a.touchTask(task)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// taskListID resolves @default to the ID of the default task list.
+func (a *API) taskListID(id string) string {
+	if id != "@default" {
+		return id
+	}
+	if mapped, ok := a.store.Data.IDMap[id]; ok {
+		return mapped
+	}
+	if lists := a.sortedTaskLists(); len(lists) > 0 {
+		return lists[0].Id
+	}
+	return id
+}
+
+// findTaskList returns the task list of the request, or writes an error and
+// returns nil if there is none.
+func (a *API) findTaskList(w http.ResponseWriter, r *http.Request) *tasks.TaskList {
+	list, _ := a.store.GetTaskList(a.taskListID(r.PathValue("tasklist")))
+	if list == nil {
+		writeError(w, http.StatusNotFound, "notFound", "Task list not found.")
+	}
+	return list
+}
+
+// findTask returns the task list ID and task of the request, or writes an
+// error and returns a nil task if there is none.
+func (a *API) findTask(w http.ResponseWriter, r *http.Request) (string, *tasks.Task) {
+	list := a.findTaskList(w, r)
+	if list == nil {
+		return "", nil
+	}
+	task, _ := a.store.GetTask(list.Id, r.PathValue("task"))
+	if task == nil {
+		writeError(w, http.StatusNotFound, "notFound", "Task not found.")
+	}
+	return list.Id, task
+}
+
+// sortedTaskLists returns the task lists in the order of creation, the
+// default task list first.
+func (a *API) sortedTaskLists() []*tasks.TaskList {
+	lists, _ := a.store.ListTaskLists()
+	sort.Slice(lists, func(i, j int) bool { return lessID(lists[i].Id, lists[j].Id) })
+	if defaultID, ok := a.store.Data.IDMap["@default"]; ok {
+		sort.SliceStable(lists, func(i, j int) bool { return lists[i].Id == defaultID && lists[j].Id != defaultID })
+	}
+	return lists
+}
+
+// sortedTasks returns the tasks of a task list in the order of the API:
+// siblings by position, each task followed by its subtasks.
+func (a *API) sortedTasks(listID string) []*tasks.Task {
+	items, _ := a.store.ListTasks(listID)
+	present := make(map[string]bool, len(items))
+	for _, task := range items {
+		present[task.Id] = true
+	}
+	children := make(map[string][]*tasks.Task)
+	for _, task := range items {
+		parent := task.Parent
+		if !present[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], task)
+	}
+
+	sorted := make([]*tasks.Task, 0, len(items))
+	var visit func(parent string)
+	visit = func(parent string) {
+		siblings := children[parent]
+		sort.Slice(siblings, func(i, j int) bool {
+			if siblings[i].Position != siblings[j].Position {
+				return siblings[i].Position < siblings[j].Position
+			}
+			return lessID(siblings[i].Id, siblings[j].Id)
+		})
+		for _, task := range siblings {
+			sorted = append(sorted, task)
+			visit(task.Id)
+		}
+	}
+	visit("")
+	return sorted
+}
+
+// lessID orders IDs such as id2 before id10.
+func lessID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// setStatus sets the status of a task and its completion time. An empty
+// status leaves the task as it is.
+func (a *API) setStatus(task *tasks.Task, status string) {
+	switch status {
+	case "completed":
+		if task.Status != "completed" || task.Completed == nil {
+			completed := a.stamp()
+			task.Completed = &completed
+		}
+		task.Status = status
+	case "needsAction":
+		task.Status, task.Completed, task.Hidden = status, nil, false
+	}
+}
+
+// touchTaskList gives a changed task list a new etag and update time.
+func (a *API) touchTaskList(list *tasks.TaskList) {
+	list.Updated = a.stamp()
+	list.Etag = a.etag()
+}
+
+// touchTask gives a changed task a new etag and update time.
+func (a *API) touchTask(task *tasks.Task) {
+	task.Updated = a.stamp()
+	task.Etag = a.etag()
+}
+
+// stamp returns the update time of a change and starts a new revision.
+// Changes within the same millisecond share an update time but not an etag.
+func (a *API) stamp() string {
+	a.revision++
+	return time.Now().UTC().Format(updatedLayout)
+}
+
+// etag returns the etag of the current revision.
+func (a *API) etag() string {
+	return strconv.Quote(strconv.Itoa(a.revision))
+}
+
+// taskListResource returns a task list as the API reports it.
+func (a *API) taskListResource(r *http.Request, list *tasks.TaskList) *tasks.TaskList {
+	resource := copyTaskList(list)
+	resource.Kind = "tasks#taskList"
+	resource.SelfLink = baseURL(r) + "users/@me/lists/" + list.Id
+	return resource
+}
+
+// taskResource returns a task as the API reports it.
+func (a *API) taskResource(r *http.Request, listID string, task *tasks.Task) *tasks.Task {
+	resource := copyTask(task)
+	resource.Kind = "tasks#task"
+	resource.SelfLink = baseURL(r) + "lists/" + listID + "/tasks/" + task.Id
+	return resource
+}
+
+// baseURL returns the URL of the API for self links.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/tasks/v1/"
+}
+
+// taskFilter selects tasks by the query parameters of a list request.
+type taskFilter struct {
+	showCompleted bool
+	showHidden    bool
+	completedMin  time.Time
+	completedMax  time.Time
+	dueMin        time.Time
+	dueMax        time.Time
+	updatedMin    time.Time
+}
+
+// newTaskFilter parses the query parameters of a list request.
+func newTaskFilter(r *http.Request) (*taskFilter, error) {
+	query := r.URL.Query()
+	filter := &taskFilter{showCompleted: query.Get("showCompleted") != "false", showHidden: query.Get("showHidden") == "true"}
+	for name, bound := range map[string]*time.Time{
+		"completedMin": &filter.completedMin,
+		"completedMax": &filter.completedMax,
+		"dueMin":       &filter.dueMin,
+		"dueMax":       &filter.dueMax,
+		"updatedMin":   &filter.updatedMin,
+	} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %q", name, v)
+			}
+			*bound = t
+		}
+	}
+	return filter, nil
+}
+
+// match reports whether a task passes the filter.
+func (f *taskFilter) match(task *tasks.Task) bool {
+	if task.Status == "completed" && !f.showCompleted || task.Hidden && !f.showHidden {
+		return false
+	}
+	completed := ""
+	if task.Completed != nil {
+		completed = *task.Completed
+	}
+	return inRange(completed, f.completedMin, f.completedMax) &&
+		inRange(task.Due, f.dueMin, f.dueMax) &&
+		inRange(task.Updated, f.updatedMin, time.Time{})
+}
+
+// inRange reports whether a timestamp is within the bounds. Zero bounds are
+// open, and empty timestamps only pass open bounds.
+func inRange(value string, lower, upper time.Time) bool {
+	if lower.IsZero() && upper.IsZero() {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return false
+	}
+	return (lower.IsZero() || !t.Before(lower)) && (upper.IsZero() || t.Before(upper))
+}
+
+// normalizeDue reduces a due time to its date, as the API does.
+func normalizeDue(due string) (string, error) {
+	if due == "" {
+		return "", nil
+	}
+	t, err := time.Parse(time.RFC3339, due)
+	if err != nil {
+		return "", fmt.Errorf("invalid due date: %q", due)
+	}
+	return t.Format(time.DateOnly) + "T00:00:00.000Z", nil
+}
+
+// paginate returns the page of items selected by the maxResults and
+// pageToken query parameters, and the token of the next page. Page tokens
+// are opaque to clients.
+func paginate[T any](r *http.Request, items []T, pageSize int) ([]T, string, error) {
+	size := pageSize
+	if v := r.URL.Query().Get("maxResults"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, "", fmt.Errorf("invalid maxResults: %q", v)
+		}
+		size = min(n, maxPageSize)
+	}
+	start := 0
+	if v := r.URL.Query().Get("pageToken"); v != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(v)
+		if err == nil {
+			start, err = strconv.Atoi(string(decoded))
+		}
+		if err != nil || start < 0 {
+			return nil, "", fmt.Errorf("invalid pageToken: %q", v)
+		}
+	}
+	start = min(start, len(items))
+	end := min(start+size, len(items))
+	next := ""
+	if end < len(items) {
+		next = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	}
+	return items[start:end], next, nil
+}
+
+// checkMatch enforces an If-Match header. It writes an error and returns
+// false if the etag does not match.
+func checkMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" && match != etag {
+		writeError(w, http.StatusPreconditionFailed, "conditionNotMet", "Precondition Failed")
+		return false
+	}
+	return true
+}
+
+// checkNoneMatch enforces an If-None-Match header. It writes Not Modified
+// and returns false if the etag matches.
+func checkNoneMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	}
+	return true
+}
+
+// decode decodes the JSON body of a request. It writes an error and returns
+// false if the body is invalid.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Invalid JSON payload: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// apiError is the JSON error format of Google APIs.
+type apiError struct {
+	Error struct {
+		Code    int            `json:"code"`
+		Message string         `json:"message"`
+		Errors  []apiErrorItem `json:"errors"`
+	} `json:"error"`
+}
+
+// apiErrorItem is a single error of an apiError.
+type apiErrorItem struct {
+	Domain  string `json:"domain"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// writeError writes an error in the format of Google APIs.
+func writeError(w http.ResponseWriter, code int, reason, message string) {
+	var body apiError
+	body.Error.Code, body.Error.Message = code, message
+	body.Error.Errors = []apiErrorItem{{Domain: "global", Reason: reason, Message: message}}
+	writeJSON(w, code, &body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// copyTaskList returns a shallow copy of a task list.
+func copyTaskList(list *tasks.TaskList) *tasks.TaskList {
+	c := *list
+	return &c
+}
+
+// copyTask returns a shallow copy of a task.
+func copyTask(task *tasks.Task) *tasks.Task {
+	c := *task
+	return &c
+}
@@ -0,0 +1,153 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+// newTestAPI starts a fake API with a single task list.
+func newTestAPI(t *testing.T) (*API, *httptest.Server, *tasks.TaskList) {
+	t.Helper()
+	st, err := store.NewInMemoryStore("")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	api := New(st)
+	list, err := api.AddTaskList("Inbox")
+	if err != nil {
+		t.Fatalf("AddTaskList failed: %v", err)
+	}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, server, list
+}
+
+// do sends a request and decodes the JSON response into v if it is not nil.
+func do(t *testing.T, method, url, body string, header http.Header, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if v != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("failed to decode %s: %v", data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAPI_Tasks(t *testing.T) {
+	_, server, list := newTestAPI(t)
+	base := server.URL + "/tasks/v1/lists/" + list.Id + "/tasks"
+
+	var first, second, child tasks.Task
+	do(t, "POST", base, `{"title":"First","due":"2026-11-01T15:30:00Z"}`, nil, &first)
+	do(t, "POST", base+"?previous="+first.Id, `{"title":"Second"}`, nil, &second)
+	do(t, "POST", base+"?parent="+first.Id, `{"title":"Child"}`, nil, &child)
+	if first.Due != "2026-11-01T00:00:00.000Z" || first.Etag == "" || first.Updated == "" || first.Kind != "tasks#task" {
+		t.Errorf("unexpected task: %+v", first)
+	}
+
+	// Tasks are listed in hierarchy order, one per page.
+	var titles []string
+	token := ""
+	for pages := 0; ; pages++ {
+		var page tasks.Tasks
+		if code := do(t, "GET", base+"?maxResults=1&pageToken="+token, "", nil, &page); code != http.StatusOK {
+			t.Fatalf("list returned %d", code)
+		}
+		for _, task := range page.Items {
+			titles = append(titles, task.Title)
+		}
+		if token = page.NextPageToken; token == "" {
+			if pages != 2 {
+				t.Errorf("expected 3 pages, got %d", pages+1)
+			}
+			break
+		}
+	}
+	if got := strings.Join(titles, ","); got != "First,Child,Second" {
+		t.Errorf("expected First,Child,Second, got %s", got)
+	}
+
+	// Completing a task sets its completion time, and clear hides it.
+	var completed tasks.Task
+	do(t, "PATCH", base+"/"+second.Id, `{"status":"completed"}`, nil, &completed)
+	if completed.Completed == nil || completed.Title != "Second" || completed.Etag == second.Etag {
+		t.Errorf("unexpected completed task: %+v", completed)
+	}
+	do(t, "POST", server.URL+"/tasks/v1/lists/"+list.Id+"/clear", "", nil, nil)
+	var visible tasks.Tasks
+	do(t, "GET", base, "", nil, &visible)
+	if len(visible.Items) != 2 {
+		t.Errorf("expected the cleared task to be hidden, got %d tasks", len(visible.Items))
+	}
+	do(t, "GET", base+"?showHidden=true", "", nil, &visible)
+	if len(visible.Items) != 3 {
+		t.Errorf("expected showHidden to show the cleared task, got %d tasks", len(visible.Items))
+	}
+
+	// Moving a task below itself fails.
+	if code := do(t, "POST", base+"/"+first.Id+"/move?parent="+child.Id, "", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a cyclic move, got %d", code)
+	}
+	if code := do(t, "GET", base+"/missing", "", nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing task, got %d", code)
+	}
+}
+
+func TestAPI_Etags(t *testing.T) {
+	_, server, list := newTestAPI(t)
+	url := server.URL + "/tasks/v1/users/@me/lists/" + list.Id
+
+	if code := do(t, "GET", url, "", http.Header{"If-None-Match": {list.Etag}}, nil); code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching etag, got %d", code)
+	}
+	var updated tasks.TaskList
+	if code := do(t, "PUT", url, `{"title":"Renamed"}`, http.Header{"If-Match": {list.Etag}}, &updated); code != http.StatusOK {
+		t.Fatalf("expected 200 for a matching etag, got %d", code)
+	}
+	if updated.Etag == list.Etag || updated.Title != "Renamed" {
+		t.Errorf("unexpected updated task list: %+v", updated)
+	}
+	if code := do(t, "DELETE", url, "", http.Header{"If-Match": {list.Etag}}, nil); code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for a stale etag, got %d", code)
+	}
+}
+
+func TestAPI_Default(t *testing.T) {
+	api, server, list := newTestAPI(t)
+	other, _ := api.AddTaskList("Other")
+
+	var got tasks.TaskList
+	do(t, "GET", server.URL+"/tasks/v1/users/@me/lists/@default", "", nil, &got)
+	if got.Id != list.Id {
+		t.Errorf("expected @default to be %s, got %s", list.Id, got.Id)
+	}
+	if code := do(t, "DELETE", server.URL+"/tasks/v1/users/@me/lists/"+list.Id, "", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 when deleting the default task list, got %d", code)
+	}
+	if code := do(t, "DELETE", server.URL+"/tasks/v1/users/@me/lists/"+other.Id, "", nil, nil); code != http.StatusNoContent {
+		t.Errorf("expected 204 when deleting another task list, got %d", code)
+	}
+	if lists := api.TaskLists(); len(lists) != 1 {
+		t.Errorf("expected 1 task list, got %d", len(lists))
+	}
+}
@@ -0,0 +1,384 @@
+package gtasks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/export"
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+// BackupOptions holds the parameters for backing up an account.
+type BackupOptions struct {
+	// Account is recorded in the archive.
+	Account string
+	// Workers is the number of task lists fetched concurrently. Zero means
+	// DefaultWorkers.
+	Workers int
+}
+
+// Backup snapshots every task list and task of the account, including
+// completed and hidden tasks, into an archive.
+func Backup(ctx context.Context, client Client, opts BackupOptions) (*store.Archive, error) {
+	lists, err := client.ListTaskLists(ctx, ListTaskListsOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([][]*tasks.Task, len(lists.Items))
+	errs := make([]error, len(lists.Items))
+	runPool(ctx, len(lists.Items), opts.Workers, func(i int) {
+		listOpts := ListTasksOptions{TaskListID: lists.Items[i].Id, ShowCompleted: true, ShowHidden: true}
+		result, err := client.ListTasks(ctx, listOpts)
+		if err != nil {
+			errs[i] = fmt.Errorf("error listing tasks of %q: %w", lists.Items[i].Title, err)
+			return
+		}
+		items[i] = result.Items
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	archive := &store.Archive{
+		Version: store.ArchiveVersion,
+		Account: opts.Account,
+		Created: time.Now().UTC().Format(time.RFC3339),
+		Data: store.Data{
+			TaskLists: make(map[string]*tasks.TaskList),
+			Tasks:     make(map[string]map[string]*tasks.Task),
+			NextID:    1,
+			IDMap:     make(map[string]string),
+		},
+	}
+	for i, list := range lists.Items {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		archive.Data.TaskLists[list.Id] = list
+		archive.Data.Tasks[list.Id] = make(map[string]*tasks.Task)
+		for _, task := range items[i] {
+			archive.Data.Tasks[list.Id][task.Id] = task
+		}
+	}
+
+	// Record the default task list, as the offline store does. The server
+	// lists it first.
+	if list, err := client.GetTaskList(ctx, GetTaskListOptions{TaskListID: "@default"}); err == nil && list != nil {
+		archive.Data.IDMap["@default"] = list.Id
+	} else if len(lists.Items) > 0 {
+		archive.Data.IDMap["@default"] = lists.Items[0].Id
+	}
+	return archive, nil
+}
+
+// RestoreOptions holds the parameters for restoring an archive.
+type RestoreOptions struct {
+	// DryRun reports what would be done without changing anything.
+	DryRun bool
+}
+
+// RestoredTaskList describes what a restore did with a single task list.
+type RestoredTaskList struct {
+	// TaskList is the archived task list.
+	TaskList *tasks.TaskList
+	// Created reports whether the task list had to be created.
+	Created bool
+	Result  *ImportResult
+}
+
+// RestoreResult summarizes a restore.
+type RestoreResult struct {
+	ImportResult
+	TaskLists []RestoredTaskList
+}
+
+// Restore recreates the task lists and tasks of an archive with their
+// hierarchy and order. Task lists are matched by ID, then by title, and
+// created if neither matches. Tasks are matched by ID, or by the archived ID
+// recorded in the notes of tasks created by an earlier restore, so that a
+// restore into another account can be repeated without creating duplicates.
+// Matched tasks get every archived field back, including empty ones, and
+// are moved back to their archived position. Tasks that are not in the
+// archive are left alone.
+func Restore(ctx context.Context, client Client, opts RestoreOptions, archive *store.Archive) (*RestoreResult, error) {
+	current, err := client.ListTaskLists(ctx, ListTaskListsOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{}
+	for _, list := range archivedTaskLists(archive.Data) {
+		restored := RestoredTaskList{TaskList: list, Result: &ImportResult{}}
+		listID := ""
+		for _, match := range current.Items {
+			if match.Id == list.Id {
+				listID = match.Id
+				break
+			}
+		}
+		if listID == "" {
+			listID = findTaskListID(current.Items, list.Title)
+		}
+
+		items := archivedItems(archive.Data.Tasks[list.Id])
+		if listID == "" {
+			restored.Created = true
+			result.TaskListsCreated++
+			if opts.DryRun {
+				// There is nothing to match against, so every task is new.
+				for _, item := range items {
+					restored.Result.Created++
+					restored.Result.Actions = append(restored.Result.Actions, ImportAction{Action: ImportCreated, UID: item.UID, Task: item.Task})
+				}
+			} else {
+				created, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: list.Title})
+				if err != nil {
+					return result, fmt.Errorf("error creating task list %q: %w", list.Title, err)
+				}
+				listID = created.Id
+			}
+		}
+		if listID != "" {
+			restored.Result, err = restoreTasks(ctx, client, opts, listID, items)
+			if err != nil {
+				return result, fmt.Errorf("error restoring task list %q: %w", list.Title, err)
+			}
+		}
+
+		result.Created += restored.Result.Created
+		result.Updated += restored.Result.Updated
+		result.Unchanged += restored.Result.Unchanged
+		result.Actions = append(result.Actions, restored.Result.Actions...)
+		result.TaskLists = append(result.TaskLists, restored)
+	}
+	return result, nil
+}
+
+// archivedTaskLists returns the task lists of archived data, the default
+// task list first and the others by title.
+func archivedTaskLists(data store.Data) []*tasks.TaskList {
+	lists := make([]*tasks.TaskList, 0, len(data.TaskLists))
+	for _, list := range data.TaskLists {
+		lists = append(lists, list)
+	}
+	defaultID := data.IDMap["@default"]
+	sort.Slice(lists, func(i, j int) bool {
+		if (lists[i].Id == defaultID) != (lists[j].Id == defaultID) {
+			return lists[i].Id == defaultID
+		}
+		return lists[i].Title < lists[j].Title
+	})
+	return lists
+}
+
+// archivedItems returns the archived tasks of a task list as items in their
+// order, parents first.
+func archivedItems(archived map[string]*tasks.Task) []export.Item {
+	list := make([]*tasks.Task, 0, len(archived))
+	for _, task := range archived {
+		list = append(list, task)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Position != list[j].Position {
+			return list[i].Position < list[j].Position
+		}
+		return list[i].Id < list[j].Id
+	})
+	return export.ItemsFromTasks(list)
+}
+
+// restoreTasks restores the archived items, parents first and siblings in
+// order, into a task list.
+func restoreTasks(ctx context.Context, client Client, opts RestoreOptions, listID string, items []export.Item) (*ImportResult, error) {
+	listOpts := ListTasksOptions{TaskListID: listID, ShowCompleted: true, ShowHidden: true}
+	existing, err := client.ListTasks(ctx, listOpts)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]*tasks.Task{}
+	byUID := map[string]*tasks.Task{}
+	for _, task := range existing.Items {
+		byID[task.Id] = task
+		if _, uid := export.SplitUID(task.Notes); uid != "" {
+			byUID[uid] = task
+		}
+	}
+
+	// ids maps the archived IDs to task IDs, and previous maps parent IDs
+	// to the last restored task under them.
+	order := newSiblingOrder(existing.Items)
+	ids := map[string]string{}
+	previous := map[string]string{}
+	result := &ImportResult{}
+	for _, item := range items {
+		parentID := ids[item.ParentUID]
+		action, err := restoreTask(ctx, client, opts, listID, item, parentID, previous[parentID], findTask(byID, byUID, item.UID), order)
+		if err != nil {
+			return result, fmt.Errorf("error restoring %q: %w", item.Task.Title, err)
+		}
+		ids[item.UID] = action.Task.Id
+		previous[parentID] = action.Task.Id
+
+		switch action.Action {
+		case ImportCreated:
+			result.Created++
+		case ImportUpdated:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+		result.Actions = append(result.Actions, action)
+	}
+	return result, nil
+}
+
+// restoreTask creates the task of an archived item, or gives its match the
+// archived fields and position back.
+func restoreTask(ctx context.Context, client Client, opts RestoreOptions, listID string, item export.Item, parentID, previousID string, match *tasks.Task, order *siblingOrder) (ImportAction, error) {
+	want := item.Task
+	status := want.Status
+	if status == "" {
+		status = "needsAction"
+	}
+	completed := ""
+	if status == "completed" && want.Completed != nil {
+		completed = *want.Completed
+	}
+
+	// Tasks that do not keep their archived ID remember it in the notes.
+	notes := want.Notes
+	if match == nil || match.Id != item.UID {
+		notes = export.SetUID(notes, item.UID)
+	}
+	replace := ReplaceTaskOptions{TaskListID: listID, Title: want.Title, Notes: notes, Due: want.Due, Status: status, Completed: completed, Hidden: want.Hidden}
+
+	if match == nil {
+		task := *want
+		task.Id, task.Notes, task.Parent = "(new)"+item.UID, notes, parentID
+		if !opts.DryRun {
+			created, err := client.CreateTask(ctx, CreateTaskOptions{TaskListID: listID, Title: want.Title, Notes: notes, Due: want.Due, Parent: parentID, Previous: previousID})
+			if err != nil {
+				return ImportAction{}, err
+			}
+			task = *created
+			if status == "completed" || want.Hidden {
+				replace.TaskID = created.Id
+				if created, err = client.ReplaceTask(ctx, replace); err != nil {
+					return ImportAction{}, err
+				}
+				task = *created
+			}
+		}
+		order.move(task.Id, parentID, previousID)
+		return ImportAction{Action: ImportCreated, UID: item.UID, Task: &task}, nil
+	}
+
+	matchStatus := match.Status
+	if matchStatus == "" {
+		matchStatus = "needsAction"
+	}
+	fields := map[string]bool{
+		"title":     want.Title != match.Title,
+		"notes":     notes != match.Notes,
+		"due":       export.DateOnly(want.Due) != export.DateOnly(match.Due),
+		"status":    status != matchStatus,
+		"completed": completed != "" && (match.Completed == nil || !sameTime(completed, *match.Completed)),
+		"hidden":    want.Hidden != match.Hidden,
+	}
+	var changes []string
+	for field, changed := range fields {
+		if changed {
+			changes = append(changes, field)
+		}
+	}
+	replaceFields := len(changes) > 0
+	move := true
+	switch {
+	case parentID != match.Parent:
+		changes = append(changes, "parent")
+	case previousID != order.previous(match.Id):
+		changes = append(changes, "position")
+	default:
+		move = false
+	}
+	if len(changes) == 0 {
+		return ImportAction{Action: ImportUnchanged, UID: item.UID, Task: match}, nil
+	}
+	sort.Strings(changes)
+	action := ImportAction{Action: ImportUpdated, UID: item.UID, Task: match, Changes: changes}
+
+	var err error
+	if replaceFields && !opts.DryRun {
+		replace.TaskID = match.Id
+		if action.Task, err = client.ReplaceTask(ctx, replace); err != nil {
+			return ImportAction{}, err
+		}
+	}
+	if move {
+		if !opts.DryRun {
+			if action.Task, err = client.MoveTask(ctx, MoveTaskOptions{TaskListID: listID, TaskID: match.Id, Parent: parentID, Previous: previousID}); err != nil {
+				return ImportAction{}, err
+			}
+		}
+		order.move(match.Id, parentID, previousID)
+	}
+	return action, nil
+}
+
+// sameTime reports whether two RFC 3339 timestamps are the same instant.
+func sameTime(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ta.Equal(tb)
+}
+
+// siblingOrder tracks the order of the tasks under each parent while a
+// restore creates and moves tasks.
+type siblingOrder struct {
+	parents  map[string]string
+	children map[string][]string
+}
+
+// newSiblingOrder returns the order of the given tasks.
+func newSiblingOrder(items []*tasks.Task) *siblingOrder {
+	sorted := append([]*tasks.Task(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+	order := &siblingOrder{parents: map[string]string{}, children: map[string][]string{}}
+	for _, task := range sorted {
+		order.parents[task.Id] = task.Parent
+		order.children[task.Parent] = append(order.children[task.Parent], task.Id)
+	}
+	return order
+}
+
+// previous returns the ID of the sibling before the task, or an empty
+// string if it is the first among its siblings.
+func (o *siblingOrder) previous(id string) string {
+	siblings := o.children[o.parents[id]]
+	if i := slices.Index(siblings, id); i > 0 {
+		return siblings[i-1]
+	}
+	return ""
+}
+
+// move places the task under parent after the sibling previous, or first
+// if previous is empty.
+func (o *siblingOrder) move(id, parent, previous string) {
+	if old, ok := o.parents[id]; ok {
+		o.children[old] = slices.DeleteFunc(o.children[old], func(sibling string) bool { return sibling == id })
+	}
+	siblings := o.children[parent]
+	i := slices.Index(siblings, previous) + 1
+	o.children[parent] = slices.Insert(siblings, i, id)
+	o.parents[id] = parent
+}
@@ -0,0 +1,154 @@
+package gtasks
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeTasksServer(t)
+	client := server.client(t)
+	work := server.addTaskList("Work")
+	parent := server.addTask(work.Id, &tasks.Task{Title: "Parent", Position: "00000000000000000001"})
+	server.addTask(work.Id, &tasks.Task{Title: "Child", Parent: parent.Id, Position: "00000000000000000000"})
+	server.addTask(work.Id, &tasks.Task{Title: "Done", Status: "completed", Hidden: true, Position: "00000000000000000000"})
+
+	// 1. The backup contains every task and survives a round trip
+	archive, err := Backup(ctx, client, BackupOptions{Account: "me@example.com"})
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := store.WriteArchive(&buf, archive); err != nil {
+		t.Fatalf("WriteArchive failed: %v", err)
+	}
+	if archive, err = store.ReadArchive(&buf); err != nil {
+		t.Fatalf("ReadArchive failed: %v", err)
+	}
+	if archive.Account != "me@example.com" || len(archive.Data.Tasks[work.Id]) != 3 || archive.Data.IDMap["@default"] != work.Id {
+		t.Fatalf("unexpected archive: %+v", archive)
+	}
+
+	// 2. Restoring into the same account changes nothing
+	result, err := Restore(ctx, client, RestoreOptions{}, archive)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if result.Unchanged != 3 || result.Created != 0 || result.TaskListsCreated != 0 {
+		t.Errorf("expected 3 unchanged tasks, got %+v", result)
+	}
+
+	// 3. A dry run into another account reports everything as new
+	other := newFakeTasksServer(t)
+	otherClient := other.client(t)
+	result, err = Restore(ctx, otherClient, RestoreOptions{DryRun: true}, archive)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if result.Created != 3 || result.TaskListsCreated != 1 || len(other.lists()) != 0 {
+		t.Errorf("expected a dry run with 3 new tasks, got %+v", result)
+	}
+
+	// 4. Restoring into another account recreates the hierarchy and order,
+	// and restoring again creates no duplicates
+	for i := 0; i < 2; i++ {
+		if _, err := Restore(ctx, otherClient, RestoreOptions{}, archive); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+	}
+	lists, _ := otherClient.ListTaskLists(ctx, ListTaskListsOptions{})
+	if len(lists.Items) != 1 || lists.Items[0].Title != "Work" {
+		t.Fatalf("expected the Work task list to be restored, got %+v", lists.Items)
+	}
+	restored, err := otherClient.ListTasks(ctx, ListTasksOptions{TaskListID: lists.Items[0].Id, ShowCompleted: true, ShowHidden: true})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	byTitle := map[string]*tasks.Task{}
+	for _, task := range restored.Items {
+		byTitle[task.Title] = task
+	}
+	if len(restored.Items) != 3 || byTitle["Child"].Parent != byTitle["Parent"].Id || byTitle["Done"].Status != "completed" {
+		t.Errorf("unexpected restored tasks: %+v", restored.Items)
+	}
+
+	// 5. The backup can seed the offline store
+	local, _ := store.NewInMemoryStore("")
+	if err := local.Seed(archive.Data); err != nil {
+		t.Fatalf("Seed failed: %v", err)
+	}
+	offline := &offlineClient{store: local}
+	list, err := offline.GetTaskList(ctx, GetTaskListOptions{TaskListID: "@default"})
+	if err != nil || list == nil || list.Title != "Work" {
+		t.Errorf("expected @default to be the Work task list, got %+v (%v)", list, err)
+	}
+}
+
+func TestRestore_RevertsChanges(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeTasksServer(t)
+	client := server.client(t)
+	work := server.addTaskList("Work")
+	a := server.addTask(work.Id, &tasks.Task{Title: "A", Notes: "Keep", Position: "00000000000000000000"})
+	b := server.addTask(work.Id, &tasks.Task{Title: "B", Position: "00000000000000000001"})
+	c := server.addTask(work.Id, &tasks.Task{Title: "C", Status: "completed", Hidden: true, Position: "00000000000000000002"})
+	archive, err := Backup(ctx, client, BackupOptions{})
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	// Edit, clear, uncomplete and reorder tasks after the backup
+	server.modifyTask(work.Id, a.Id, func(task *tasks.Task) { task.Title, task.Notes = "Renamed", "" })
+	server.modifyTask(work.Id, b.Id, func(task *tasks.Task) {
+		task.Notes, task.Due = "Added later", "2026-11-01T00:00:00.000Z"
+	})
+	if _, err := client.UncompleteTask(ctx, UncompleteTaskOptions{TaskListID: work.Id, TaskID: c.Id}); err != nil {
+		t.Fatalf("UncompleteTask failed: %v", err)
+	}
+	if _, err := client.MoveTask(ctx, MoveTaskOptions{TaskListID: work.Id, TaskID: c.Id}); err != nil {
+		t.Fatalf("MoveTask failed: %v", err)
+	}
+
+	result, err := Restore(ctx, client, RestoreOptions{}, archive)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if result.Updated != 3 || result.Created != 0 {
+		t.Errorf("expected 3 updated tasks, got %+v", result)
+	}
+
+	// Every field and the order are back as archived
+	restored, err := client.ListTasks(ctx, ListTasksOptions{TaskListID: work.Id, ShowCompleted: true, ShowHidden: true, SortBy: "position"})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	var order []string
+	for _, task := range restored.Items {
+		order = append(order, task.Title)
+		want := archive.Data.Tasks[work.Id][task.Id]
+		if want == nil {
+			t.Fatalf("unexpected task %+v", task)
+		}
+		if task.Title != want.Title || task.Notes != want.Notes || task.Due != want.Due || task.Status != want.Status ||
+			task.Hidden != want.Hidden || task.Parent != want.Parent {
+			t.Errorf("expected %+v, got %+v", want, task)
+		}
+		if (want.Completed == nil) != (task.Completed == nil) || want.Completed != nil && *want.Completed != *task.Completed {
+			t.Errorf("expected %q to keep its completion time %v, got %v", task.Title, want.Completed, task.Completed)
+		}
+	}
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected the order %v, got %v", want, order)
+	}
+
+	// Restoring again changes nothing
+	if result, err = Restore(ctx, client, RestoreOptions{}, archive); err != nil || result.Unchanged != 3 {
+		t.Errorf("expected 3 unchanged tasks, got %+v (%v)", result, err)
+	}
+}
@@ -0,0 +1,146 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// DefaultWorkers is the default number of concurrent API calls of a bulk
+// operation.
+const DefaultWorkers = 4
+
+// ErrEmptySelection is returned by SelectTasks if neither task IDs nor
+// filters are given, to avoid applying an operation to a whole list by
+// accident.
+var ErrEmptySelection = NewError(ErrInvalidArgument, "no task IDs or filters given")
+
+// SelectTasksOptions holds the parameters for selecting the tasks of a bulk
+// operation.
+type SelectTasksOptions struct {
+	TaskListID string
+	// TaskIDs selects tasks by ID. The filter is applied to them as well.
+	// If empty, all tasks of the list that match the filter are selected.
+	TaskIDs []string
+	Filter  FilterOptions
+	// ShowCompleted and ShowHidden apply when selecting by filter only.
+	ShowCompleted bool
+	ShowHidden    bool
+	// Workers is the number of concurrent API calls. Defaults to
+	// DefaultWorkers.
+	Workers int
+}
+
+// BulkResult holds the outcome of a bulk operation for a single task.
+type BulkResult struct {
+	// Task is the task returned by the operation, or the selected task if
+	// the operation failed or returned no task.
+	Task *tasks.Task
+	Err  error
+}
+
+// IsEmpty reports whether no filter criteria are set.
+func (o FilterOptions) IsEmpty() bool {
+	return o == FilterOptions{}
+}
+
+// SelectTasks returns the tasks selected by IDs and filters.
+func SelectTasks(ctx context.Context, client Client, opts SelectTasksOptions) ([]*tasks.Task, error) {
+	if len(opts.TaskIDs) == 0 {
+		if opts.Filter.IsEmpty() {
+			return nil, ErrEmptySelection
+		}
+		listOpts := ListTasksOptions{
+			TaskListID:    opts.TaskListID,
+			ShowCompleted: opts.ShowCompleted,
+			ShowHidden:    opts.ShowHidden,
+			SortBy:        "position",
+		}
+		result, err := ListFilteredTasks(ctx, client, listOpts, opts.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return result.Items, nil
+	}
+
+	filter, err := NewTaskFilter(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*tasks.Task, len(opts.TaskIDs))
+	errs := make([]error, len(opts.TaskIDs))
+	runPool(ctx, len(opts.TaskIDs), opts.Workers, func(i int) {
+		getOpts := GetTaskOptions{TaskListID: opts.TaskListID, TaskID: opts.TaskIDs[i]}
+		items[i], errs[i] = client.GetTask(ctx, getOpts)
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var selected []*tasks.Task
+	for _, task := range items {
+		if filter.Match(task) {
+			selected = append(selected, task)
+		}
+	}
+	return selected, nil
+}
+
+// RunBulk applies fn to every task with at most the given number of
+// concurrent calls. The results are in the order of the tasks. Tasks that
+// have not been started when the context is done fail with the context's
+// error.
+func RunBulk(ctx context.Context, items []*tasks.Task, workers int, fn func(ctx context.Context, task *tasks.Task) (*tasks.Task, error)) []BulkResult {
+	results := make([]BulkResult, len(items))
+	runPool(ctx, len(items), workers, func(i int) {
+		task, err := fn(ctx, items[i])
+		if task == nil {
+			task = items[i]
+		}
+		results[i] = BulkResult{Task: task, Err: err}
+	})
+	for i := range results {
+		if results[i].Task == nil { // Never started.
+			results[i] = BulkResult{Task: items[i], Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// runPool calls fn for the indexes 0 to n-1 with at most the given number of
+// concurrent calls. It stops handing out indexes when the context is done.
+func runPool(ctx context.Context, n, workers int, fn func(i int)) {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := range n {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,170 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestSelectTasks(t *testing.T) {
+	ctx := context.Background()
+	client := newTestOfflineClient(t)
+	list, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: "Bulk"})
+	if err != nil {
+		t.Fatalf("CreateTaskList failed: %v", err)
+	}
+
+	var ids []string
+	for _, title := range []string{"Daily standup", "Weekly standup", "Write report"} {
+		task, err := client.CreateTask(ctx, CreateTaskOptions{TaskListID: list.Id, Title: title})
+		if err != nil {
+			t.Fatalf("CreateTask failed: %v", err)
+		}
+		ids = append(ids, task.Id)
+	}
+	if _, err := client.CompleteTask(ctx, CompleteTaskOptions{TaskListID: list.Id, TaskID: ids[1]}); err != nil {
+		t.Fatalf("CompleteTask failed: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		opts           SelectTasksOptions
+		expectedTitles []string
+		expectedError  error
+	}{
+		{
+			name:           "By IDs",
+			opts:           SelectTasksOptions{TaskIDs: []string{ids[0], ids[2]}},
+			expectedTitles: []string{"Daily standup", "Write report"},
+		},
+		{
+			name:           "By IDs includes completed tasks",
+			opts:           SelectTasksOptions{TaskIDs: []string{ids[1]}},
+			expectedTitles: []string{"Weekly standup"},
+		},
+		{
+			name:           "By IDs and filter",
+			opts:           SelectTasksOptions{TaskIDs: ids, Filter: FilterOptions{TitleContains: "report"}},
+			expectedTitles: []string{"Write report"},
+		},
+		{
+			name:           "By filter",
+			opts:           SelectTasksOptions{Filter: FilterOptions{TitleContains: "standup"}},
+			expectedTitles: []string{"Daily standup"},
+		},
+		{
+			name:           "By filter with completed tasks",
+			opts:           SelectTasksOptions{Filter: FilterOptions{TitleContains: "standup"}, ShowCompleted: true},
+			expectedTitles: []string{"Weekly standup", "Daily standup"},
+		},
+		{
+			name:          "Empty selection",
+			opts:          SelectTasksOptions{},
+			expectedError: ErrEmptySelection,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.TaskListID = list.Id
+			selected, err := SelectTasks(ctx, client, tc.opts)
+			if tc.expectedError != nil {
+				if !errors.Is(err, tc.expectedError) {
+					t.Fatalf("expected error %v, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectTasks failed: %v", err)
+			}
+			if len(selected) != len(tc.expectedTitles) {
+				t.Fatalf("expected %d tasks, got %d", len(tc.expectedTitles), len(selected))
+			}
+			for i, title := range tc.expectedTitles {
+				if selected[i].Title != title {
+					t.Errorf("expected task %d to be '%s', got '%s'", i, title, selected[i].Title)
+				}
+			}
+		})
+	}
+
+	if _, err := SelectTasks(ctx, client, SelectTasksOptions{TaskListID: list.Id, TaskIDs: []string{"missing"}}); err == nil {
+		t.Error("expected an error for an unknown task ID")
+	}
+}
+
+func TestRunBulk(t *testing.T) {
+	var items []*tasks.Task
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		items = append(items, &tasks.Task{Id: id, Title: id})
+	}
+
+	var mu sync.Mutex
+	var running, maxRunning int32
+	release := make(chan struct{})
+	go func() {
+		for range items {
+			release <- struct{}{}
+		}
+	}()
+
+	failure := errors.New("failure")
+	results := RunBulk(context.Background(), items, 3, func(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+		n := atomic.AddInt32(&running, 1)
+		mu.Lock()
+		if n > maxRunning {
+			maxRunning = n
+		}
+		mu.Unlock()
+		<-release
+		atomic.AddInt32(&running, -1)
+
+		if task.Id == "c" {
+			return nil, failure
+		}
+		return &tasks.Task{Id: task.Id, Title: task.Title, Status: "completed"}, nil
+	})
+
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", maxRunning)
+	}
+	for i, result := range results {
+		if result.Task.Id != items[i].Id {
+			t.Errorf("expected result %d to be for task '%s', got '%s'", i, items[i].Id, result.Task.Id)
+		}
+		if items[i].Id == "c" {
+			if !errors.Is(result.Err, failure) {
+				t.Errorf("expected failure for task 'c', got %v", result.Err)
+			}
+			continue
+		}
+		if result.Err != nil || result.Task.Status != "completed" {
+			t.Errorf("expected task '%s' to be completed, got %v (%v)", items[i].Id, result.Task.Status, result.Err)
+		}
+	}
+}
+
+func TestRunBulk_Cancelled(t *testing.T) {
+	items := []*tasks.Task{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int32
+	results := RunBulk(ctx, items, 1, func(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		return task, nil
+	})
+
+	if calls != 1 {
+		t.Errorf("expected 1 call after cancellation, got %d", calls)
+	}
+	for _, result := range results[1:] {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("expected task '%s' to fail with context.Canceled, got %v", result.Task.Id, result.Err)
+		}
+	}
+}
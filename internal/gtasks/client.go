@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"net/http"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/yanicksenn/gtasks/internal/auth"
@@ -13,26 +15,112 @@ import (
 	"google.golang.org/api/tasks/v1"
 )
 
+// Client is the interface for interacting with Google Tasks. It is implemented
+// by onlineClient, which talks to the real API, and offlineClient, which works
+// against the local offline store.
+type Client interface {
+	ListTaskLists(ctx context.Context, opts ListTaskListsOptions) (*tasks.TaskLists, error)
+	GetTaskList(ctx context.Context, opts GetTaskListOptions) (*tasks.TaskList, error)
+	CreateTaskList(ctx context.Context, opts CreateTaskListOptions) (*tasks.TaskList, error)
+	UpdateTaskList(ctx context.Context, opts UpdateTaskListOptions) (*tasks.TaskList, error)
+	DeleteTaskList(ctx context.Context, opts DeleteTaskListOptions) error
+
+	ListTasks(ctx context.Context, opts ListTasksOptions) (*tasks.Tasks, error)
+	AllTasks(ctx context.Context, opts ListTasksOptions) iter.Seq2[*tasks.Task, error]
+	GetTask(ctx context.Context, opts GetTaskOptions) (*tasks.Task, error)
+	CreateTask(ctx context.Context, opts CreateTaskOptions) (*tasks.Task, error)
+	UpdateTask(ctx context.Context, opts UpdateTaskOptions) (*tasks.Task, error)
+	ReplaceTask(ctx context.Context, opts ReplaceTaskOptions) (*tasks.Task, error)
+	CompleteTask(ctx context.Context, opts CompleteTaskOptions) (*tasks.Task, error)
+	UncompleteTask(ctx context.Context, opts UncompleteTaskOptions) (*tasks.Task, error)
+	MoveTask(ctx context.Context, opts MoveTaskOptions) (*tasks.Task, error)
+	DeleteTask(ctx context.Context, opts DeleteTaskOptions) error
+}
+
 // onlineClient is a client that interacts with the real Google Tasks API.
 // It implements the Client interface.
 type onlineClient struct {
 	service *tasks.Service
 }
 
-// NewClientFromCommand creates a new client based on the --offline flag from a cobra command.
+// ClientOptions holds the parameters for creating a client.
+type ClientOptions struct {
+	// Offline selects the offline client.
+	Offline bool
+	// APIEndpoint replaces the URL of the Google Tasks API, e.g. with a
+	// local fake. Requests to it are not authenticated.
+	APIEndpoint string
+	// Account selects the account of the online client instead of the
+	// active account. The account must be logged in.
+	Account string
+}
+
+// NewClientFromCommand creates a new client based on the --offline,
+// --api-endpoint and --account flags from a cobra command.
 func NewClientFromCommand(cmd *cobra.Command, ctx context.Context) (Client, error) {
 	offline, _ := cmd.Flags().GetBool("offline")
-	return NewClient(ctx, offline)
+	endpoint, _ := cmd.Flags().GetString("api-endpoint")
+	account, _ := cmd.Flags().GetString("account")
+	return NewClient(ctx, ClientOptions{Offline: offline, APIEndpoint: endpoint, Account: account})
 }
 
-// NewClient creates a new client based on the offline flag.
-func NewClient(ctx context.Context, offline bool) (Client, error) {
-	if offline {
+// NewClient creates a new client based on the options.
+func NewClient(ctx context.Context, opts ClientOptions) (Client, error) {
+	if opts.Offline {
 		return newOfflineClient()
 	}
+	if opts.APIEndpoint != "" {
+		return newEndpointClient(ctx, opts.APIEndpoint)
+	}
+	if opts.Account != "" {
+		return newAccountClient(ctx, opts.Account)
+	}
 	return newOnlineClient(ctx)
 }
 
+// newEndpointClient creates an online client for another API endpoint,
+// without authentication.
+func newEndpointClient(ctx context.Context, endpoint string) (*onlineClient, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	httpClient := &http.Client{Transport: newRetryTransport(nil, cfg.Retry)}
+	service, err := tasks.NewService(ctx, option.WithEndpoint(endpoint), option.WithoutAuthentication(), option.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+	return &onlineClient{service: service}, nil
+}
+
+// newAccountClient creates an online client for the given account. Unlike
+// newOnlineClient, it does not start a login if the account has no valid
+// credentials, because that would make the new account the active one.
+func newAccountClient(ctx context.Context, account string) (*onlineClient, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := auth.GetClient(ctx, account)
+	if errors.Is(err, auth.ErrCredentialsNotFound) {
+		return nil, &Error{Kind: ErrUnauthenticated, Message: fmt.Sprintf("account %s is not logged in; run 'gtasks accounts login' first", account), Err: err}
+	}
+	if errors.Is(err, auth.ErrTokenRefreshFailed) {
+		return nil, &Error{Kind: ErrUnauthenticated, Message: fmt.Sprintf("the credentials of %s have expired; run 'gtasks accounts login' again", account), Err: err}
+	}
+	if errors.Is(err, auth.ErrTokenMismatch) {
+		return nil, &Error{Kind: ErrUnauthenticated, Message: err.Error(), Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newServiceClient(ctx, httpClient, cfg.Retry)
+}
+
 func newOnlineClient(ctx context.Context) (*onlineClient, error) {
 	cfg, err := config.Load()
 	if err != nil {
@@ -46,6 +134,8 @@ func newOnlineClient(ctx context.Context) (*onlineClient, error) {
 		if getClientErr != nil {
 			if errors.Is(getClientErr, auth.ErrTokenRefreshFailed) || errors.Is(getClientErr, auth.ErrCredentialsNotFound) {
 				httpClient = nil
+			} else if errors.Is(getClientErr, auth.ErrTokenMismatch) {
+				return nil, &Error{Kind: ErrUnauthenticated, Message: getClientErr.Error(), Err: getClientErr}
 			} else {
 				return nil, getClientErr
 			}
@@ -56,11 +146,14 @@ func newOnlineClient(ctx context.Context) (*onlineClient, error) {
 		fmt.Println("Authentication required. Please follow the instructions to log in.")
 		user, loginErr := auth.LoginViaWebFlow(ctx)
 		if loginErr != nil {
-			return nil, fmt.Errorf("authentication failed: %w", loginErr)
+			return nil, &Error{Kind: ErrUnauthenticated, Message: fmt.Sprintf("authentication failed: %v", loginErr), Err: loginErr}
 		}
 
-		cfg.ActiveAccount = user
-		if err := cfg.Save(); err != nil {
+		err := config.Update(func(cfg *config.Config) error {
+			cfg.ActiveAccount = user
+			return nil
+		})
+		if err != nil {
 			return nil, fmt.Errorf("failed to save active account: %w", err)
 		}
 
@@ -71,6 +164,14 @@ func newOnlineClient(ctx context.Context) (*onlineClient, error) {
 		}
 	}
 
+	return newServiceClient(ctx, httpClient, cfg.Retry)
+}
+
+// newServiceClient creates an online client that sends its requests through
+// the authenticated HTTP client, retrying them as configured.
+func newServiceClient(ctx context.Context, httpClient *http.Client, retry config.RetryConfig) (*onlineClient, error) {
+	httpClient = &http.Client{Transport: newRetryTransport(httpClient.Transport, retry)}
+
 	service, err := tasks.NewService(ctx, option.WithHTTPClient(httpClient))
 	if err != nil {
 		return nil, err
@@ -0,0 +1,123 @@
+package gtasks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+// FieldDeleted is the pseudo field used for conflicts where an item was
+// deleted on one side and modified on the other.
+const FieldDeleted = "deleted"
+
+// Resolution decides which side wins when a field was changed both offline
+// and on the server.
+type Resolution string
+
+const (
+	// KeepLocal keeps the value from the offline store.
+	KeepLocal Resolution = "local"
+	// KeepRemote keeps the value from the server.
+	KeepRemote Resolution = "remote"
+	// MergeNotes merges both versions of the notes. It only applies to the
+	// notes field.
+	MergeNotes Resolution = "merge"
+)
+
+// ParseResolution parses a resolution from its string representation.
+func ParseResolution(s string) (Resolution, error) {
+	switch r := Resolution(strings.ToLower(s)); r {
+	case KeepLocal, KeepRemote, MergeNotes:
+		return r, nil
+	default:
+		return "", NewError(ErrInvalidArgument, "unknown conflict resolution: %s. Available resolutions: local, remote, merge", s)
+	}
+}
+
+// ConflictPolicy holds the resolutions for conflicting fields. Fields
+// without a resolution are reported as conflicts.
+type ConflictPolicy struct {
+	// Default is used for fields without an explicit resolution.
+	Default Resolution
+	// Fields maps a field name (title, notes, due, status or deleted) to
+	// its resolution.
+	Fields map[string]Resolution
+}
+
+// resolution returns the resolution for the given field.
+func (p ConflictPolicy) resolution(field string) Resolution {
+	if r, ok := p.Fields[field]; ok {
+		return r
+	}
+	return p.Default
+}
+
+// Conflict describes a field that was changed both offline and on the server.
+type Conflict struct {
+	Op         store.Operation
+	TaskListID string
+	TaskID     string
+	Field      string
+	Base       string
+	Local      string
+	Remote     string
+}
+
+// String returns a human-readable description of the conflict.
+func (c Conflict) String() string {
+	item := "task list " + c.TaskListID
+	if c.TaskID != "" {
+		item = "task " + c.TaskID
+	}
+	return fmt.Sprintf("%s: %s changed offline to %q and on the server to %q (was %q)", item, c.Field, c.Local, c.Remote, c.Base)
+}
+
+func newConflict(entry store.JournalEntry, field, base, local, remote string) Conflict {
+	return Conflict{
+		Op:         entry.Op,
+		TaskListID: entry.TaskListID,
+		TaskID:     entry.TaskID,
+		Field:      field,
+		Base:       base,
+		Local:      local,
+		Remote:     remote,
+	}
+}
+
+// taskFields are the task fields that are merged field by field on sync.
+var taskFields = []struct {
+	name string
+	get  func(*tasks.Task) string
+	set  func(*tasks.Task, string)
+}{
+	{"title", func(t *tasks.Task) string { return t.Title }, func(t *tasks.Task, v string) { t.Title = v }},
+	{"notes", func(t *tasks.Task) string { return t.Notes }, func(t *tasks.Task, v string) { t.Notes = v }},
+	{"due", func(t *tasks.Task) string { return t.Due }, func(t *tasks.Task, v string) { t.Due = v }},
+	{"status", func(t *tasks.Task) string { return t.Status }, func(t *tasks.Task, v string) { t.Status = v }},
+}
+
+// mergeNotes merges two versions of the notes line by line. The remote
+// version is kept and lines added offline are appended.
+func mergeNotes(base, local, remote string) string {
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(base, "\n") {
+		existing[line] = true
+	}
+	for _, line := range strings.Split(remote, "\n") {
+		existing[line] = true
+	}
+
+	merged := remote
+	for _, line := range strings.Split(local, "\n") {
+		if existing[line] {
+			continue
+		}
+		if merged != "" {
+			merged += "\n"
+		}
+		merged += line
+	}
+	return merged
+}
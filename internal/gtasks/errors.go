@@ -0,0 +1,140 @@
+package gtasks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// The kinds of errors returned by the online and offline clients. Use
+// errors.Is to check the kind of an error, e.g. errors.Is(err, ErrNotFound).
+var (
+	// ErrNotFound reports a task list or task that does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict reports a change that conflicts with the current state,
+	// e.g. an item that was modified since it was read.
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthenticated reports missing, expired or insufficient
+	// credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrRateLimited reports a request that was rejected because of a rate
+	// limit, even after retrying.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrInvalidArgument reports a request with invalid parameters.
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// kinds lists the error kinds from the most to the least specific.
+var kinds = []error{ErrNotFound, ErrConflict, ErrUnauthenticated, ErrRateLimited, ErrInvalidArgument}
+
+// Error is an error of a given kind. It matches its kind with errors.Is and
+// unwraps to the underlying error, if any.
+type Error struct {
+	// Kind is one of ErrNotFound, ErrConflict, ErrUnauthenticated,
+	// ErrRateLimited and ErrInvalidArgument.
+	Kind error
+	// Message describes the error.
+	Message string
+	// Err is the underlying error.
+	Err error
+}
+
+// NewError creates an error of the given kind with a formatted message.
+func NewError(kind error, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error returns the message of the error.
+func (e *Error) Error() string {
+	if e.Message == "" && e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+// Is reports whether target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of an error, or nil if it has none.
+func KindOf(err error) error {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// classify wraps errors of the Google Tasks API, the authentication and the
+// offline store in an Error of the corresponding kind. Other errors, and
+// errors that already have a kind, are returned unchanged.
+func classify(err error) error {
+	if err == nil || KindOf(err) != nil {
+		return err
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		kind := apiErrorKind(apiErr)
+		if kind == nil {
+			return err
+		}
+		message := apiErr.Message
+		if message == "" {
+			message = fmt.Sprintf("%s (%d)", http.StatusText(apiErr.Code), apiErr.Code)
+		}
+		return &Error{Kind: kind, Message: message, Err: err}
+	}
+	var retrieveErr *oauth2.RetrieveError
+	switch {
+	case errors.As(err, &retrieveErr):
+		return &Error{Kind: ErrUnauthenticated, Message: "the access token could not be refreshed, please log in again", Err: err}
+	case errors.Is(err, store.ErrNotFound):
+		return &Error{Kind: ErrNotFound, Err: err}
+	case errors.Is(err, store.ErrInvalidPlacement):
+		return &Error{Kind: ErrInvalidArgument, Err: err}
+	}
+	return err
+}
+
+// classified classifies the error of a call that returns a value.
+func classified[T any](v T, err error) (T, error) {
+	return v, classify(err)
+}
+
+// apiErrorKind returns the kind of an error response of the Google Tasks
+// API, or nil for server errors.
+func apiErrorKind(apiErr *googleapi.Error) error {
+	switch apiErr.Code {
+	case http.StatusBadRequest:
+		return ErrInvalidArgument
+	case http.StatusUnauthorized:
+		return ErrUnauthenticated
+	case http.StatusForbidden:
+		// Google reports some rate limits as 403 and the rest are missing
+		// permissions, e.g. a token without the Tasks scope.
+		for _, item := range apiErr.Errors {
+			if item.Reason == "rateLimitExceeded" || item.Reason == "userRateLimitExceeded" {
+				return ErrRateLimited
+			}
+		}
+		return ErrUnauthenticated
+	case http.StatusNotFound, http.StatusGone:
+		return ErrNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return nil
+}
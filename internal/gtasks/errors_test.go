@@ -0,0 +1,100 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/googleapi"
+)
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeTasksServer(t)
+	online := server.client(t)
+	serverList := server.addTaskList("Inbox")
+	offline := &offlineClient{store: store.NewTestStore()}
+	lists, _ := offline.store.ListTaskLists()
+	offlineList := lists[0]
+
+	for _, tc := range []struct {
+		name   string
+		client Client
+		listID string
+	}{
+		{"online", online, serverList.Id},
+		{"offline", offline, offlineList.Id},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.client.GetTask(ctx, GetTaskOptions{TaskListID: tc.listID, TaskID: "missing"})
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetTask: expected ErrNotFound, got %v", err)
+			}
+			_, err = tc.client.UpdateTaskList(ctx, UpdateTaskListOptions{TaskListID: "missing", Title: "Renamed"})
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("UpdateTaskList: expected ErrNotFound, got %v", err)
+			}
+			if err := tc.client.DeleteTask(ctx, DeleteTaskOptions{TaskListID: tc.listID, TaskID: "missing"}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("DeleteTask: expected ErrNotFound, got %v", err)
+			}
+
+			parent, err := tc.client.CreateTask(ctx, CreateTaskOptions{TaskListID: tc.listID, Title: "Parent"})
+			if err != nil {
+				t.Fatalf("CreateTask failed: %v", err)
+			}
+			child, err := tc.client.CreateTask(ctx, CreateTaskOptions{TaskListID: tc.listID, Title: "Child", Parent: parent.Id})
+			if err != nil {
+				t.Fatalf("CreateTask failed: %v", err)
+			}
+			_, err = tc.client.MoveTask(ctx, MoveTaskOptions{TaskListID: tc.listID, TaskID: parent.Id, Parent: child.Id})
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("MoveTask: expected ErrInvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	apiError := func(code int, reason string) error {
+		err := &googleapi.Error{Code: code, Message: "message"}
+		if reason != "" {
+			err.Errors = []googleapi.ErrorItem{{Reason: reason}}
+		}
+		return fmt.Errorf("wrapped: %w", err)
+	}
+
+	testCases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"bad request", apiError(http.StatusBadRequest, ""), ErrInvalidArgument},
+		{"unauthorized", apiError(http.StatusUnauthorized, ""), ErrUnauthenticated},
+		{"forbidden", apiError(http.StatusForbidden, "insufficientPermissions"), ErrUnauthenticated},
+		{"forbidden rate limit", apiError(http.StatusForbidden, "userRateLimitExceeded"), ErrRateLimited},
+		{"not found", apiError(http.StatusNotFound, ""), ErrNotFound},
+		{"precondition failed", apiError(http.StatusPreconditionFailed, ""), ErrConflict},
+		{"too many requests", apiError(http.StatusTooManyRequests, ""), ErrRateLimited},
+		{"server error", apiError(http.StatusInternalServerError, ""), nil},
+		{"store", fmt.Errorf("task x %w", store.ErrNotFound), ErrNotFound},
+		{"placement", fmt.Errorf("%w: x", store.ErrInvalidPlacement), ErrInvalidArgument},
+		{"query", &QueryError{Query: "(", Msg: "unexpected"}, ErrInvalidArgument},
+		{"sync", ErrUnresolvedConflicts, ErrConflict},
+		{"other", errors.New("other"), nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := classify(tc.err)
+			if kind := KindOf(err); kind != tc.kind {
+				t.Errorf("expected kind %v, got %v", tc.kind, kind)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected the classified error to wrap %v", tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package gtasks
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yanicksenn/gtasks/internal/fakeapi"
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+// fakeTasksServer runs the fake Tasks API of the fakeapi package for the
+// online client in tests.
+type fakeTasksServer struct {
+	t      *testing.T
+	api    *fakeapi.API
+	server *httptest.Server
+}
+
+// newFakeTasksServer starts a fake Tasks API server without task lists that
+// is shut down when the test finishes.
+func newFakeTasksServer(t *testing.T) *fakeTasksServer {
+	t.Helper()
+	st, err := store.NewInMemoryStore("")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	f := &fakeTasksServer{t: t, api: fakeapi.New(st)}
+	f.server = httptest.NewServer(f.api)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client returns an online client that talks to the fake server.
+func (f *fakeTasksServer) client(t *testing.T) Client {
+	t.Helper()
+	client, err := newTestClient(f.server.URL + "/")
+	if err != nil {
+		t.Fatalf("failed to create test client: %v", err)
+	}
+	return client
+}
+
+// addTaskList seeds the server with a task list.
+func (f *fakeTasksServer) addTaskList(title string) *tasks.TaskList {
+	f.t.Helper()
+	list, err := f.api.AddTaskList(title)
+	if err != nil {
+		f.t.Fatalf("failed to add task list: %v", err)
+	}
+	return list
+}
+
+// addTask seeds the server with a task.
+func (f *fakeTasksServer) addTask(listID string, task *tasks.Task) *tasks.Task {
+	f.t.Helper()
+	created, err := f.api.AddTask(listID, task)
+	if err != nil {
+		f.t.Fatalf("failed to add task: %v", err)
+	}
+	return created
+}
+
+// modifyTask changes a task on the server as if another client did.
+func (f *fakeTasksServer) modifyTask(listID, id string, modify func(*tasks.Task)) {
+	f.t.Helper()
+	if err := f.api.ModifyTask(listID, id, modify); err != nil {
+		f.t.Fatalf("failed to modify task: %v", err)
+	}
+}
+
+// lists returns the task lists on the server.
+func (f *fakeTasksServer) lists() []*tasks.TaskList {
+	return f.api.TaskLists()
+}
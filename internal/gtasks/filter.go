@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/yanicksenn/gtasks/internal/dates"
 	taskspb "google.golang.org/api/tasks/v1"
 )
 
@@ -13,55 +14,104 @@ type FilterOptions struct {
 	TitleContains string
 	// NotesContains filters tasks by notes (case-insensitive).
 	NotesContains string
-	// DueBefore filters tasks with a due date before the specified date (e.g., "2025-12-31").
+	// DueBefore filters tasks with a due date before the specified date (e.g., "2025-12-31" or "next friday").
 	DueBefore string
-	// DueAfter filters tasks with a due date after the specified date (e.g., "2025-12-31").
+	// DueAfter filters tasks with a due date after the specified date (e.g., "2025-12-31" or "today").
 	DueAfter string
+	// Query filters tasks by a query expression (see Query).
+	Query string
 }
 
-// FilterTasks filters a slice of tasks based on the provided options.
-func FilterTasks(tasks []*taskspb.Task, opts FilterOptions) ([]*taskspb.Task, error) {
-	var filtered []*taskspb.Task
+// TaskFilter is a compiled set of filter options that can be applied to
+// tasks one at a time, e.g. while streaming them from the API.
+type TaskFilter struct {
+	titleContains string
+	notesContains string
+	dueBefore     *time.Time
+	dueAfter      *time.Time
+	query         *Query
+}
 
-	for _, task := range tasks {
-		if opts.TitleContains != "" && !strings.Contains(strings.ToLower(task.Title), strings.ToLower(opts.TitleContains)) {
-			continue
-		}
+// NewTaskFilter compiles the filter options. It returns an error if one of
+// the dates or the query cannot be parsed.
+func NewTaskFilter(opts FilterOptions) (*TaskFilter, error) {
+	return newTaskFilter(opts, time.Now())
+}
 
-		if opts.NotesContains != "" && !strings.Contains(strings.ToLower(task.Notes), strings.ToLower(opts.NotesContains)) {
-			continue
+// newTaskFilter compiles the filter options, resolving relative dates
+// against now.
+func newTaskFilter(opts FilterOptions, now time.Time) (*TaskFilter, error) {
+	f := &TaskFilter{
+		titleContains: strings.ToLower(opts.TitleContains),
+		notesContains: strings.ToLower(opts.NotesContains),
+	}
+	if opts.DueBefore != "" {
+		dueBefore, err := dates.Parse(opts.DueBefore, now)
+		if err != nil {
+			return nil, err
 		}
-
-		if opts.DueBefore != "" {
-			dueBefore, err := time.Parse("2006-01-02", opts.DueBefore)
-			if err != nil {
-				return nil, err
-			}
-			taskDue, err := time.Parse(time.RFC3339, task.Due)
-			if err != nil {
-				continue
-			}
-			if !taskDue.Before(dueBefore) {
-				continue
-			}
+		f.dueBefore = &dueBefore
+	}
+	if opts.DueAfter != "" {
+		dueAfter, err := dates.Parse(opts.DueAfter, now)
+		if err != nil {
+			return nil, err
+		}
+		f.dueAfter = &dueAfter
+	}
+	if opts.Query != "" {
+		query, err := ParseQuery(opts.Query, now)
+		if err != nil {
+			return nil, err
 		}
+		f.query = query
+	}
+	return f, nil
+}
+
+// Match reports whether the task satisfies all criteria of the filter.
+func (f *TaskFilter) Match(task *taskspb.Task) bool {
+	if f.titleContains != "" && !strings.Contains(strings.ToLower(task.Title), f.titleContains) {
+		return false
+	}
 
-		if opts.DueAfter != "" {
-			dueAfter, err := time.Parse("2006-01-02", opts.DueAfter)
-			if err != nil {
-				return nil, err
-			}
-			taskDue, err := time.Parse(time.RFC3339, task.Due)
-			if err != nil {
-				continue
-			}
-			if !taskDue.After(dueAfter) {
-				continue
-			}
+	if f.notesContains != "" && !strings.Contains(strings.ToLower(task.Notes), f.notesContains) {
+		return false
+	}
+
+	if f.dueBefore != nil || f.dueAfter != nil {
+		taskDue, err := time.Parse(time.RFC3339, task.Due)
+		if err != nil {
+			return false
+		}
+		if f.dueBefore != nil && !taskDue.Before(*f.dueBefore) {
+			return false
 		}
+		if f.dueAfter != nil && !taskDue.After(*f.dueAfter) {
+			return false
+		}
+	}
+
+	if f.query != nil && !f.query.Match(task) {
+		return false
+	}
+
+	return true
+}
 
-		filtered = append(filtered, task)
+// FilterTasks filters a slice of tasks based on the provided options.
+func FilterTasks(tasks []*taskspb.Task, opts FilterOptions) ([]*taskspb.Task, error) {
+	filter, err := NewTaskFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []*taskspb.Task
+	for _, task := range tasks {
+		if filter.Match(task) {
+			filtered = append(filtered, task)
+		}
 	}
 
 	return filtered, nil
-}
\ No newline at end of file
+}
@@ -1,7 +1,9 @@
 package gtasks
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	taskspb "google.golang.org/api/tasks/v1"
 )
@@ -55,6 +57,12 @@ func TestFilterTasks(t *testing.T) {
 			expectedCount: 1,
 			expectedTitle: "Call mom",
 		},
+		{
+			name:          "Filter by query",
+			opts:          FilterOptions{Query: `due>2025-12-21 AND notes~/q\d/i`},
+			expectedCount: 1,
+			expectedTitle: "Finish report",
+		},
 		{
 			name:          "No filters",
 			opts:          FilterOptions{},
@@ -83,4 +91,42 @@ func TestFilterTasks(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestFilterTasks_RelativeDates(t *testing.T) {
+	now := time.Date(2025, 12, 19, 12, 0, 0, 0, time.UTC) // A Friday.
+	tasks := []*taskspb.Task{
+		{Title: "Buy milk", Due: "2025-12-20T00:00:00.000Z"},
+		{Title: "Finish report", Due: "2025-12-22T00:00:00.000Z"},
+		{Title: "Call mom", Due: "2025-12-25T00:00:00.000Z"},
+	}
+
+	testCases := []struct {
+		opts           FilterOptions
+		expectedTitles []string
+	}{
+		{FilterOptions{DueBefore: "monday"}, []string{"Buy milk"}},
+		{FilterOptions{DueAfter: "tomorrow", DueBefore: "in 1 week"}, []string{"Finish report", "Call mom"}},
+		{FilterOptions{DueAfter: "+3d"}, []string{"Call mom"}},
+	}
+
+	for _, tc := range testCases {
+		filter, err := newTaskFilter(tc.opts, now)
+		if err != nil {
+			t.Fatalf("newTaskFilter failed: %v", err)
+		}
+		var titles []string
+		for _, task := range tasks {
+			if filter.Match(task) {
+				titles = append(titles, task.Title)
+			}
+		}
+		if strings.Join(titles, ",") != strings.Join(tc.expectedTitles, ",") {
+			t.Errorf("%+v: expected %v, got %v", tc.opts, tc.expectedTitles, titles)
+		}
+	}
+
+	if _, err := newTaskFilter(FilterOptions{DueBefore: "someday"}, now); err == nil {
+		t.Error("expected an error for an invalid date")
+	}
+}
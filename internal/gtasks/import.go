@@ -0,0 +1,327 @@
+package gtasks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yanicksenn/gtasks/internal/export"
+	"google.golang.org/api/tasks/v1"
+)
+
+// ImportOptions holds the parameters for importing tasks into a task list.
+type ImportOptions struct {
+	TaskListID string
+	// DryRun reports what would be done without changing anything.
+	DryRun bool
+}
+
+// Import actions.
+const (
+	ImportCreated   = "create"
+	ImportUpdated   = "update"
+	ImportUnchanged = "unchanged"
+)
+
+// ImportAction describes what an import did with a single item.
+type ImportAction struct {
+	Action string
+	UID    string
+	Task   *tasks.Task
+	// Changes names the fields an update changes: title, notes, due,
+	// status or parent.
+	Changes []string
+}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Actions   []ImportAction
+	// TaskListsCreated counts the task lists created by ImportTaskLists.
+	TaskListsCreated int
+}
+
+// ImportTasks creates or updates the tasks of the items in a task list.
+// Items are matched to existing tasks by UID: a task matches if its ID is
+// the UID, or if it was created by an earlier import of the same UID. This
+// makes importing the same file twice a no-op. Parents are imported before
+// their subtasks; an item whose parent is neither in the file nor in the
+// list becomes a top-level task. Items with local UIDs are always created.
+// Created tasks keep the order of the items.
+func ImportTasks(ctx context.Context, client Client, opts ImportOptions, items []export.Item) (*ImportResult, error) {
+	listOpts := ListTasksOptions{TaskListID: opts.TaskListID, ShowCompleted: true, ShowHidden: true}
+	existing, err := client.ListTasks(ctx, listOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index the existing tasks by ID and by imported UID.
+	byID := map[string]*tasks.Task{}
+	byUID := map[string]*tasks.Task{}
+	for _, task := range existing.Items {
+		byID[task.Id] = task
+		if _, uid := export.SplitUID(task.Notes); uid != "" {
+			byUID[uid] = task
+		}
+	}
+
+	// ids maps the UIDs of imported items to task IDs, and previous maps
+	// parent IDs to the last task created under them, so that created tasks
+	// keep the order of the file.
+	ids := map[string]string{}
+	previous := map[string]string{}
+	result := &ImportResult{}
+	for _, item := range orderItems(items) {
+		parentID := ids[item.ParentUID]
+		if parentID == "" && item.ParentUID != "" && !item.LocalUID {
+			if parent := findTask(byID, byUID, item.ParentUID); parent != nil {
+				parentID = parent.Id
+			}
+		}
+
+		action, err := importItem(ctx, client, opts, item, parentID, previous[parentID], byID, byUID)
+		if err != nil {
+			return result, fmt.Errorf("error importing %q: %w", item.Task.Title, err)
+		}
+		if action.Action == ImportCreated {
+			previous[parentID] = action.Task.Id
+		}
+		if item.UID != "" {
+			ids[item.UID] = action.Task.Id
+		}
+
+		switch action.Action {
+		case ImportCreated:
+			result.Created++
+		case ImportUpdated:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+		result.Actions = append(result.Actions, action)
+	}
+	return result, nil
+}
+
+// importItem creates or updates the task of a single item.
+func importItem(ctx context.Context, client Client, opts ImportOptions, item export.Item, parentID, previousID string, byID, byUID map[string]*tasks.Task) (ImportAction, error) {
+	want := item.Task
+	var match *tasks.Task
+	if !item.LocalUID {
+		match = findTask(byID, byUID, item.UID)
+	}
+
+	if match == nil {
+		notes := want.Notes
+		if item.Attributes != nil {
+			notes = export.SetAttributes(notes, item.Attributes)
+		}
+		if item.UID != "" && !item.LocalUID {
+			notes = export.SetUID(notes, item.UID)
+		}
+		if opts.DryRun {
+			task := *want
+			task.Id, task.Notes, task.Parent = "(new)", notes, parentID
+			return ImportAction{Action: ImportCreated, UID: item.UID, Task: &task}, nil
+		}
+
+		created, err := client.CreateTask(ctx, CreateTaskOptions{
+			TaskListID: opts.TaskListID,
+			Title:      want.Title,
+			Notes:      notes,
+			Due:        want.Due,
+			Parent:     parentID,
+			Previous:   previousID,
+		})
+		if err != nil {
+			return ImportAction{}, err
+		}
+		if want.Status == "completed" {
+			if created, err = client.CompleteTask(ctx, CompleteTaskOptions{TaskListID: opts.TaskListID, TaskID: created.Id}); err != nil {
+				return ImportAction{}, err
+			}
+		}
+		return ImportAction{Action: ImportCreated, UID: item.UID, Task: created}, nil
+	}
+
+	// Keep the recorded UID of tasks that were matched by it. Attributes
+	// replace the attributes line and keep the rest of the notes.
+	notes := want.Notes
+	if _, uid := export.SplitUID(match.Notes); uid != "" && uid == item.UID {
+		notes = export.SetUID(notes, uid)
+	}
+	setNotes := want.Notes != ""
+	if item.Attributes != nil {
+		notes, setNotes = export.SetAttributes(match.Notes, item.Attributes), true
+	}
+
+	// Empty fields are left as they are, because the client cannot clear
+	// them in every mode.
+	changeTitle := want.Title != "" && want.Title != match.Title
+	changeNotes := setNotes && notes != match.Notes
+	changeDue := want.Due != "" && export.DateOnly(want.Due) != export.DateOnly(match.Due)
+	changeStatus := want.Status != "" && want.Status != match.Status
+	move := parentID != match.Parent && (parentID != "" || item.ParentUID == "")
+
+	var changes []string
+	for field, changed := range map[string]bool{"title": changeTitle, "notes": changeNotes, "due": changeDue, "status": changeStatus, "parent": move} {
+		if changed {
+			changes = append(changes, field)
+		}
+	}
+	if len(changes) == 0 {
+		return ImportAction{Action: ImportUnchanged, UID: item.UID, Task: match}, nil
+	}
+	sort.Strings(changes)
+	action := ImportAction{Action: ImportUpdated, UID: item.UID, Task: match, Changes: changes}
+	if opts.DryRun {
+		return action, nil
+	}
+
+	var err error
+	if changeTitle || changeNotes || changeDue {
+		title := want.Title
+		if title == "" {
+			title = match.Title
+		}
+		if !setNotes {
+			notes = match.Notes
+		}
+		due := want.Due
+		if due == "" {
+			due = match.Due
+		}
+		action.Task, err = client.UpdateTask(ctx, UpdateTaskOptions{TaskListID: opts.TaskListID, TaskID: match.Id, Title: title, Notes: notes, Due: due})
+		if err != nil {
+			return ImportAction{}, err
+		}
+	}
+	if changeStatus {
+		if want.Status == "completed" {
+			action.Task, err = client.CompleteTask(ctx, CompleteTaskOptions{TaskListID: opts.TaskListID, TaskID: match.Id})
+		} else {
+			action.Task, err = client.UncompleteTask(ctx, UncompleteTaskOptions{TaskListID: opts.TaskListID, TaskID: match.Id})
+		}
+		if err != nil {
+			return ImportAction{}, err
+		}
+	}
+	if move {
+		if action.Task, err = client.MoveTask(ctx, MoveTaskOptions{TaskListID: opts.TaskListID, TaskID: match.Id, Parent: parentID}); err != nil {
+			return ImportAction{}, err
+		}
+	}
+	return action, nil
+}
+
+// ImportTaskLists imports the items into the task lists named by their
+// TaskList, creating lists that do not exist. Items without a task list go
+// into opts.TaskListID. A list matches if its title is the name, with
+// underscores read as spaces. Subtasks whose parent is in another list
+// become top-level tasks.
+func ImportTaskLists(ctx context.Context, client Client, opts ImportOptions, items []export.Item) (*ImportResult, error) {
+	lists, err := client.ListTaskLists(ctx, ListTaskListsOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the items by list, in the order the lists first appear.
+	var names []string
+	groups := map[string][]export.Item{}
+	for _, item := range items {
+		if _, ok := groups[item.TaskList]; !ok {
+			names = append(names, item.TaskList)
+		}
+		groups[item.TaskList] = append(groups[item.TaskList], item)
+	}
+
+	result := &ImportResult{}
+	for _, name := range names {
+		listOpts := opts
+		if name != "" {
+			listOpts.TaskListID = findTaskListID(lists.Items, name)
+		}
+		if listOpts.TaskListID == "" {
+			result.TaskListsCreated++
+			if opts.DryRun {
+				// There is nothing to match against, so every task is new.
+				for _, item := range groups[name] {
+					result.Created++
+					result.Actions = append(result.Actions, ImportAction{Action: ImportCreated, UID: item.UID, Task: item.Task})
+				}
+				continue
+			}
+			list, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: strings.ReplaceAll(name, "_", " ")})
+			if err != nil {
+				return result, fmt.Errorf("error creating task list %q: %w", name, err)
+			}
+			listOpts.TaskListID = list.Id
+		}
+
+		listResult, err := ImportTasks(ctx, client, listOpts, groups[name])
+		if listResult != nil {
+			result.Created += listResult.Created
+			result.Updated += listResult.Updated
+			result.Unchanged += listResult.Unchanged
+			result.Actions = append(result.Actions, listResult.Actions...)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// findTaskListID returns the ID of the task list with the given name, or an
+// empty string if there is none.
+func findTaskListID(lists []*tasks.TaskList, name string) string {
+	for _, list := range lists {
+		if list.Title == name || list.Title == strings.ReplaceAll(name, "_", " ") {
+			return list.Id
+		}
+	}
+	return ""
+}
+
+// findTask returns the existing task for a UID, or nil if there is none.
+func findTask(byID, byUID map[string]*tasks.Task, uid string) *tasks.Task {
+	if uid == "" {
+		return nil
+	}
+	if task, ok := byUID[uid]; ok {
+		return task
+	}
+	return byID[uid]
+}
+
+// orderItems orders the items so that parents come before their subtasks,
+// keeping the file order otherwise. Cycles are broken arbitrarily.
+func orderItems(items []export.Item) []export.Item {
+	index := map[string]int{}
+	for i, item := range items {
+		if item.UID != "" {
+			index[item.UID] = i
+		}
+	}
+
+	ordered := make([]export.Item, 0, len(items))
+	visited := make([]bool, len(items))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		if parent, ok := index[items[i].ParentUID]; ok {
+			visit(parent)
+		}
+		ordered = append(ordered, items[i])
+	}
+	for i := range items {
+		visit(i)
+	}
+	return ordered
+}
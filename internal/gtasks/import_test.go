@@ -0,0 +1,171 @@
+package gtasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yanicksenn/gtasks/internal/export"
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestImportTasks(t *testing.T) {
+	ctx := context.Background()
+	client := newTestOfflineClient(t)
+	list, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: "Imported"})
+	if err != nil {
+		t.Fatalf("CreateTaskList failed: %v", err)
+	}
+
+	items := []export.Item{
+		{UID: "child", ParentUID: "parent", Task: &tasks.Task{Title: "Child", Status: "completed"}},
+		{UID: "parent", Task: &tasks.Task{Title: "Parent", Notes: "Notes", Due: "2026-11-01T00:00:00.000Z", Status: "needsAction"}},
+	}
+	opts := ImportOptions{TaskListID: list.Id}
+
+	// 1. A dry run changes nothing
+	result, err := ImportTasks(ctx, client, ImportOptions{TaskListID: list.Id, DryRun: true}, items)
+	if err != nil {
+		t.Fatalf("ImportTasks failed: %v", err)
+	}
+	if result.Created != 2 {
+		t.Errorf("expected 2 tasks to be created in the dry run, got %d", result.Created)
+	}
+	if listed, _ := client.ListTasks(ctx, ListTasksOptions{TaskListID: list.Id, ShowCompleted: true}); len(listed.Items) != 0 {
+		t.Errorf("expected the dry run to create no tasks, got %d", len(listed.Items))
+	}
+
+	// 2. The first import creates the tasks, parents first
+	result, err = ImportTasks(ctx, client, opts, items)
+	if err != nil {
+		t.Fatalf("ImportTasks failed: %v", err)
+	}
+	if result.Created != 2 {
+		t.Fatalf("expected 2 tasks to be created, got %d", result.Created)
+	}
+	parent, child := result.Actions[0].Task, result.Actions[1].Task
+	if parent.Title != "Parent" || child.Parent != parent.Id || child.Status != "completed" {
+		t.Errorf("unexpected tasks: %+v, %+v", parent, child)
+	}
+	if notes, uid := export.SplitUID(parent.Notes); notes != "Notes" || uid != "parent" {
+		t.Errorf("expected the UID to be recorded in the notes, got %q", parent.Notes)
+	}
+
+	// 3. Importing again changes nothing
+	result, err = ImportTasks(ctx, client, opts, items)
+	if err != nil {
+		t.Fatalf("ImportTasks failed: %v", err)
+	}
+	if result.Unchanged != 2 || result.Created != 0 || result.Updated != 0 {
+		t.Errorf("expected 2 unchanged tasks, got %+v", result)
+	}
+
+	// 4. Changed items update the existing tasks
+	items[1].Task.Title = "Renamed parent"
+	items[0].Task.Status = "needsAction"
+	result, err = ImportTasks(ctx, client, opts, items)
+	if err != nil {
+		t.Fatalf("ImportTasks failed: %v", err)
+	}
+	if result.Updated != 2 {
+		t.Errorf("expected 2 updated tasks, got %+v", result)
+	}
+	updated, err := client.GetTask(ctx, GetTaskOptions{TaskListID: list.Id, TaskID: parent.Id})
+	if err != nil || updated.Title != "Renamed parent" {
+		t.Errorf("expected the parent to be renamed, got %+v (%v)", updated, err)
+	}
+	if notes, uid := export.SplitUID(updated.Notes); notes != "Notes" || uid != "parent" {
+		t.Errorf("expected the UID to be kept in the notes, got %q", updated.Notes)
+	}
+
+	// 5. Items whose UID is a task ID match that task
+	result, err = ImportTasks(ctx, client, opts, []export.Item{{UID: child.Id, Task: &tasks.Task{Title: "Child"}}})
+	if err != nil {
+		t.Fatalf("ImportTasks failed: %v", err)
+	}
+	if result.Created != 0 {
+		t.Errorf("expected the task to be matched by ID, got %+v", result)
+	}
+}
+
+func TestImportTaskLists(t *testing.T) {
+	ctx := context.Background()
+	client := newTestOfflineClient(t)
+	work, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: "Work"})
+	if err != nil {
+		t.Fatalf("CreateTaskList failed: %v", err)
+	}
+	inbox, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: "Inbox"})
+	if err != nil {
+		t.Fatalf("CreateTaskList failed: %v", err)
+	}
+
+	items := []export.Item{
+		{TaskList: "Work", Attributes: []string{"@office"}, Task: &tasks.Task{Title: "Write report"}},
+		{TaskList: "Home_Stuff", Task: &tasks.Task{Title: "Paint the fence"}},
+		{Task: &tasks.Task{Title: "Call mom"}},
+	}
+	result, err := ImportTaskLists(ctx, client, ImportOptions{TaskListID: inbox.Id}, items)
+	if err != nil {
+		t.Fatalf("ImportTaskLists failed: %v", err)
+	}
+	if result.Created != 3 || result.TaskListsCreated != 1 {
+		t.Errorf("expected 3 tasks and 1 task list to be created, got %+v", result)
+	}
+
+	lists, _ := client.ListTaskLists(ctx, ListTaskListsOptions{})
+	home := findTaskListID(lists.Items, "Home Stuff")
+	for listID, title := range map[string]string{work.Id: "Write report", home: "Paint the fence", inbox.Id: "Call mom"} {
+		listed, err := client.ListTasks(ctx, ListTasksOptions{TaskListID: listID})
+		if err != nil || len(listed.Items) != 1 || listed.Items[0].Title != title {
+			t.Errorf("expected %q in list %q, got %+v (%v)", title, listID, listed, err)
+		}
+	}
+	if task := result.Actions[0].Task; task.Notes != "@office" {
+		t.Errorf("expected the attributes to be kept in the notes, got %q", task.Notes)
+	}
+}
+
+func TestImportTasks_LocalUIDs(t *testing.T) {
+	ctx := context.Background()
+	client := newTestOfflineClient(t)
+	list, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: "Checklist"})
+	if err != nil {
+		t.Fatalf("CreateTaskList failed: %v", err)
+	}
+
+	items := []export.Item{
+		{UID: "1", LocalUID: true, Task: &tasks.Task{Title: "First"}},
+		{UID: "2", LocalUID: true, ParentUID: "1", Task: &tasks.Task{Title: "Nested"}},
+		{UID: "3", LocalUID: true, Task: &tasks.Task{Title: "Second"}},
+	}
+	for i := 0; i < 2; i++ {
+		result, err := ImportTasks(ctx, client, ImportOptions{TaskListID: list.Id}, items)
+		if err != nil {
+			t.Fatalf("ImportTasks failed: %v", err)
+		}
+		if result.Created != 3 {
+			t.Errorf("expected local UIDs never to match, got %+v", result)
+		}
+	}
+
+	listed, err := client.ListTasks(ctx, ListTasksOptions{TaskListID: list.Id, SortBy: "position"})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	var titles []string
+	for _, task := range listed.Items {
+		if task.Notes != "" {
+			t.Errorf("expected no UID in the notes, got %q", task.Notes)
+		}
+		if task.Title == "Nested" && task.Parent == "" {
+			t.Error("expected the nested task to keep its parent")
+		}
+		if task.Parent == "" {
+			titles = append(titles, task.Title)
+		}
+	}
+	if got := strings.Join(titles, ","); got != "First,Second,First,Second" {
+		t.Errorf("expected the tasks in file order, got %s", got)
+	}
+}
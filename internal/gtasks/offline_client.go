@@ -1,6 +1,8 @@
 package gtasks
 
 import (
+	"context"
+	"iter"
 	"sort"
 	"strings"
 
@@ -16,11 +18,7 @@ type offlineClient struct {
 
 // newOfflineClient creates a new client that works with the local offline store.
 func newOfflineClient() (*offlineClient, error) {
-	path, err := store.GetOfflineStorePath()
-	if err != nil {
-		return nil, err
-	}
-	s, err := store.NewInMemoryStore(path)
+	s, err := OpenOfflineStore()
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +26,10 @@ func newOfflineClient() (*offlineClient, error) {
 }
 
 // ListTaskLists lists the task lists from the offline store.
-func (c *offlineClient) ListTaskLists(opts ListTaskListsOptions) (*tasks.TaskLists, error) {
+func (c *offlineClient) ListTaskLists(ctx context.Context, opts ListTaskListsOptions) (*tasks.TaskLists, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	lists, err := c.store.ListTaskLists()
 	if err != nil {
 		return nil, err
@@ -51,86 +52,164 @@ func (c *offlineClient) ListTaskLists(opts ListTaskListsOptions) (*tasks.TaskLis
 }
 
 // CreateTaskList creates a new task list in the offline store.
-func (c *offlineClient) CreateTaskList(opts CreateTaskListOptions) (*tasks.TaskList, error) {
+func (c *offlineClient) CreateTaskList(ctx context.Context, opts CreateTaskListOptions) (*tasks.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	list := &tasks.TaskList{
 		Title: opts.Title,
 	}
-	return c.store.CreateTaskList(list)
+	return classified(c.store.CreateTaskList(list))
 }
 
 // GetTaskList retrieves a task list from the offline store.
-func (c *offlineClient) GetTaskList(opts GetTaskListOptions) (*tasks.TaskList, error) {
-	return c.store.GetTaskList(opts.TaskListID)
+func (c *offlineClient) GetTaskList(ctx context.Context, opts GetTaskListOptions) (*tasks.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return classified(c.store.GetTaskList(opts.TaskListID))
 }
 
 // UpdateTaskList updates a task list in the offline store.
-func (c *offlineClient) UpdateTaskList(opts UpdateTaskListOptions) (*tasks.TaskList, error) {
+func (c *offlineClient) UpdateTaskList(ctx context.Context, opts UpdateTaskListOptions) (*tasks.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	list := &tasks.TaskList{
 		Title: opts.Title,
 	}
-	return c.store.UpdateTaskList(opts.TaskListID, list)
+	return classified(c.store.UpdateTaskList(opts.TaskListID, list))
 }
 
 // DeleteTaskList deletes a task list from the offline store.
-func (c *offlineClient) DeleteTaskList(opts DeleteTaskListOptions) error {
-	return c.store.DeleteTaskList(opts.TaskListID)
+func (c *offlineClient) DeleteTaskList(ctx context.Context, opts DeleteTaskListOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return classify(c.store.DeleteTaskList(opts.TaskListID))
 }
 
 // ListTasks lists the tasks from the offline store.
-func (c *offlineClient) ListTasks(opts ListTasksOptions) (*tasks.Tasks, error) {
-	taskItems, err := c.store.ListTasks(opts.TaskListID)
+func (c *offlineClient) ListTasks(ctx context.Context, opts ListTasksOptions) (*tasks.Tasks, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	allItems, err := c.store.ListTasks(opts.TaskListID)
 	if err != nil {
 		return nil, err
 	}
+	// Apply the same visibility rules as the API.
+	var taskItems []*tasks.Task
+	for _, task := range allItems {
+		if (task.Status == "completed" && !opts.ShowCompleted) || (task.Hidden && !opts.ShowHidden) {
+			continue
+		}
+		taskItems = append(taskItems, task)
+	}
+	sortTasks(taskItems, opts.SortBy)
+	if opts.MaxResults > 0 && int64(len(taskItems)) > opts.MaxResults {
+		taskItems = taskItems[:opts.MaxResults]
+	}
 	return &tasks.Tasks{Items: taskItems}, nil
 }
 
+// AllTasks returns an iterator over the tasks in the offline store.
+func (c *offlineClient) AllTasks(ctx context.Context, opts ListTasksOptions) iter.Seq2[*tasks.Task, error] {
+	return func(yield func(*tasks.Task, error) bool) {
+		result, err := c.ListTasks(ctx, opts)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, task := range result.Items {
+			if !yield(task, nil) {
+				return
+			}
+		}
+	}
+}
+
 // CreateTask creates a new task in the offline store.
-func (c *offlineClient) CreateTask(opts CreateTaskOptions) (*tasks.Task, error) {
+func (c *offlineClient) CreateTask(ctx context.Context, opts CreateTaskOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	task := &tasks.Task{
-		Title: opts.Title,
-		Notes: opts.Notes,
-		Due:   opts.Due,
+		Title:  opts.Title,
+		Notes:  opts.Notes,
+		Due:    opts.Due,
+		Parent: opts.Parent,
 	}
-	return c.store.CreateTask(opts.TaskListID, task)
+	return classified(c.store.CreateTask(opts.TaskListID, task, opts.Previous))
 }
 
 // GetTask retrieves a task from the offline store.
-func (c *offlineClient) GetTask(opts GetTaskOptions) (*tasks.Task, error) {
-	return c.store.GetTask(opts.TaskListID, opts.TaskID)
+func (c *offlineClient) GetTask(ctx context.Context, opts GetTaskOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return classified(c.store.GetTask(opts.TaskListID, opts.TaskID))
 }
 
 // UpdateTask updates a task in the offline store.
-func (c *offlineClient) UpdateTask(opts UpdateTaskOptions) (*tasks.Task, error) {
+func (c *offlineClient) UpdateTask(ctx context.Context, opts UpdateTaskOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	task := &tasks.Task{
 		Title: opts.Title,
 		Notes: opts.Notes,
 		Due:   opts.Due,
 	}
-	return c.store.UpdateTask(opts.TaskListID, opts.TaskID, task)
+	return classified(c.store.UpdateTask(opts.TaskListID, opts.TaskID, task))
+}
+
+// ReplaceTask replaces the fields of a task in the offline store.
+func (c *offlineClient) ReplaceTask(ctx context.Context, opts ReplaceTaskOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	task := &tasks.Task{
+		Title:  opts.Title,
+		Notes:  opts.Notes,
+		Due:    opts.Due,
+		Status: opts.Status,
+		Hidden: opts.Hidden,
+	}
+	if opts.Completed != "" {
+		task.Completed = &opts.Completed
+	}
+	return classified(c.store.ReplaceTask(opts.TaskListID, opts.TaskID, task))
 }
 
 // CompleteTask marks a task as complete in the offline store.
-func (c *offlineClient) CompleteTask(opts CompleteTaskOptions) (*tasks.Task, error) {
-	task, err := c.store.GetTask(opts.TaskListID, opts.TaskID)
-	if err != nil {
+func (c *offlineClient) CompleteTask(ctx context.Context, opts CompleteTaskOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	task.Status = "completed"
-	return c.store.UpdateTask(opts.TaskListID, opts.TaskID, task)
+	return classified(c.store.UpdateTask(opts.TaskListID, opts.TaskID, &tasks.Task{Status: "completed"}))
 }
 
 // UncompleteTask marks a task as not complete in the offline store.
-func (c *offlineClient) UncompleteTask(opts UncompleteTaskOptions) (*tasks.Task, error) {
-	task, err := c.store.GetTask(opts.TaskListID, opts.TaskID)
-	if err != nil {
+func (c *offlineClient) UncompleteTask(ctx context.Context, opts UncompleteTaskOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	task.Status = "needsAction"
-	return c.store.UpdateTask(opts.TaskListID, opts.TaskID, task)
+	return classified(c.store.UpdateTask(opts.TaskListID, opts.TaskID, &tasks.Task{Status: "needsAction"}))
+}
+
+// MoveTask moves a task to another parent or position in the offline store.
+func (c *offlineClient) MoveTask(ctx context.Context, opts MoveTaskOptions) (*tasks.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return classified(c.store.MoveTask(opts.TaskListID, opts.TaskID, opts.Parent, opts.Previous))
 }
 
 // DeleteTask deletes a task from the offline store.
-func (c *offlineClient) DeleteTask(opts DeleteTaskOptions) error {
-	return c.store.DeleteTask(opts.TaskListID, opts.TaskID)
+func (c *offlineClient) DeleteTask(ctx context.Context, opts DeleteTaskOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return classify(c.store.DeleteTask(opts.TaskListID, opts.TaskID))
 }
@@ -0,0 +1,640 @@
+package gtasks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yanicksenn/gtasks/internal/dates"
+	"google.golang.org/api/tasks/v1"
+)
+
+// Query is a compiled task query such as
+//
+//	status:needsAction AND due<today+7d AND (title~"deploy" OR notes~/JIRA-\d+/)
+//
+// A query consists of terms combined with AND, OR, NOT and parentheses.
+// Terms next to each other are combined with AND, and a leading "-" negates
+// a term. A term is either free text, which matches the title or the notes,
+// or a field, an operator and a value:
+//
+//	title, notes        : and ~ (contains, or regex if the value is /.../),
+//	                    = and != (equals, case-insensitive)
+//	status              : = != with needsAction (open) or completed (done)
+//	due, completed,     : = != < <= > >= with a date accepted by dates.Parse
+//	updated             such as 2026-10-01, today+7d or "next friday", an
+//	                    instant such as now-12h, or a range such as
+//	                    2026-10-01..2026-10-31
+//	parent              : = != with a task ID
+//	has                 : with parent, notes, due or completed
+type Query struct {
+	input string
+	match predicate
+}
+
+// QueryError describes a syntax error in a query. Pos is the byte offset of
+// the offending token.
+type QueryError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+// Error returns the message followed by the query with a marker below the
+// offending token.
+func (e *QueryError) Error() string {
+	column := utf8.RuneCountInString(e.Query[:e.Pos])
+	return fmt.Sprintf("%s at position %d\n  %s\n  %s^", e.Msg, column+1, e.Query, strings.Repeat(" ", column))
+}
+
+// Is reports whether target is ErrInvalidArgument, the kind of all query
+// errors.
+func (e *QueryError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// predicate reports whether a task matches part of a query.
+type predicate func(task *tasks.Task) bool
+
+// ParseQuery parses and compiles a query. Relative dates such as "today" are
+// resolved against now, in the location of now.
+func ParseQuery(input string, now time.Time) (*Query, error) {
+	p := &queryParser{lexer: queryLexer{input: input}, now: now}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, p.errorf(p.tok.pos, "empty query")
+	}
+
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf(p.tok.pos, "unexpected %s", p.tok)
+	}
+	return &Query{input: input, match: match}, nil
+}
+
+// Match reports whether the task matches the query.
+func (q *Query) Match(task *tasks.Task) bool {
+	return q.match(task)
+}
+
+// String returns the query as it was given.
+func (q *Query) String() string {
+	return q.input
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokTerm
+)
+
+// token is a lexical token of a query. Terms carry their field, operator and
+// value with the positions of each part.
+type token struct {
+	kind     tokenKind
+	pos      int
+	field    string // Empty for free text.
+	op       string
+	opPos    int
+	value    string
+	valuePos int
+	regex    bool // The value was given as /.../.
+	flags    string
+}
+
+// String describes the token for error messages.
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokLParen:
+		return `"("`
+	case tokRParen:
+		return `")"`
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	case tokNot:
+		return "NOT"
+	}
+	if t.field == "" {
+		return fmt.Sprintf("%q", t.value)
+	}
+	return fmt.Sprintf("term %q", t.field+t.op+t.value)
+}
+
+// queryLexer splits a query into tokens.
+type queryLexer struct {
+	input string
+	pos   int
+}
+
+// isOperatorChar reports whether c starts an operator.
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte(":~=<>!", c) >= 0
+}
+
+// next returns the next token of the query.
+func (l *queryLexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(rune(l.input[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	switch c := l.input[l.pos]; {
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen, pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen, pos: start}, nil
+	case c == '-' && l.pos+1 < len(l.input) && !unicode.IsSpace(rune(l.input[l.pos+1])):
+		l.pos++
+		return token{kind: tokNot, pos: start}, nil
+	case c == '"':
+		value, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokTerm, pos: start, value: value, valuePos: start}, nil
+	}
+
+	// A word is either a keyword, free text or the field of a term.
+	for l.pos < len(l.input) && !l.isWordEnd() && !isOperatorChar(l.input[l.pos]) {
+		l.pos++
+	}
+	word := l.input[start:l.pos]
+	if l.pos >= len(l.input) || !isOperatorChar(l.input[l.pos]) {
+		switch word {
+		case "AND":
+			return token{kind: tokAnd, pos: start}, nil
+		case "OR":
+			return token{kind: tokOr, pos: start}, nil
+		case "NOT":
+			return token{kind: tokNot, pos: start}, nil
+		}
+		return token{kind: tokTerm, pos: start, value: word, valuePos: start}, nil
+	}
+	if word == "" {
+		return token{}, &QueryError{Query: l.input, Pos: start, Msg: "missing field name before operator"}
+	}
+
+	tok := token{kind: tokTerm, pos: start, field: strings.ToLower(word), opPos: l.pos}
+	for _, op := range []string{"!=", "<=", ">=", ":", "~", "=", "<", ">"} {
+		if strings.HasPrefix(l.input[l.pos:], op) {
+			tok.op = op
+			break
+		}
+	}
+	if tok.op == "" {
+		return token{}, &QueryError{Query: l.input, Pos: l.pos, Msg: "invalid operator"}
+	}
+	l.pos += len(tok.op)
+	tok.valuePos = l.pos
+
+	var err error
+	switch {
+	case l.pos < len(l.input) && l.input[l.pos] == '"':
+		tok.value, err = l.quoted()
+	case l.pos < len(l.input) && l.input[l.pos] == '/':
+		tok.value, tok.flags, err = l.regex()
+		tok.regex = true
+	default:
+		for l.pos < len(l.input) && !l.isWordEnd() {
+			l.pos++
+		}
+		tok.value = l.input[tok.valuePos:l.pos]
+		if tok.value == "" {
+			err = &QueryError{Query: l.input, Pos: tok.valuePos, Msg: fmt.Sprintf("missing value after %q", word+tok.op)}
+		}
+	}
+	return tok, err
+}
+
+// isWordEnd reports whether the current character ends a bare word.
+func (l *queryLexer) isWordEnd() bool {
+	c := l.input[l.pos]
+	return c == '(' || c == ')' || unicode.IsSpace(rune(c))
+}
+
+// quoted reads a double-quoted string with backslash escapes.
+func (l *queryLexer) quoted() (string, error) {
+	start := l.pos
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.input); l.pos++ {
+		switch c := l.input[l.pos]; c {
+		case '\\':
+			if l.pos+1 < len(l.input) {
+				l.pos++
+				b.WriteByte(l.input[l.pos])
+			}
+		case '"':
+			l.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", &QueryError{Query: l.input, Pos: start, Msg: "unterminated string"}
+}
+
+// regex reads a regular expression between slashes, followed by optional
+// flags. An escaped slash is kept as part of the expression.
+func (l *queryLexer) regex() (string, string, error) {
+	start := l.pos
+	for l.pos++; l.pos < len(l.input); l.pos++ {
+		switch l.input[l.pos] {
+		case '\\':
+			l.pos++
+		case '/':
+			expr := l.input[start+1 : l.pos]
+			l.pos++
+			flagsStart := l.pos
+			for l.pos < len(l.input) && !l.isWordEnd() {
+				l.pos++
+			}
+			return expr, l.input[flagsStart:l.pos], nil
+		}
+	}
+	return "", "", &QueryError{Query: l.input, Pos: start, Msg: "unterminated regular expression"}
+}
+
+// queryParser is a recursive descent parser for queries:
+//
+//	or      = and { "OR" and }
+//	and     = unary { ["AND"] unary }
+//	unary   = ("NOT" | "-") unary | primary
+//	primary = "(" or ")" | term
+type queryParser struct {
+	lexer queryLexer
+	tok   token
+	now   time.Time
+}
+
+// next advances to the next token.
+func (p *queryParser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// errorf returns a QueryError at the given position.
+func (p *queryParser) errorf(pos int, format string, args ...any) error {
+	return &QueryError{Query: p.lexer.input, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) parseOr() (predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(task *tasks.Task) bool { return l(task) || right(task) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.tok.kind {
+		case tokAnd:
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		case tokTerm, tokLParen, tokNot:
+			// Terms next to each other are combined with AND.
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(task *tasks.Task) bool { return l(task) && right(task) }
+	}
+}
+
+func (p *queryParser) parseUnary() (predicate, error) {
+	if p.tok.kind != tokNot {
+		return p.parsePrimary()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return func(task *tasks.Task) bool { return !operand(task) }, nil
+}
+
+func (p *queryParser) parsePrimary() (predicate, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokLParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokRParen {
+			return nil, p.errorf(p.tok.pos, "empty parentheses")
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf(tok.pos, "missing closing parenthesis")
+		}
+		return inner, p.next()
+	case tokTerm:
+		match, err := p.compileTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		return match, p.next()
+	}
+	return nil, p.errorf(tok.pos, "expected a term but found %s", tok)
+}
+
+// textFields maps the names of text fields to their accessors.
+var textFields = map[string]func(task *tasks.Task) string{
+	"title": func(task *tasks.Task) string { return task.Title },
+	"notes": func(task *tasks.Task) string { return task.Notes },
+}
+
+// dateFields maps the names of date fields to their accessors.
+var dateFields = map[string]func(task *tasks.Task) string{
+	"due":       func(task *tasks.Task) string { return task.Due },
+	"completed": func(task *tasks.Task) string { return stringValue(task.Completed) },
+	"updated":   func(task *tasks.Task) string { return task.Updated },
+}
+
+// hasValues maps the values of the has field to their predicates.
+var hasValues = map[string]predicate{
+	"parent":    func(task *tasks.Task) bool { return task.Parent != "" },
+	"notes":     func(task *tasks.Task) bool { return task.Notes != "" },
+	"due":       func(task *tasks.Task) bool { return task.Due != "" },
+	"completed": func(task *tasks.Task) bool { return stringValue(task.Completed) != "" },
+}
+
+// compileTerm compiles a single term into a predicate.
+func (p *queryParser) compileTerm(tok token) (predicate, error) {
+	if tok.field == "" {
+		text := strings.ToLower(tok.value)
+		return func(task *tasks.Task) bool {
+			return strings.Contains(strings.ToLower(task.Title), text) || strings.Contains(strings.ToLower(task.Notes), text)
+		}, nil
+	}
+	if tok.regex && tok.op != "~" && tok.op != ":" {
+		return nil, p.errorf(tok.opPos, "regular expressions require the ~ operator")
+	}
+
+	if get, ok := textFields[tok.field]; ok {
+		return p.compileText(tok, get)
+	}
+	if get, ok := dateFields[tok.field]; ok {
+		return p.compileDate(tok, get)
+	}
+
+	switch tok.field {
+	case "status":
+		if err := p.checkOperator(tok, ":", "=", "!="); err != nil {
+			return nil, err
+		}
+		var status string
+		switch strings.ToLower(tok.value) {
+		case "needsaction", "open":
+			status = "needsAction"
+		case "completed", "done":
+			status = "completed"
+		default:
+			return nil, p.errorf(tok.valuePos, "unknown status %q (expected needsAction or completed)", tok.value)
+		}
+		return negateIf(tok.op == "!=", func(task *tasks.Task) bool { return task.Status == status }), nil
+	case "parent":
+		if err := p.checkOperator(tok, ":", "=", "!="); err != nil {
+			return nil, err
+		}
+		return negateIf(tok.op == "!=", func(task *tasks.Task) bool { return task.Parent == tok.value }), nil
+	case "has":
+		if err := p.checkOperator(tok, ":"); err != nil {
+			return nil, err
+		}
+		match, ok := hasValues[strings.ToLower(tok.value)]
+		if !ok {
+			return nil, p.errorf(tok.valuePos, "unknown value %q for has (expected parent, notes, due or completed)", tok.value)
+		}
+		return match, nil
+	}
+	return nil, p.errorf(tok.pos, "unknown field %q", tok.field)
+}
+
+// checkOperator returns an error if the operator of the term is not one of
+// the given operators.
+func (p *queryParser) checkOperator(tok token, ops ...string) error {
+	for _, op := range ops {
+		if tok.op == op {
+			return nil
+		}
+	}
+	return p.errorf(tok.opPos, "operator %q is not supported for %s (expected one of %s)", tok.op, tok.field, strings.Join(ops, " "))
+}
+
+// compileText compiles a term on a text field.
+func (p *queryParser) compileText(tok token, get func(task *tasks.Task) string) (predicate, error) {
+	if err := p.checkOperator(tok, ":", "~", "=", "!="); err != nil {
+		return nil, err
+	}
+	if tok.regex {
+		expr := tok.value
+		if tok.flags != "" {
+			if strings.Trim(tok.flags, "ims") != "" {
+				return nil, p.errorf(tok.valuePos, "invalid regular expression flags %q (expected i, m or s)", tok.flags)
+			}
+			expr = "(?" + tok.flags + ")" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, p.errorf(tok.valuePos, "invalid regular expression: %v", err)
+		}
+		return func(task *tasks.Task) bool { return re.MatchString(get(task)) }, nil
+	}
+
+	value := strings.ToLower(tok.value)
+	switch tok.op {
+	case "=":
+		return func(task *tasks.Task) bool { return strings.ToLower(get(task)) == value }, nil
+	case "!=":
+		return func(task *tasks.Task) bool { return strings.ToLower(get(task)) != value }, nil
+	}
+	return func(task *tasks.Task) bool { return strings.Contains(strings.ToLower(get(task)), value) }, nil
+}
+
+// dateValue is the period denoted by a date in a query. Dates without a
+// time denote the whole day; instants have an empty period.
+type dateValue struct {
+	start time.Time
+	end   time.Time
+}
+
+// compileDate compiles a term on a date field.
+func (p *queryParser) compileDate(tok token, get func(task *tasks.Task) string) (predicate, error) {
+	if err := p.checkOperator(tok, ":", "=", "!=", "<", "<=", ">", ">="); err != nil {
+		return nil, err
+	}
+
+	var value dateValue
+	if from, to, ok := strings.Cut(tok.value, ".."); ok {
+		if tok.op != ":" && tok.op != "=" {
+			return nil, p.errorf(tok.opPos, "ranges require the : or = operator")
+		}
+		start, err := p.parseDate(from, tok.valuePos)
+		if err != nil {
+			return nil, err
+		}
+		end, err := p.parseDate(to, tok.valuePos+len(from)+2)
+		if err != nil {
+			return nil, err
+		}
+		value = dateValue{start: start.start, end: end.end}
+	} else {
+		var err error
+		if value, err = p.parseDate(tok.value, tok.valuePos); err != nil {
+			return nil, err
+		}
+	}
+
+	instant := value.start.Equal(value.end)
+	loc := p.now.Location()
+	isDue := tok.field == "due"
+	return func(task *tasks.Task) bool {
+		t, ok := taskTime(get(task), isDue, loc)
+		if !ok {
+			return false
+		}
+		within := !t.Before(value.start) && (t.Before(value.end) || instant && t.Equal(value.end))
+		switch tok.op {
+		case "!=":
+			return !within
+		case "<":
+			return t.Before(value.start)
+		case "<=":
+			return t.Before(value.end) || instant && t.Equal(value.end)
+		case ">":
+			return t.After(value.end) || !instant && t.Equal(value.end)
+		case ">=":
+			return !t.Before(value.start)
+		}
+		return within
+	}, nil
+}
+
+// relativeInstant matches an instant with an offset, such as now-12h.
+var relativeInstant = regexp.MustCompile(`^(.+?)([+-]\d+)([hdwmy])$`)
+
+// parseDate parses a single date of a query. "now" and RFC3339 timestamps,
+// optionally with an offset, denote instants; all other dates are parsed
+// with dates.Parse and denote a whole day.
+func (p *queryParser) parseDate(s string, pos int) (dateValue, error) {
+	base, n, unit := s, 0, byte(0)
+	if m := relativeInstant.FindStringSubmatch(s); m != nil {
+		base, unit = m[1], m[3][0]
+		n, _ = strconv.Atoi(m[2])
+	}
+
+	var instant time.Time
+	if strings.EqualFold(base, "now") {
+		instant = p.now
+	} else if t, err := time.Parse(time.RFC3339, base); err == nil {
+		instant = t
+	} else {
+		date, err := dates.Parse(s, p.now)
+		if err != nil {
+			return dateValue{}, p.errorf(pos, "%v", err)
+		}
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, p.now.Location())
+		return dateValue{start: day, end: day.AddDate(0, 0, 1)}, nil
+	}
+
+	switch unit {
+	case 'h':
+		instant = instant.Add(time.Duration(n) * time.Hour)
+	case 'd':
+		instant = instant.AddDate(0, 0, n)
+	case 'w':
+		instant = instant.AddDate(0, 0, 7*n)
+	case 'm':
+		instant = instant.AddDate(0, n, 0)
+	case 'y':
+		instant = instant.AddDate(n, 0, 0)
+	}
+	return dateValue{start: instant, end: instant}, nil
+}
+
+// taskTime parses a timestamp of a task. Due dates only carry a date, so they
+// are mapped to midnight of that date in the given location.
+func taskTime(s string, isDue bool, loc *time.Location) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if isDue {
+		t = t.UTC()
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+	return t, true
+}
+
+// negateIf negates the predicate if negate is set.
+func negateIf(negate bool, match predicate) predicate {
+	if negate {
+		return func(task *tasks.Task) bool { return !match(task) }
+	}
+	return match
+}
+
+// stringValue returns the string s points to, or the empty string if s is nil.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
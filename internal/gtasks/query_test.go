@@ -0,0 +1,144 @@
+package gtasks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestQuery(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	completed := "2026-10-12T16:00:00.000Z"
+	items := []*tasks.Task{
+		{
+			Id:      "deploy",
+			Title:   "Deploy service",
+			Status:  "needsAction",
+			Due:     "2026-10-16T00:00:00.000Z",
+			Updated: "2026-10-14T08:00:00.000Z",
+		},
+		{
+			Id:      "ticket",
+			Title:   "Fix bug",
+			Notes:   "See JIRA-123",
+			Status:  "needsAction",
+			Due:     "2026-10-30T00:00:00.000Z",
+			Updated: "2026-10-01T08:00:00.000Z",
+			Parent:  "deploy",
+		},
+		{
+			Id:        "report",
+			Title:     "Write report",
+			Status:    "completed",
+			Completed: &completed,
+			Updated:   "2026-10-12T16:00:00.000Z",
+		},
+	}
+
+	testCases := []struct {
+		query       string
+		expectedIDs []string
+	}{
+		{`status:needsAction AND due<today+7d AND (title~"deploy" OR notes~/JIRA-\d+/)`, []string{"deploy"}},
+		{`status:needsAction (title~"deploy" OR notes~/JIRA-\d+/)`, []string{"deploy", "ticket"}},
+		{`title~deploy OR title~report`, []string{"deploy", "report"}},
+		{`title="fix bug"`, []string{"ticket"}},
+		{`notes~/jira-\d+/i`, []string{"ticket"}},
+		{`notes~/jira-\d+/`, nil},
+		{`report`, []string{"report"}},
+		{`"jira-123"`, []string{"ticket"}},
+		{`status:done`, []string{"report"}},
+		{`status!=completed`, []string{"deploy", "ticket"}},
+		{`NOT status:completed`, []string{"deploy", "ticket"}},
+		{`-status:completed -has:parent`, []string{"deploy"}},
+		{`has:parent`, []string{"ticket"}},
+		{`parent:deploy`, []string{"ticket"}},
+		{`has:due`, []string{"deploy", "ticket"}},
+		{`due:2026-10-16`, []string{"deploy"}},
+		{`due<=2026-10-16`, []string{"deploy"}},
+		{`due<2026-10-16`, nil},
+		{`due>2026-10-16`, []string{"ticket"}},
+		{`due>=today+2d`, []string{"deploy", "ticket"}},
+		{`due:today..today+1w`, []string{"deploy"}},
+		{`due:friday`, []string{"deploy"}},
+		{`due<="next friday"`, []string{"deploy"}},
+		{`due<"in 2 weeks"`, []string{"deploy"}},
+		{`due:2026-10-01..2026-10-31`, []string{"deploy", "ticket"}},
+		{`completed:2026-10-12`, []string{"report"}},
+		{`completed>=today-7d`, []string{"report"}},
+		{`completed<2026-10-01`, nil},
+		{`updated>=yesterday`, []string{"deploy"}},
+		{`updated>now-12h`, []string{"deploy"}},
+		{`updated<2026-10-12T16:00:00Z OR updated>now`, []string{"ticket"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			query, err := ParseQuery(tc.query, now)
+			if err != nil {
+				t.Fatalf("ParseQuery failed: %v", err)
+			}
+			var ids []string
+			for _, task := range items {
+				if query.Match(task) {
+					ids = append(ids, task.Id)
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(tc.expectedIDs, ",") {
+				t.Errorf("expected %v, got %v", tc.expectedIDs, ids)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	testCases := []struct {
+		query       string
+		expectedPos int
+		expectedMsg string
+	}{
+		{``, 0, "empty query"},
+		{`titel:deploy`, 0, `unknown field "titel"`},
+		{`title:deploy AND`, 16, "expected a term but found end of query"},
+		{`(title:deploy`, 0, "missing closing parenthesis"},
+		{`title:deploy)`, 12, `unexpected ")"`},
+		{`status:maybe`, 7, `unknown status "maybe"`},
+		{`due<soon`, 4, `invalid date "soon"`},
+		{`due:today..later`, 11, `invalid date "later"`},
+		{`due<today..tomorrow`, 3, "ranges require the : or = operator"},
+		{`title<deploy`, 5, `operator "<" is not supported for title`},
+		{`title:"deploy`, 6, "unterminated string"},
+		{`notes~/JIRA-(\d+/`, 6, "invalid regular expression"},
+		{`notes~/JIRA`, 6, "unterminated regular expression"},
+		{`has:children`, 4, `unknown value "children" for has`},
+		{`title:`, 6, `missing value after "title:"`},
+		{`due!today`, 3, "invalid operator"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := ParseQuery(tc.query, time.Now())
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("expected a QueryError, got %v", err)
+			}
+			if queryErr.Pos != tc.expectedPos {
+				t.Errorf("expected error at position %d, got %d: %v", tc.expectedPos, queryErr.Pos, err)
+			}
+			if !strings.HasPrefix(queryErr.Msg, tc.expectedMsg) {
+				t.Errorf("expected message starting with '%s', got '%s'", tc.expectedMsg, queryErr.Msg)
+			}
+		})
+	}
+}
+
+func TestQueryErrorMessage(t *testing.T) {
+	_, err := ParseQuery(`status:open AND titel:x`, time.Now())
+	expected := "unknown field \"titel\" at position 17\n  status:open AND titel:x\n                  ^"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error\n%s\ngot\n%v", expected, err)
+	}
+}
@@ -0,0 +1,217 @@
+package gtasks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/config"
+)
+
+const (
+	defaultMaxAttempts  = 4
+	defaultInitialDelay = 500 * time.Millisecond
+	defaultMaxDelay     = 30 * time.Second
+)
+
+// retryTransport is an http.RoundTripper that retries requests which failed
+// with a network error, a rate limit or a server error. It waits with
+// exponential backoff and jitter between attempts and honours the
+// Retry-After header.
+type retryTransport struct {
+	base               http.RoundTripper
+	maxAttempts        int
+	initialDelay       time.Duration
+	maxDelay           time.Duration
+	retryNonIdempotent bool
+	// sleep waits for the given duration or until the context is done.
+	sleep func(ctx context.Context, d time.Duration) error
+	// jitter returns a random number in [0, 1).
+	jitter func() float64
+}
+
+// newRetryTransport wraps the base transport with the retry settings from
+// the configuration.
+func newRetryTransport(base http.RoundTripper, cfg config.RetryConfig) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &retryTransport{
+		base:               base,
+		maxAttempts:        cfg.MaxAttempts,
+		initialDelay:       cfg.InitialDelay,
+		maxDelay:           cfg.MaxDelay,
+		retryNonIdempotent: cfg.RetryNonIdempotent,
+		sleep:              sleepContext,
+		jitter:             rand.Float64,
+	}
+	if t.maxAttempts <= 0 {
+		t.maxAttempts = defaultMaxAttempts
+	}
+	if t.initialDelay <= 0 {
+		t.initialDelay = defaultInitialDelay
+	}
+	if t.maxDelay <= 0 {
+		t.maxDelay = defaultMaxDelay
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req.Method) || t.retryNonIdempotent
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retryable = false // The body cannot be replayed.
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if attempt > 1 && req.Method == http.MethodDelete && err == nil && resp.StatusCode == http.StatusNotFound {
+			// An earlier attempt deleted the resource, but its response was
+			// lost.
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			return noContent(req), nil
+		}
+		if !retryable || attempt >= t.maxAttempts || !shouldRetry(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		// Return the response if the server asks to wait for longer than
+		// the maximum delay or the deadline of the request.
+		delay, ok := t.backoff(attempt, resp)
+		if deadline, hasDeadline := req.Context().Deadline(); !ok || hasDeadline && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt. A Retry-After header
+// takes precedence over the exponential backoff; ok is false if it asks for
+// more than the maximum delay.
+func (t *retryTransport) backoff(attempt int, resp *http.Response) (delay time.Duration, ok bool) {
+	if resp != nil {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return delay, delay <= t.maxDelay
+		}
+	}
+
+	delay = t.initialDelay << (attempt - 1)
+	if delay > t.maxDelay || delay <= 0 {
+		delay = t.maxDelay
+	}
+	// Equal jitter: wait at least half of the delay.
+	half := delay / 2
+	return half + time.Duration(t.jitter()*float64(delay-half)), true
+}
+
+// noContent returns an empty successful response to the request.
+func noContent(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
+
+// shouldRetry reports whether a request that resulted in the given response
+// or error is worth retrying.
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusForbidden:
+		return isRateLimitResponse(resp)
+	}
+	return false
+}
+
+// isRateLimitResponse reports whether a 403 response is caused by a rate
+// limit, which Google reports with a rateLimitExceeded reason. The body is
+// restored so that it can still be read by the caller.
+func isRateLimitResponse(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(body, []byte("rateLimitExceeded")) || bytes.Contains(body, []byte("userRateLimitExceeded"))
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether requests with the given method can safely be
+// sent more than once. gtasks only patches fields to fixed values, so its
+// PATCH requests are idempotent too.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
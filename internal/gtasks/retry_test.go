@@ -0,0 +1,306 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/config"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/tasks/v1"
+)
+
+// newRetryTestClient returns an online client that talks to the given server
+// through a retry transport, and records the delays it waited.
+func newRetryTestClient(t *testing.T, serverURL string, cfg config.RetryConfig) (Client, *[]time.Duration) {
+	t.Helper()
+	delays := &[]time.Duration{}
+	transport := newRetryTransport(http.DefaultTransport, cfg)
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+	transport.jitter = func() float64 { return 0 }
+
+	service, err := tasks.NewService(context.Background(),
+		option.WithEndpoint(serverURL+"/"),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	return &onlineClient{service: service}, delays
+}
+
+// failingHandler fails the first n requests with the given status before
+// answering with a task list.
+func failingHandler(n int32, status int, header http.Header, calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= n {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"code":%d,"message":"failure"}}`, status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"list1","title":"Inbox"}`))
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	testCases := []struct {
+		name          string
+		failures      int32
+		status        int
+		header        http.Header
+		cfg           config.RetryConfig
+		expectedCalls int32
+		expectedError bool
+		expectedDelay []time.Duration
+	}{
+		{
+			name:          "Retries server errors with backoff",
+			failures:      2,
+			status:        http.StatusServiceUnavailable,
+			cfg:           config.RetryConfig{InitialDelay: time.Second},
+			expectedCalls: 3,
+			expectedDelay: []time.Duration{500 * time.Millisecond, time.Second},
+		},
+		{
+			name:          "Honours Retry-After",
+			failures:      1,
+			status:        http.StatusTooManyRequests,
+			header:        http.Header{"Retry-After": {"7"}},
+			expectedCalls: 2,
+			expectedDelay: []time.Duration{7 * time.Second},
+		},
+		{
+			name:          "Gives up if Retry-After exceeds the maximum delay",
+			failures:      1,
+			status:        http.StatusTooManyRequests,
+			header:        http.Header{"Retry-After": {"3600"}},
+			expectedCalls: 1,
+			expectedError: true,
+		},
+		{
+			name:          "Caps the delay",
+			failures:      3,
+			status:        http.StatusBadGateway,
+			cfg:           config.RetryConfig{InitialDelay: time.Second, MaxDelay: 2 * time.Second},
+			expectedCalls: 4,
+			expectedDelay: []time.Duration{500 * time.Millisecond, time.Second, time.Second},
+		},
+		{
+			name:          "Gives up after max attempts",
+			failures:      5,
+			status:        http.StatusInternalServerError,
+			cfg:           config.RetryConfig{MaxAttempts: 2},
+			expectedCalls: 2,
+			expectedError: true,
+			expectedDelay: []time.Duration{250 * time.Millisecond},
+		},
+		{
+			name:          "Does not retry client errors",
+			failures:      1,
+			status:        http.StatusBadRequest,
+			expectedCalls: 1,
+			expectedError: true,
+		},
+		{
+			name:          "Disabled with a single attempt",
+			failures:      1,
+			status:        http.StatusServiceUnavailable,
+			cfg:           config.RetryConfig{MaxAttempts: 1},
+			expectedCalls: 1,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(failingHandler(tc.failures, tc.status, tc.header, &calls))
+			defer server.Close()
+
+			client, delays := newRetryTestClient(t, server.URL, tc.cfg)
+			list, err := client.GetTaskList(context.Background(), GetTaskListOptions{TaskListID: "list1"})
+			if tc.expectedError {
+				var apiErr *googleapi.Error
+				if !errors.As(err, &apiErr) || apiErr.Code != tc.status {
+					t.Errorf("expected API error with code %d, got %v", tc.status, err)
+				}
+			} else if err != nil {
+				t.Fatalf("GetTaskList failed: %v", err)
+			} else if list.Title != "Inbox" {
+				t.Errorf("expected title 'Inbox', got '%s'", list.Title)
+			}
+
+			if calls != tc.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tc.expectedCalls, calls)
+			}
+			if len(*delays) != len(tc.expectedDelay) {
+				t.Fatalf("expected delays %v, got %v", tc.expectedDelay, *delays)
+			}
+			for i, d := range tc.expectedDelay {
+				if (*delays)[i] != d {
+					t.Errorf("expected delay %d to be %v, got %v", i, d, (*delays)[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransport_NonIdempotent(t *testing.T) {
+	for _, retry := range []bool{false, true} {
+		var calls int32
+		server := httptest.NewServer(failingHandler(1, http.StatusServiceUnavailable, nil, &calls))
+		defer server.Close()
+
+		client, _ := newRetryTestClient(t, server.URL, config.RetryConfig{RetryNonIdempotent: retry})
+		_, err := client.CreateTaskList(context.Background(), CreateTaskListOptions{Title: "Inbox"})
+
+		expectedCalls := int32(1)
+		if retry {
+			expectedCalls = 2
+			if err != nil {
+				t.Errorf("expected create to succeed after a retry, got %v", err)
+			}
+		} else if err == nil {
+			t.Error("expected create to fail without retries")
+		}
+		if calls != expectedCalls {
+			t.Errorf("retry=%v: expected %d calls, got %d", retry, expectedCalls, calls)
+		}
+	}
+}
+
+func TestRetryTransport_Update(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"task1","title":"Renamed"}`))
+	}))
+	defer server.Close()
+
+	// Updates are retried by default
+	client, _ := newRetryTestClient(t, server.URL, config.RetryConfig{})
+	if _, err := client.UpdateTask(context.Background(), UpdateTaskOptions{TaskListID: "list1", TaskID: "task1", Title: "Renamed"}); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestRetryTransport_RateLimitForbidden(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":403,"errors":[{"reason":"userRateLimitExceeded"}]}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"list1","title":"Inbox"}`))
+	}))
+	defer server.Close()
+
+	client, _ := newRetryTestClient(t, server.URL, config.RetryConfig{})
+	if _, err := client.GetTaskList(context.Background(), GetTaskListOptions{TaskListID: "list1"}); err != nil {
+		t.Fatalf("GetTaskList failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestRetryTransport_Cancelled(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(failingHandler(10, http.StatusServiceUnavailable, nil, &calls))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := newRetryTransport(http.DefaultTransport, config.RetryConfig{MaxAttempts: 10})
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryTransport_Deadline(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(failingHandler(1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"20"}}, &calls))
+	defer server.Close()
+
+	// The server asks to wait for longer than the request may take
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, delays := newRetryTestClient(t, server.URL, config.RetryConfig{})
+	_, err := client.GetTaskList(ctx, GetTaskListOptions{TaskListID: "list1"})
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the 503 to be returned, got %v", err)
+	}
+	if calls != 1 || len(*delays) != 0 {
+		t.Errorf("expected no retry, got %d calls and delays %v", calls, *delays)
+	}
+}
+
+func TestRetryTransport_DeleteAlreadyDone(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt deletes the task, but its response is lost
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":404,"message":"Task not found."}}`))
+	}))
+	defer server.Close()
+
+	client, _ := newRetryTestClient(t, server.URL, config.RetryConfig{})
+	if err := client.DeleteTask(context.Background(), DeleteTaskOptions{TaskListID: "list1", TaskID: "task1"}); err != nil {
+		t.Errorf("expected the retried delete to succeed, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+
+	// A delete that was not retried still reports a missing task
+	atomic.StoreInt32(&calls, 1)
+	if err := client.DeleteTask(context.Background(), DeleteTaskOptions{TaskListID: "list1", TaskID: "task1"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d, ok := retryAfter("3"); !ok || d != 3*time.Second {
+		t.Errorf("expected 3s, got %v (%v)", d, ok)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d, ok := retryAfter(date); !ok || d <= 0 || d > time.Minute {
+		t.Errorf("expected a delay of up to a minute, got %v (%v)", d, ok)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Error("expected invalid Retry-After to be ignored")
+	}
+}
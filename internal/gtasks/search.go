@@ -0,0 +1,79 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// SearchTasksOptions holds the parameters for searching tasks across all
+// task lists.
+type SearchTasksOptions struct {
+	ShowCompleted bool
+	ShowHidden    bool
+	SortBy        string
+	Filter        FilterOptions
+	// Workers is the number of task lists fetched concurrently. Defaults to
+	// DefaultWorkers.
+	Workers int
+}
+
+// TaskListTasks holds the tasks of a single task list.
+type TaskListTasks struct {
+	TaskList *tasks.TaskList
+	Tasks    []*tasks.Task
+}
+
+// SearchTasks fetches the tasks of all task lists concurrently and returns
+// the tasks that match the filter, grouped by task list in the order of the
+// task lists. Task lists without matching tasks are omitted.
+func SearchTasks(ctx context.Context, client Client, opts SearchTasksOptions) ([]TaskListTasks, error) {
+	// Compile the filter once to report errors before fetching anything.
+	if _, err := NewTaskFilter(opts.Filter); err != nil {
+		return nil, err
+	}
+
+	lists, err := client.ListTaskLists(ctx, ListTaskListsOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TaskListTasks, len(lists.Items))
+	errs := make([]error, len(lists.Items))
+	runPool(ctx, len(lists.Items), opts.Workers, func(i int) {
+		list := lists.Items[i]
+		listOpts := ListTasksOptions{
+			TaskListID:    list.Id,
+			ShowCompleted: opts.ShowCompleted,
+			ShowHidden:    opts.ShowHidden,
+			SortBy:        opts.SortBy,
+		}
+		listTasks, err := client.ListTasks(ctx, listOpts)
+		if err != nil {
+			errs[i] = fmt.Errorf("task list %s (%s): %w", list.Title, list.Id, err)
+			return
+		}
+		filtered, err := FilterTasks(listTasks.Items, opts.Filter)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		results[i] = TaskListTasks{TaskList: list, Tasks: filtered}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var matched []TaskListTasks
+	for _, result := range results {
+		if len(result.Tasks) > 0 {
+			matched = append(matched, result)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,53 @@
+package gtasks
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchTasks(t *testing.T) {
+	ctx := context.Background()
+	client := newTestOfflineClient(t)
+
+	titles := map[string][]string{
+		"Work":     {"Deploy api", "Write report"},
+		"Personal": {"Deploy garden shed"},
+		"Empty":    nil,
+	}
+	for _, listTitle := range []string{"Work", "Personal", "Empty"} {
+		list, err := client.CreateTaskList(ctx, CreateTaskListOptions{Title: listTitle})
+		if err != nil {
+			t.Fatalf("CreateTaskList failed: %v", err)
+		}
+		for _, title := range titles[listTitle] {
+			if _, err := client.CreateTask(ctx, CreateTaskOptions{TaskListID: list.Id, Title: title}); err != nil {
+				t.Fatalf("CreateTask failed: %v", err)
+			}
+		}
+	}
+
+	results, err := SearchTasks(ctx, client, SearchTasksOptions{
+		SortBy: "alphabetical",
+		Filter: FilterOptions{TitleContains: "deploy"},
+	})
+	if err != nil {
+		t.Fatalf("SearchTasks failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected matches in 2 task lists, got %d", len(results))
+	}
+
+	found := map[string]string{}
+	for _, result := range results {
+		for _, task := range result.Tasks {
+			found[task.Title] = result.TaskList.Title
+		}
+	}
+	if found["Deploy api"] != "Work" || found["Deploy garden shed"] != "Personal" || len(found) != 2 {
+		t.Errorf("unexpected matches: %v", found)
+	}
+
+	if _, err := SearchTasks(ctx, client, SearchTasksOptions{Filter: FilterOptions{Query: "titel:x"}}); err == nil {
+		t.Error("expected an error for an invalid query")
+	}
+}
@@ -0,0 +1,442 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+// ErrUnresolvedConflicts is returned by Sync when some journal entries could
+// not be replayed because of conflicts that the policy does not resolve.
+var ErrUnresolvedConflicts = NewError(ErrConflict, "unresolved sync conflicts")
+
+// SyncOptions holds the parameters for a sync.
+type SyncOptions struct {
+	// Policy decides how conflicting fields are resolved.
+	Policy ConflictPolicy
+}
+
+// SyncResult summarizes the changes made during a sync.
+type SyncResult struct {
+	// Created is the number of tasks and task lists created on the server.
+	Created int
+	// Updated is the number of tasks and task lists updated on the server.
+	Updated int
+	// Deleted is the number of tasks and task lists deleted on the server.
+	Deleted int
+	// Resolved is the number of conflicts resolved by the policy.
+	Resolved int
+	// Conflicts are the conflicts that the policy did not resolve. The
+	// corresponding journal entries are kept for the next sync.
+	Conflicts []Conflict
+	// PulledTaskLists is the number of task lists pulled into the offline store.
+	PulledTaskLists int
+	// PulledTasks is the number of tasks pulled into the offline store.
+	PulledTasks int
+}
+
+// OpenOfflineStore opens the offline store at its default location.
+func OpenOfflineStore() (*store.InMemoryStore, error) {
+	path, err := store.GetOfflineStorePath()
+	if err != nil {
+		return nil, err
+	}
+	return store.NewInMemoryStore(path)
+}
+
+// Sync replays the journal of the offline store against the remote client
+// and then replaces the content of the store with the state of the server.
+// Local IDs of pushed items are mapped to the IDs assigned by the server so
+// that they keep working in offline mode.
+//
+// If the server changed an item after the offline mutation was made, the
+// changed fields are merged. Fields changed on both sides are resolved using
+// the policy of the options. If conflicts remain, the pull is skipped, the
+// conflicting entries stay in the journal and ErrUnresolvedConflicts is
+// returned.
+func Sync(ctx context.Context, remote Client, local *store.InMemoryStore, opts SyncOptions) (*SyncResult, error) {
+	r := &replayer{
+		ctx:     ctx,
+		remote:  remote,
+		local:   local,
+		policy:  opts.Policy,
+		result:  &SyncResult{},
+		etags:   make(map[string]string),
+		blocked: make(map[string]bool),
+	}
+	if err := r.replay(); err != nil {
+		return r.result, err
+	}
+	if len(r.result.Conflicts) > 0 {
+		return r.result, fmt.Errorf("%w: %d conflict(s) must be resolved before pulling", ErrUnresolvedConflicts, len(r.result.Conflicts))
+	}
+	if err := pull(ctx, remote, local, r.result); err != nil {
+		return r.result, err
+	}
+	return r.result, nil
+}
+
+// replayer replays the journal of the offline store against the server. It
+// only lives for the duration of a single Sync call.
+type replayer struct {
+	ctx    context.Context
+	remote Client
+	local  *store.InMemoryStore
+	policy ConflictPolicy
+	result *SyncResult
+	// etags holds the etag of items after they have been pushed, so that
+	// later entries for the same item are not mistaken for conflicts.
+	etags map[string]string
+	// blocked holds the items with unresolved conflicts. Later entries for
+	// these items are kept in the journal to preserve their order.
+	blocked map[string]bool
+}
+
+func (r *replayer) replay() error {
+	for _, entry := range r.local.Journal() {
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		listID := r.local.ResolveID(entry.TaskListID)
+		taskID := r.local.ResolveID(entry.TaskID)
+		if r.blocked[listID] || (taskID != "" && r.blocked[taskID]) {
+			continue
+		}
+
+		var err error
+		switch entry.Op {
+		case store.OpCreateTaskList:
+			err = r.createTaskList(entry)
+		case store.OpUpdateTaskList:
+			err = r.updateTaskList(entry, listID)
+		case store.OpDeleteTaskList:
+			err = r.deleteTaskList(entry, listID)
+		case store.OpCreateTask:
+			err = r.createTask(entry, listID)
+		case store.OpUpdateTask, store.OpCompleteTask, store.OpUncompleteTask:
+			err = r.updateTask(entry, listID, taskID)
+		case store.OpMoveTask:
+			err = r.moveTask(entry, listID, taskID)
+		case store.OpDeleteTask:
+			err = r.deleteTask(entry, listID, taskID)
+		default:
+			err = fmt.Errorf("unknown journal operation %q", entry.Op)
+		}
+		if errors.Is(err, errConflict) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to replay %s of %s: %w", entry.Op, entry.ItemID(), err)
+		}
+		if err := r.local.AcknowledgeJournal(entry.Seq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errConflict signals that an entry was skipped because of a conflict.
+var errConflict = errors.New("conflict")
+
+// conflict records unresolved conflicts and blocks the item.
+func (r *replayer) conflict(itemID string, conflicts []Conflict) error {
+	r.result.Conflicts = append(r.result.Conflicts, conflicts...)
+	r.blocked[itemID] = true
+	return errConflict
+}
+
+// changedOnServer reports whether the server version of an item differs
+// from the version the entry was made against.
+func (r *replayer) changedOnServer(entry store.JournalEntry, serverID, etag string) bool {
+	expected, ok := r.etags[serverID]
+	if !ok {
+		expected = entry.BaseEtag
+	}
+	return expected != "" && expected != etag
+}
+
+func (r *replayer) createTaskList(entry store.JournalEntry) error {
+	created, err := r.remote.CreateTaskList(r.ctx, CreateTaskListOptions{Title: entry.TaskList.Title})
+	if err != nil {
+		return err
+	}
+	r.etags[created.Id] = created.Etag
+	r.result.Created++
+	return r.local.MapID(entry.TaskListID, created.Id)
+}
+
+func (r *replayer) updateTaskList(entry store.JournalEntry, listID string) error {
+	current, err := r.remote.GetTaskList(r.ctx, GetTaskListOptions{TaskListID: listID})
+	if errors.Is(err, ErrNotFound) {
+		return r.conflict(listID, []Conflict{newConflict(entry, FieldDeleted, "modified", "", "deleted")})
+	}
+	if err != nil {
+		return err
+	}
+
+	changed := r.changedOnServer(entry, listID, current.Etag)
+	title, conflicts := r.mergeField(entry, "title", entry.BaseTaskList.Title, entry.TaskList.Title, current.Title, changed)
+	if len(conflicts) > 0 {
+		return r.conflict(listID, conflicts)
+	}
+
+	updated, err := r.remote.UpdateTaskList(r.ctx, UpdateTaskListOptions{TaskListID: listID, Title: title})
+	if err != nil {
+		return err
+	}
+	r.etags[listID] = updated.Etag
+	r.result.Updated++
+	return nil
+}
+
+func (r *replayer) deleteTaskList(entry store.JournalEntry, listID string) error {
+	current, err := r.remote.GetTaskList(r.ctx, GetTaskListOptions{TaskListID: listID})
+	if errors.Is(err, ErrNotFound) {
+		return nil // Already deleted on the server.
+	}
+	if err != nil {
+		return err
+	}
+	if r.changedOnServer(entry, listID, current.Etag) {
+		if !r.keepLocalDeletion(entry) {
+			return r.conflictOrDrop(entry, listID)
+		}
+	}
+	if err := r.remote.DeleteTaskList(r.ctx, DeleteTaskListOptions{TaskListID: listID}); err != nil {
+		return err
+	}
+	r.result.Deleted++
+	return nil
+}
+
+func (r *replayer) createTask(entry store.JournalEntry, listID string) error {
+	created, err := r.remote.CreateTask(r.ctx, CreateTaskOptions{
+		TaskListID: listID,
+		Title:      entry.Task.Title,
+		Notes:      entry.Task.Notes,
+		Due:        entry.Task.Due,
+		Parent:     r.local.ResolveID(entry.Task.Parent),
+		Previous:   r.local.ResolveID(entry.Previous),
+	})
+	if err != nil {
+		return err
+	}
+	r.etags[created.Id] = created.Etag
+	r.result.Created++
+	return r.local.MapID(entry.TaskID, created.Id)
+}
+
+func (r *replayer) updateTask(entry store.JournalEntry, listID, taskID string) error {
+	current, err := r.remote.GetTask(r.ctx, GetTaskOptions{TaskListID: listID, TaskID: taskID})
+	if errors.Is(err, ErrNotFound) {
+		return r.recreateTask(entry, listID, taskID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only the fields changed by this entry are applied on top of the
+	// current server version.
+	changed := r.changedOnServer(entry, taskID, current.Etag)
+	merged := *current
+	var conflicts []Conflict
+	for _, field := range taskFields {
+		value, fieldConflicts := r.mergeField(entry, field.name, field.get(entry.BaseTask), field.get(entry.Task), field.get(current), changed)
+		field.set(&merged, value)
+		conflicts = append(conflicts, fieldConflicts...)
+	}
+	if len(conflicts) > 0 {
+		return r.conflict(taskID, conflicts)
+	}
+
+	if err := r.pushTask(listID, taskID, &merged, current); err != nil {
+		return err
+	}
+	r.result.Updated++
+	return nil
+}
+
+func (r *replayer) moveTask(entry store.JournalEntry, listID, taskID string) error {
+	moved, err := r.remote.MoveTask(r.ctx, MoveTaskOptions{
+		TaskListID: listID,
+		TaskID:     taskID,
+		Parent:     r.local.ResolveID(entry.Task.Parent),
+		Previous:   r.local.ResolveID(entry.Previous),
+	})
+	if errors.Is(err, ErrNotFound) {
+		// The task or its new neighbours were deleted on the server.
+		if r.policy.resolution(FieldDeleted) == KeepRemote {
+			r.result.Resolved++
+			return nil
+		}
+		return r.conflict(taskID, []Conflict{newConflict(entry, FieldDeleted, "moved", "", "deleted")})
+	}
+	if err != nil {
+		return err
+	}
+	r.etags[taskID] = moved.Etag
+	r.result.Updated++
+	return nil
+}
+
+// recreateTask handles a task that was modified offline but deleted on the
+// server. Keeping the local version recreates the task.
+func (r *replayer) recreateTask(entry store.JournalEntry, listID, taskID string) error {
+	switch r.policy.resolution(FieldDeleted) {
+	case KeepRemote:
+		r.result.Resolved++
+		r.blocked[taskID] = true // Drop later entries for the deleted task.
+		return nil
+	case KeepLocal:
+		created, err := r.remote.CreateTask(r.ctx, CreateTaskOptions{
+			TaskListID: listID,
+			Title:      entry.Task.Title,
+			Notes:      entry.Task.Notes,
+			Due:        entry.Task.Due,
+		})
+		if err != nil {
+			return err
+		}
+		if err := r.pushTask(listID, created.Id, entry.Task, created); err != nil {
+			return err
+		}
+		r.result.Resolved++
+		r.result.Created++
+		if err := r.local.MapID(entry.TaskID, created.Id); err != nil {
+			return err
+		}
+		return r.local.MapID(taskID, created.Id)
+	default:
+		return r.conflict(taskID, []Conflict{newConflict(entry, FieldDeleted, "modified", "", "deleted")})
+	}
+}
+
+// pushTask sends the fields and the completion status of a task to the server
+// if they differ from the current server version.
+func (r *replayer) pushTask(listID, taskID string, task, current *tasks.Task) error {
+	etag := current.Etag
+	if task.Title != current.Title || task.Notes != current.Notes || task.Due != current.Due {
+		updated, err := r.remote.UpdateTask(r.ctx, UpdateTaskOptions{
+			TaskListID: listID,
+			TaskID:     taskID,
+			Title:      task.Title,
+			Notes:      task.Notes,
+			Due:        task.Due,
+		})
+		if err != nil {
+			return err
+		}
+		etag = updated.Etag
+	}
+	if task.Status != current.Status {
+		var updated *tasks.Task
+		var err error
+		if task.Status == "completed" {
+			updated, err = r.remote.CompleteTask(r.ctx, CompleteTaskOptions{TaskListID: listID, TaskID: taskID})
+		} else {
+			updated, err = r.remote.UncompleteTask(r.ctx, UncompleteTaskOptions{TaskListID: listID, TaskID: taskID})
+		}
+		if err != nil {
+			return err
+		}
+		etag = updated.Etag
+	}
+	r.etags[taskID] = etag
+	return nil
+}
+
+func (r *replayer) deleteTask(entry store.JournalEntry, listID, taskID string) error {
+	current, err := r.remote.GetTask(r.ctx, GetTaskOptions{TaskListID: listID, TaskID: taskID})
+	if errors.Is(err, ErrNotFound) {
+		return nil // Already deleted on the server.
+	}
+	if err != nil {
+		return err
+	}
+	if r.changedOnServer(entry, taskID, current.Etag) {
+		if !r.keepLocalDeletion(entry) {
+			return r.conflictOrDrop(entry, taskID)
+		}
+	}
+	if err := r.remote.DeleteTask(r.ctx, DeleteTaskOptions{TaskListID: listID, TaskID: taskID}); err != nil {
+		return err
+	}
+	r.result.Deleted++
+	return nil
+}
+
+// keepLocalDeletion reports whether a local deletion of an item that was
+// modified on the server should be pushed anyway.
+func (r *replayer) keepLocalDeletion(entry store.JournalEntry) bool {
+	if r.policy.resolution(FieldDeleted) == KeepLocal {
+		r.result.Resolved++
+		return true
+	}
+	return false
+}
+
+// conflictOrDrop handles a local deletion of an item that was modified on
+// the server and is not pushed. Keeping the remote version drops the entry.
+func (r *replayer) conflictOrDrop(entry store.JournalEntry, itemID string) error {
+	if r.policy.resolution(FieldDeleted) == KeepRemote {
+		r.result.Resolved++
+		return nil
+	}
+	return r.conflict(itemID, []Conflict{newConflict(entry, FieldDeleted, "modified", "deleted", "modified")})
+}
+
+// mergeField performs a three-way merge of a single field. Changes made on
+// only one side are taken as is; changes made on both sides are resolved
+// using the policy. If the server has not changed since the entry was made,
+// local changes always win.
+func (r *replayer) mergeField(entry store.JournalEntry, field, base, local, remote string, changedOnServer bool) (string, []Conflict) {
+	if local == base || local == remote {
+		return remote, nil
+	}
+	if remote == base || !changedOnServer {
+		return local, nil
+	}
+
+	switch r.policy.resolution(field) {
+	case KeepLocal:
+		r.result.Resolved++
+		return local, nil
+	case KeepRemote:
+		r.result.Resolved++
+		return remote, nil
+	case MergeNotes:
+		if field == "notes" {
+			r.result.Resolved++
+			return mergeNotes(base, local, remote), nil
+		}
+	}
+	return remote, []Conflict{newConflict(entry, field, base, local, remote)}
+}
+
+// pull replaces the content of the offline store with the state of the server.
+func pull(ctx context.Context, remote Client, local *store.InMemoryStore, result *SyncResult) error {
+	lists, err := remote.ListTaskLists(ctx, ListTaskListsOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull task lists: %w", err)
+	}
+
+	items := make(map[string][]*tasks.Task)
+	for _, list := range lists.Items {
+		listTasks, err := remote.ListTasks(ctx, ListTasksOptions{
+			TaskListID:    list.Id,
+			ShowCompleted: true,
+			ShowHidden:    true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to pull tasks of task list %s: %w", list.Id, err)
+		}
+		items[list.Id] = listTasks.Items
+		result.PulledTasks += len(listTasks.Items)
+	}
+	result.PulledTaskLists = len(lists.Items)
+
+	return local.Replace(lists.Items, items)
+}
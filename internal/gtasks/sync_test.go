@@ -0,0 +1,230 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yanicksenn/gtasks/internal/store"
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestSync(t *testing.T) {
+	server := newFakeTasksServer(t)
+	remote := server.client(t)
+
+	inbox := server.addTaskList("Inbox")
+	server.addTask(inbox.Id, &tasks.Task{Title: "Remote task"})
+	toDelete := server.addTask(inbox.Id, &tasks.Task{Title: "Delete me"})
+	toComplete := server.addTask(inbox.Id, &tasks.Task{Title: "Complete me"})
+
+	local, err := store.NewInMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewInMemoryStore failed: %v", err)
+	}
+	offline := &offlineClient{store: local}
+
+	// 1. The first sync pulls everything from the server.
+	result, err := Sync(context.Background(), remote, local, SyncOptions{})
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.PulledTaskLists != 1 || result.PulledTasks != 3 {
+		t.Fatalf("expected 1 task list and 3 tasks to be pulled, got %+v", result)
+	}
+	if list, _ := offline.GetTaskList(context.Background(), GetTaskListOptions{TaskListID: "@default"}); list == nil || list.Id != inbox.Id {
+		t.Fatalf("expected @default to resolve to %s, got %v", inbox.Id, list)
+	}
+
+	// 2. Make changes while offline.
+	newList, err := offline.CreateTaskList(context.Background(), CreateTaskListOptions{Title: "Travel"})
+	if err != nil {
+		t.Fatalf("CreateTaskList failed: %v", err)
+	}
+	newTask, err := offline.CreateTask(context.Background(), CreateTaskOptions{TaskListID: newList.Id, Title: "Pack bags"})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if _, err := offline.CompleteTask(context.Background(), CompleteTaskOptions{TaskListID: inbox.Id, TaskID: toComplete.Id}); err != nil {
+		t.Fatalf("CompleteTask failed: %v", err)
+	}
+	if err := offline.DeleteTask(context.Background(), DeleteTaskOptions{TaskListID: inbox.Id, TaskID: toDelete.Id}); err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+
+	// 3. The second sync pushes the changes.
+	result, err = Sync(context.Background(), remote, local, SyncOptions{})
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Created != 2 || result.Updated != 1 || result.Deleted != 1 {
+		t.Errorf("expected 2 created, 1 updated and 1 deleted, got %+v", result)
+	}
+
+	remoteLists, err := remote.ListTaskLists(context.Background(), ListTaskListsOptions{})
+	if err != nil {
+		t.Fatalf("ListTaskLists failed: %v", err)
+	}
+	if len(remoteLists.Items) != 2 {
+		t.Fatalf("expected 2 remote task lists, got %d", len(remoteLists.Items))
+	}
+
+	remoteTasks, err := remote.ListTasks(context.Background(), ListTasksOptions{TaskListID: inbox.Id, ShowCompleted: true})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(remoteTasks.Items) != 2 {
+		t.Errorf("expected 2 remote tasks after deletion, got %d", len(remoteTasks.Items))
+	}
+	for _, task := range remoteTasks.Items {
+		if task.Id == toComplete.Id && task.Status != "completed" {
+			t.Errorf("expected task %s to be completed on the server", task.Id)
+		}
+	}
+
+	// 4. Local IDs keep resolving to the synced items.
+	task, err := offline.GetTask(context.Background(), GetTaskOptions{TaskListID: newList.Id, TaskID: newTask.Id})
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if task == nil || task.Title != "Pack bags" || task.Id == newTask.Id {
+		t.Errorf("expected local ID %s to resolve to the server task, got %v", newTask.Id, task)
+	}
+	if journal := local.Journal(); len(journal) != 0 {
+		t.Errorf("expected an empty journal after sync, got %+v", journal)
+	}
+}
+
+func TestSyncConflicts(t *testing.T) {
+	server := newFakeTasksServer(t)
+	remote := server.client(t)
+
+	inbox := server.addTaskList("Inbox")
+	task := server.addTask(inbox.Id, &tasks.Task{Title: "Deploy", Notes: "step 1"})
+
+	local, err := store.NewInMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewInMemoryStore failed: %v", err)
+	}
+	offline := &offlineClient{store: local}
+	if _, err := Sync(context.Background(), remote, local, SyncOptions{}); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	// 1. Change the same task offline and on the server.
+	if _, err := offline.UpdateTask(context.Background(), UpdateTaskOptions{TaskListID: inbox.Id, TaskID: task.Id, Title: "Deploy v2", Notes: "step 1\nstep 2"}); err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	if _, err := offline.CompleteTask(context.Background(), CompleteTaskOptions{TaskListID: inbox.Id, TaskID: task.Id}); err != nil {
+		t.Fatalf("CompleteTask failed: %v", err)
+	}
+	journal := local.Journal()
+	if len(journal) != 2 || journal[0].Op != store.OpUpdateTask || journal[1].Op != store.OpCompleteTask {
+		t.Fatalf("expected an update and a complete entry in the journal, got %+v", journal)
+	}
+	if journal[0].BaseEtag != task.Etag {
+		t.Errorf("expected base etag %s, got %s", task.Etag, journal[0].BaseEtag)
+	}
+	server.modifyTask(inbox.Id, task.Id, func(t *tasks.Task) {
+		t.Title = "Deploy v3"
+		t.Notes = "step 1\nstep 0"
+		t.Due = "2025-12-31T00:00:00.000Z"
+	})
+
+	// 2. Without a policy, the conflicting entries stay in the journal.
+	result, err := Sync(context.Background(), remote, local, SyncOptions{})
+	if !errors.Is(err, ErrUnresolvedConflicts) {
+		t.Fatalf("expected ErrUnresolvedConflicts, got %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, c := range result.Conflicts {
+		fields[c.Field] = true
+	}
+	if len(fields) != 2 || !fields["title"] || !fields["notes"] {
+		t.Errorf("expected conflicts on title and notes, got %+v", result.Conflicts)
+	}
+	if len(local.Journal()) != 2 {
+		t.Errorf("expected the journal to be kept, got %d entries", len(local.Journal()))
+	}
+
+	// 3. With a policy, the fields are resolved and merged.
+	policy := ConflictPolicy{Fields: map[string]Resolution{"title": KeepLocal, "notes": MergeNotes}}
+	result, err = Sync(context.Background(), remote, local, SyncOptions{Policy: policy})
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Resolved != 2 {
+		t.Errorf("expected 2 resolved conflicts, got %d", result.Resolved)
+	}
+
+	got, err := remote.GetTask(context.Background(), GetTaskOptions{TaskListID: inbox.Id, TaskID: task.Id})
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if got.Title != "Deploy v2" {
+		t.Errorf("expected local title to win, got %q", got.Title)
+	}
+	if got.Notes != "step 1\nstep 0\nstep 2" {
+		t.Errorf("expected merged notes, got %q", got.Notes)
+	}
+	if got.Due != "2025-12-31T00:00:00.000Z" {
+		t.Errorf("expected remote due date to be kept, got %q", got.Due)
+	}
+	if got.Status != "completed" {
+		t.Errorf("expected task to be completed, got %q", got.Status)
+	}
+	if len(local.Journal()) != 0 {
+		t.Errorf("expected an empty journal, got %d entries", len(local.Journal()))
+	}
+}
+
+func TestSyncSubtasks(t *testing.T) {
+	server := newFakeTasksServer(t)
+	remote := server.client(t)
+	inbox := server.addTaskList("Inbox")
+
+	local, err := store.NewInMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewInMemoryStore failed: %v", err)
+	}
+	offline := &offlineClient{store: local}
+	if _, err := Sync(context.Background(), remote, local, SyncOptions{}); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	parent, err := offline.CreateTask(context.Background(), CreateTaskOptions{TaskListID: inbox.Id, Title: "Release"})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	child, err := offline.CreateTask(context.Background(), CreateTaskOptions{TaskListID: inbox.Id, Title: "Tag", Parent: parent.Id})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	other, err := offline.CreateTask(context.Background(), CreateTaskOptions{TaskListID: inbox.Id, Title: "Announce"})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if _, err := offline.MoveTask(context.Background(), MoveTaskOptions{TaskListID: inbox.Id, TaskID: other.Id, Parent: parent.Id, Previous: child.Id}); err != nil {
+		t.Fatalf("MoveTask failed: %v", err)
+	}
+
+	if _, err := Sync(context.Background(), remote, local, SyncOptions{}); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	remoteTasks, err := remote.ListTasks(context.Background(), ListTasksOptions{TaskListID: inbox.Id})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	parents := make(map[string]string)
+	var parentID string
+	for _, task := range remoteTasks.Items {
+		parents[task.Title] = task.Parent
+		if task.Title == "Release" {
+			parentID = task.Id
+		}
+	}
+	if parents["Tag"] != parentID || parents["Announce"] != parentID {
+		t.Errorf("expected both subtasks to have parent %s, got %v", parentID, parents)
+	}
+}
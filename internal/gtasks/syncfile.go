@@ -0,0 +1,192 @@
+package gtasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/export"
+	"google.golang.org/api/tasks/v1"
+)
+
+// SyncFileOptions holds the parameters for keeping a file and a task list
+// in step.
+type SyncFileOptions struct {
+	TaskListID string
+	// Items are the items read from the file.
+	Items []export.Item
+	// State is the state saved by the previous sync, or nil if the file
+	// was never synced.
+	State *SyncFileState
+	// DryRun reports what would be done without changing anything.
+	DryRun bool
+}
+
+// SyncFileResult summarizes a sync with a file.
+type SyncFileResult struct {
+	ImportResult
+	// Deleted counts the tasks deleted because their line was removed.
+	Deleted int
+	// Dropped counts the lines dropped because their task was deleted.
+	Dropped int
+	// Items are the tasks of the list to write back to the file. They are
+	// nil on a dry run.
+	Items []export.Item
+	// State is the state to save for the next sync. It is nil on a dry
+	// run.
+	State *SyncFileState
+}
+
+// SyncFileState is what a sync remembers about a file for the next sync.
+type SyncFileState struct {
+	// Synced is the latest modification time of the tasks written to the
+	// file. It is taken from the server, so that the local clock does not
+	// matter.
+	Synced time.Time `json:"synced"`
+	// TaskIDs are the IDs of the tasks written to the file.
+	TaskIDs []string `json:"task_ids"`
+}
+
+// SyncFile keeps a file and a task list in step. The state saved by the
+// previous sync tells which side changed:
+//
+//   - Lines without an ID are new and are created.
+//   - Lines whose task changed after the previous sync are replaced by the
+//     task, otherwise the line is imported.
+//   - Lines whose task no longer exists are dropped.
+//   - Tasks that were written to the file by the previous sync but no
+//     longer have a line were removed from the file and are deleted,
+//     unless they changed after the previous sync. Tasks created since are
+//     kept and added to the file.
+//
+// Nothing is deleted on the first sync of a file or if the file has no IDs
+// at all, since such a file was not written by a sync. The returned items
+// reflect the list after the sync, each with the list as its task list.
+func SyncFile(ctx context.Context, client Client, opts SyncFileOptions) (*SyncFileResult, error) {
+	list, err := client.GetTaskList(ctx, GetTaskListOptions{TaskListID: opts.TaskListID})
+	if err != nil {
+		return nil, err
+	}
+	listOpts := ListTasksOptions{TaskListID: opts.TaskListID, ShowCompleted: true, ShowHidden: true, SortBy: "position"}
+	existing, err := client.ListTasks(ctx, listOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]*tasks.Task{}
+	byUID := map[string]*tasks.Task{}
+	for _, task := range existing.Items {
+		byID[task.Id] = task
+		if _, uid := export.SplitUID(task.Notes); uid != "" {
+			byUID[uid] = task
+		}
+	}
+
+	var synced time.Time
+	written := map[string]bool{}
+	if opts.State != nil {
+		synced = opts.State.Synced
+		for _, id := range opts.State.TaskIDs {
+			written[id] = true
+		}
+	}
+
+	// Decide which lines to import.
+	result := &SyncFileResult{}
+	hasIDs := false
+	referenced := map[string]bool{}
+	var items []export.Item
+	for _, item := range opts.Items {
+		item.Attributes = listAttributes(item, list.Title)
+		item.TaskList = ""
+		if item.UID == "" {
+			items = append(items, item)
+			continue
+		}
+		hasIDs = true
+		match := findTask(byID, byUID, item.UID)
+		if match == nil {
+			result.Dropped++
+			continue
+		}
+		referenced[match.Id] = true
+		if !changedSince(match, synced) {
+			items = append(items, item)
+		}
+	}
+
+	importResult, err := ImportTasks(ctx, client, ImportOptions{TaskListID: opts.TaskListID, DryRun: opts.DryRun}, items)
+	if importResult != nil {
+		result.ImportResult = *importResult
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// Delete the tasks whose line was removed. A task is kept while one of
+	// its subtasks is, because deleting it would delete the subtask too.
+	if opts.State != nil && hasIDs {
+		for _, task := range existing.Items {
+			if referenced[task.Id] {
+				for parent := task.Parent; parent != "" && byID[parent] != nil; parent = byID[parent].Parent {
+					referenced[parent] = true
+				}
+			}
+		}
+		for _, task := range existing.Items {
+			if !written[task.Id] || referenced[task.Id] || task.Updated == "" || changedSince(task, synced) {
+				continue
+			}
+			result.Deleted++
+			if opts.DryRun {
+				continue
+			}
+			err := client.DeleteTask(ctx, DeleteTaskOptions{TaskListID: opts.TaskListID, TaskID: task.Id})
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return result, fmt.Errorf("error deleting %q: %w", task.Title, err)
+			}
+		}
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	// Read the list back for the file.
+	after, err := client.ListTasks(ctx, listOpts)
+	if err != nil {
+		return result, err
+	}
+	result.Items = export.ItemsFromTasks(after.Items)
+	for i := range result.Items {
+		result.Items[i].TaskList = list.Title
+	}
+	result.State = &SyncFileState{Synced: synced, TaskIDs: []string{}}
+	for _, task := range after.Items {
+		result.State.TaskIDs = append(result.State.TaskIDs, task.Id)
+		if updated, err := time.Parse(time.RFC3339, task.Updated); err == nil && updated.After(result.State.Synced) {
+			result.State.Synced = updated
+		}
+	}
+	return result, nil
+}
+
+// changedSince reports whether the task was modified after t. Tasks without
+// a known modification time are not.
+func changedSince(task *tasks.Task, t time.Time) bool {
+	if t.IsZero() || task.Updated == "" {
+		return false
+	}
+	updated, err := time.Parse(time.RFC3339, task.Updated)
+	return err == nil && updated.After(t)
+}
+
+// listAttributes returns the attributes of an item, keeping a project that
+// names another list as an attribute.
+func listAttributes(item export.Item, title string) []string {
+	if item.TaskList == "" || findTaskListID([]*tasks.TaskList{{Id: "-", Title: title}}, item.TaskList) != "" {
+		return item.Attributes
+	}
+	return append([]string{"+" + strings.ReplaceAll(item.TaskList, " ", "_")}, item.Attributes...)
+}
@@ -0,0 +1,125 @@
+package gtasks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yanicksenn/gtasks/internal/export"
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestSyncFile(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTasksServer(t)
+	client := f.client(t)
+	list := f.addTaskList("Work")
+	edited := f.addTask(list.Id, &tasks.Task{Title: "Edited in the file"})
+	removed := f.addTask(list.Id, &tasks.Task{Title: "Removed from the file"})
+	changed := f.addTask(list.Id, &tasks.Task{Title: "Changed on the server"})
+
+	// The file was synced after the tasks were created, and one task
+	// changed since.
+	time.Sleep(2 * time.Millisecond)
+	state := &SyncFileState{Synced: time.Now(), TaskIDs: []string{edited.Id, removed.Id, changed.Id}}
+	time.Sleep(2 * time.Millisecond)
+	f.modifyTask(list.Id, changed.Id, func(task *tasks.Task) { task.Title = "Renamed on the server" })
+
+	items := []export.Item{
+		{UID: edited.Id, TaskList: "Work", Attributes: []string{}, Task: &tasks.Task{Title: "Edited", Status: "needsAction"}},
+		{UID: changed.Id, TaskList: "Work", Attributes: []string{}, Task: &tasks.Task{Title: "Renamed in the file", Status: "needsAction"}},
+		{UID: "gone", TaskList: "Work", Attributes: []string{}, Task: &tasks.Task{Title: "Deleted on the server", Status: "needsAction"}},
+		{TaskList: "Other", Attributes: []string{"@home"}, Task: &tasks.Task{Title: "New", Status: "needsAction"}},
+	}
+	result, err := SyncFile(ctx, client, SyncFileOptions{TaskListID: list.Id, Items: items, State: state})
+	if err != nil {
+		t.Fatalf("SyncFile failed: %v", err)
+	}
+	if result.Created != 1 || result.Updated != 1 || result.Deleted != 1 || result.Dropped != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	titles := map[string]string{}
+	for _, item := range result.Items {
+		if item.TaskList != "Work" {
+			t.Errorf("expected the items to belong to Work, got %q", item.TaskList)
+		}
+		titles[item.UID] = item.Task.Title
+		if item.Task.Title == "New" && item.Task.Notes != "+Other @home" {
+			t.Errorf("expected the other project to be kept as an attribute, got %q", item.Task.Notes)
+		}
+	}
+	if len(titles) != 3 || titles[edited.Id] != "Edited" || titles[changed.Id] != "Renamed on the server" {
+		t.Errorf("unexpected tasks after the sync: %v", titles)
+	}
+	if _, ok := titles[removed.Id]; ok {
+		t.Errorf("expected %q to be deleted", removed.Title)
+	}
+	if result.State == nil || len(result.State.TaskIDs) != 3 || result.State.Synced.Before(state.Synced) {
+		t.Errorf("unexpected state for the next sync: %+v", result.State)
+	}
+}
+
+func TestSyncFile_FirstSync(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTasksServer(t)
+	client := f.client(t)
+	list := f.addTaskList("Work")
+	f.addTask(list.Id, &tasks.Task{Title: "A"})
+	f.addTask(list.Id, &tasks.Task{Title: "B"})
+
+	// A hand-written file that was never synced deletes nothing.
+	items := []export.Item{{Attributes: []string{}, Task: &tasks.Task{Title: "New", Status: "needsAction"}}}
+	result, err := SyncFile(ctx, client, SyncFileOptions{TaskListID: list.Id, Items: items})
+	if err != nil {
+		t.Fatalf("SyncFile failed: %v", err)
+	}
+	if result.Created != 1 || result.Deleted != 0 || len(result.Items) != 3 {
+		t.Errorf("expected the new task to be added to the existing ones, got %+v", result)
+	}
+
+	// Neither does a file without IDs, even with a state.
+	result, err = SyncFile(ctx, client, SyncFileOptions{TaskListID: list.Id, Items: nil, State: result.State})
+	if err != nil {
+		t.Fatalf("SyncFile failed: %v", err)
+	}
+	if result.Deleted != 0 || len(result.Items) != 3 {
+		t.Errorf("expected no tasks to be deleted, got %+v", result)
+	}
+}
+
+func TestSyncFile_TasksCreatedAfterSync(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTasksServer(t)
+	client := f.client(t)
+	list := f.addTaskList("Work")
+	f.addTask(list.Id, &tasks.Task{Title: "Synced"})
+
+	first, err := SyncFile(ctx, client, SyncFileOptions{TaskListID: list.Id})
+	if err != nil {
+		t.Fatalf("SyncFile failed: %v", err)
+	}
+
+	// A task is created and another one renamed on the server, and the
+	// file is edited later, which has no bearing on either.
+	time.Sleep(2 * time.Millisecond)
+	created := f.addTask(list.Id, &tasks.Task{Title: "Created on the server"})
+	synced := first.Items[0]
+	f.modifyTask(list.Id, synced.UID, func(task *tasks.Task) { task.Title = "Renamed on the server" })
+	synced.Task = &tasks.Task{Title: "Renamed in the file", Status: "needsAction"}
+
+	result, err := SyncFile(ctx, client, SyncFileOptions{TaskListID: list.Id, Items: []export.Item{synced}, State: first.State})
+	if err != nil {
+		t.Fatalf("SyncFile failed: %v", err)
+	}
+	titles := map[string]string{}
+	for _, item := range result.Items {
+		titles[item.UID] = item.Task.Title
+	}
+	if result.Deleted != 0 || titles[created.Id] != "Created on the server" {
+		t.Errorf("expected the new task to be kept, got %+v", result)
+	}
+	if titles[synced.UID] != "Renamed on the server" {
+		t.Errorf("expected the newer server edit to win, got %q", titles[synced.UID])
+	}
+}
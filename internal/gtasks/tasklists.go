@@ -1,6 +1,7 @@
 package gtasks
 
 import (
+	"context"
 	"sort"
 	"strings"
 
@@ -16,6 +17,7 @@ type ListTaskListsOptions struct {
 type GetTaskListOptions struct {
 	TaskListID string
 }
+
 // CreateTaskListOptions holds the parameters for creating a new task list.
 type CreateTaskListOptions struct {
 	Title string
@@ -32,10 +34,19 @@ type DeleteTaskListOptions struct {
 	TaskListID string
 }
 
-func (c *onlineClient) ListTaskLists(opts ListTaskListsOptions) (*tasks.TaskLists, error) {
-	lists, err := c.service.Tasklists.List().Do()
-	if err != nil {
-		return nil, err
+func (c *onlineClient) ListTaskLists(ctx context.Context, opts ListTaskListsOptions) (*tasks.TaskLists, error) {
+	lists := &tasks.TaskLists{}
+	pageToken := ""
+	for {
+		page, err := c.service.Tasklists.List().PageToken(pageToken).Context(ctx).Do()
+		if err != nil {
+			return nil, classify(err)
+		}
+		lists.Items = append(lists.Items, page.Items...)
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
 	}
 
 	switch opts.SortBy {
@@ -54,24 +65,24 @@ func (c *onlineClient) ListTaskLists(opts ListTaskListsOptions) (*tasks.TaskList
 	return lists, nil
 }
 
-func (c *onlineClient) GetTaskList(opts GetTaskListOptions) (*tasks.TaskList, error) {
-	return c.service.Tasklists.Get(opts.TaskListID).Do()
+func (c *onlineClient) GetTaskList(ctx context.Context, opts GetTaskListOptions) (*tasks.TaskList, error) {
+	return classified(c.service.Tasklists.Get(opts.TaskListID).Context(ctx).Do())
 }
 
-func (c *onlineClient) CreateTaskList(opts CreateTaskListOptions) (*tasks.TaskList, error) {
+func (c *onlineClient) CreateTaskList(ctx context.Context, opts CreateTaskListOptions) (*tasks.TaskList, error) {
 	list := &tasks.TaskList{
 		Title: opts.Title,
 	}
-	return c.service.Tasklists.Insert(list).Do()
+	return classified(c.service.Tasklists.Insert(list).Context(ctx).Do())
 }
 
-func (c *onlineClient) UpdateTaskList(opts UpdateTaskListOptions) (*tasks.TaskList, error) {
+func (c *onlineClient) UpdateTaskList(ctx context.Context, opts UpdateTaskListOptions) (*tasks.TaskList, error) {
 	list := &tasks.TaskList{
 		Title: opts.Title,
 	}
-	return c.service.Tasklists.Update(opts.TaskListID, list).Do()
+	return classified(c.service.Tasklists.Update(opts.TaskListID, list).Context(ctx).Do())
 }
 
-func (c *onlineClient) DeleteTaskList(opts DeleteTaskListOptions) error {
-	return c.service.Tasklists.Delete(opts.TaskListID).Do()
+func (c *onlineClient) DeleteTaskList(ctx context.Context, opts DeleteTaskListOptions) error {
+	return classify(c.service.Tasklists.Delete(opts.TaskListID).Context(ctx).Do())
 }
@@ -1,6 +1,7 @@
 package gtasks
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -20,7 +21,7 @@ func TestTaskListsLifecycle(t *testing.T) {
 
 	// 1. Initial list should contain the default list
 	output := CaptureOutput(t, func() {
-		lists, err := client.ListTaskLists(ListTaskListsOptions{})
+		lists, err := client.ListTaskLists(context.Background(), ListTaskListsOptions{})
 		if err != nil {
 			t.Fatalf("ListTaskLists failed: %v", err)
 		}
@@ -34,7 +35,7 @@ func TestTaskListsLifecycle(t *testing.T) {
 	var listID string
 	CaptureOutput(t, func() {
 		opts := CreateTaskListOptions{Title: "Groceries"}
-		list, err := client.CreateTaskList(opts)
+		list, err := client.CreateTaskList(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("CreateTaskList failed: %v", err)
 		}
@@ -43,7 +44,7 @@ func TestTaskListsLifecycle(t *testing.T) {
 
 	// 3. List should now contain the new list
 	output = CaptureOutput(t, func() {
-		lists, err := client.ListTaskLists(ListTaskListsOptions{})
+		lists, err := client.ListTaskLists(context.Background(), ListTaskListsOptions{})
 		if err != nil {
 			t.Fatalf("ListTaskLists failed: %v", err)
 		}
@@ -56,7 +57,7 @@ func TestTaskListsLifecycle(t *testing.T) {
 	// 4. Get the list by ID
 	output = CaptureOutput(t, func() {
 		opts := GetTaskListOptions{TaskListID: listID}
-		list, err := client.GetTaskList(opts)
+		list, err := client.GetTaskList(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("GetTaskList failed: %v", err)
 		}
@@ -69,7 +70,7 @@ func TestTaskListsLifecycle(t *testing.T) {
 	// 5. Update the list
 	CaptureOutput(t, func() {
 		opts := UpdateTaskListOptions{TaskListID: listID, Title: "Updated Groceries"}
-		_, err := client.UpdateTaskList(opts)
+		_, err := client.UpdateTaskList(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("UpdateTaskList failed: %v", err)
 		}
@@ -78,7 +79,7 @@ func TestTaskListsLifecycle(t *testing.T) {
 	// 6. Delete the list
 	CaptureOutput(t, func() {
 		opts := DeleteTaskListOptions{TaskListID: listID}
-		err := client.DeleteTaskList(opts)
+		err := client.DeleteTaskList(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("DeleteTaskList failed: %v", err)
 		}
@@ -86,7 +87,7 @@ func TestTaskListsLifecycle(t *testing.T) {
 
 	// 7. Final list should not contain the deleted list
 	output = CaptureOutput(t, func() {
-		lists, err := client.ListTaskLists(ListTaskListsOptions{})
+		lists, err := client.ListTaskLists(context.Background(), ListTaskListsOptions{})
 		if err != nil {
 			t.Fatalf("ListTaskLists failed: %v", err)
 		}
@@ -104,7 +105,7 @@ func TestTaskListPrint(t *testing.T) {
 	var listID string
 	CaptureOutput(t, func() {
 		opts := CreateTaskListOptions{Title: "Test List"}
-		list, err := client.CreateTaskList(opts)
+		list, err := client.CreateTaskList(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("CreateTaskList failed: %v", err)
 		}
@@ -113,7 +114,7 @@ func TestTaskListPrint(t *testing.T) {
 
 	// 2. Print the title
 	output := CaptureOutput(t, func() {
-		list, err := client.GetTaskList(GetTaskListOptions{TaskListID: listID})
+		list, err := client.GetTaskList(context.Background(), GetTaskListOptions{TaskListID: listID})
 		if err != nil {
 			t.Fatalf("GetTaskList failed: %v", err)
 		}
@@ -1,6 +1,8 @@
 package gtasks
 
 import (
+	"context"
+	"iter"
 	"sort"
 	"strings"
 	"time"
@@ -14,6 +16,12 @@ type ListTasksOptions struct {
 	ShowCompleted bool
 	ShowHidden    bool
 	SortBy        string
+	// MaxResults caps the number of returned tasks. Zero means no limit.
+	// With SortBy, the tasks are sorted before they are capped.
+	MaxResults int64
+	// PageSize is the number of tasks requested per API call. Zero uses the
+	// API default.
+	PageSize int64
 }
 
 // GetTaskOptions holds the parameters for retrieving a single task.
@@ -28,9 +36,16 @@ type CreateTaskOptions struct {
 	Title      string
 	Notes      string
 	Due        string
+	// Parent is the ID of the parent task. If empty, the task is created at
+	// the top level.
+	Parent string
+	// Previous is the ID of the sibling after which the task is created. If
+	// empty, the task is created first among its siblings.
+	Previous string
 }
 
 // UpdateTaskOptions holds the parameters for updating a task.
+// Empty fields are left unchanged.
 type UpdateTaskOptions struct {
 	TaskListID string
 	TaskID     string
@@ -39,6 +54,22 @@ type UpdateTaskOptions struct {
 	Due        string
 }
 
+// ReplaceTaskOptions holds the parameters for replacing the fields of a
+// task. Unlike with UpdateTaskOptions, empty fields are cleared.
+type ReplaceTaskOptions struct {
+	TaskListID string
+	TaskID     string
+	Title      string
+	Notes      string
+	Due        string
+	// Status is "needsAction" or "completed".
+	Status string
+	// Completed is the completion time of a completed task. If empty, the
+	// task is completed now.
+	Completed string
+	Hidden    bool
+}
+
 // CompleteTaskOptions holds the parameters for completing a task.
 type CompleteTaskOptions struct {
 	TaskListID string
@@ -51,87 +82,245 @@ type UncompleteTaskOptions struct {
 	TaskID     string
 }
 
+// MoveTaskOptions holds the parameters for moving a task within its task list.
+type MoveTaskOptions struct {
+	TaskListID string
+	TaskID     string
+	// Parent is the ID of the new parent task. If empty, the task is moved
+	// to the top level.
+	Parent string
+	// Previous is the ID of the new previous sibling. If empty, the task is
+	// moved to the first position among its siblings.
+	Previous string
+}
+
 // DeleteTaskOptions holds the parameters for deleting a task.
 type DeleteTaskOptions struct {
 	TaskListID string
 	TaskID     string
 }
 
-func (c *onlineClient) ListTasks(opts ListTasksOptions) (*tasks.Tasks, error) {
-	tasks, err := c.service.Tasks.List(opts.TaskListID).ShowCompleted(opts.ShowCompleted).ShowHidden(opts.ShowHidden).Do()
+func (c *onlineClient) ListTasks(ctx context.Context, opts ListTasksOptions) (*tasks.Tasks, error) {
+	// Sorting needs all tasks, so only stop early if there is no sort.
+	maxResults := opts.MaxResults
+	if opts.SortBy != "" {
+		opts.MaxResults = 0
+	}
+
+	result := &tasks.Tasks{}
+	for task, err := range c.AllTasks(ctx, opts) {
+		if err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, task)
+	}
+
+	sortTasks(result.Items, opts.SortBy)
+	if maxResults > 0 && int64(len(result.Items)) > maxResults {
+		result.Items = result.Items[:maxResults]
+	}
+
+	return result, nil
+}
+
+// AllTasks returns an iterator over the tasks of a task list. It follows the
+// page tokens of the API and only holds one page in memory at a time. The
+// tasks are yielded in API order; SortBy is ignored.
+func (c *onlineClient) AllTasks(ctx context.Context, opts ListTasksOptions) iter.Seq2[*tasks.Task, error] {
+	return func(yield func(*tasks.Task, error) bool) {
+		call := c.service.Tasks.List(opts.TaskListID).ShowCompleted(opts.ShowCompleted).ShowHidden(opts.ShowHidden)
+		if pageSize := pageSize(opts.PageSize, opts.MaxResults); pageSize > 0 {
+			call = call.MaxResults(pageSize)
+		}
+
+		var count int64
+		pageToken := ""
+		for {
+			page, err := call.PageToken(pageToken).Context(ctx).Do()
+			if err != nil {
+				yield(nil, classify(err))
+				return
+			}
+			for _, task := range page.Items {
+				if opts.MaxResults > 0 && count >= opts.MaxResults {
+					return
+				}
+				if !yield(task, nil) {
+					return
+				}
+				count++
+			}
+			if page.NextPageToken == "" {
+				return
+			}
+			pageToken = page.NextPageToken
+		}
+	}
+}
+
+// pageSize returns the number of items to request per page. Requesting more
+// than the remaining maximum is pointless.
+func pageSize(pageSize, maxResults int64) int64 {
+	if maxResults > 0 && (pageSize == 0 || maxResults < pageSize) {
+		return maxResults
+	}
+	return pageSize
+}
+
+// ListFilteredTasks streams the tasks of a task list through the filter and
+// returns the matching tasks, sorted by the SortBy option. Only matching
+// tasks are held in memory. MaxResults caps the number of matching tasks
+// after sorting, so all matching tasks are read when a sort is requested.
+func ListFilteredTasks(ctx context.Context, client Client, listOpts ListTasksOptions, filterOpts FilterOptions) (*tasks.Tasks, error) {
+	filter, err := NewTaskFilter(filterOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	switch opts.SortBy {
+	maxResults := listOpts.MaxResults
+	listOpts.MaxResults = 0
+
+	result := &tasks.Tasks{}
+	for task, err := range client.AllTasks(ctx, listOpts) {
+		if err != nil {
+			return nil, err
+		}
+		if !filter.Match(task) {
+			continue
+		}
+		result.Items = append(result.Items, task)
+		if maxResults > 0 && listOpts.SortBy == "" && int64(len(result.Items)) >= maxResults {
+			break
+		}
+	}
+
+	sortTasks(result.Items, listOpts.SortBy)
+	if maxResults > 0 && int64(len(result.Items)) > maxResults {
+		result.Items = result.Items[:maxResults]
+	}
+
+	return result, nil
+}
+
+// sortTasks sorts tasks in place by the given criteria.
+func sortTasks(items []*tasks.Task, sortBy string) {
+	switch sortBy {
 	case "alphabetical":
-		sort.Slice(tasks.Items, func(i, j int) bool {
-			return strings.ToLower(tasks.Items[i].Title) < strings.ToLower(tasks.Items[j].Title)
+		sort.Slice(items, func(i, j int) bool {
+			return strings.ToLower(items[i].Title) < strings.ToLower(items[j].Title)
 		})
 	case "last-modified":
-		sort.Slice(tasks.Items, func(i, j int) bool {
-			return tasks.Items[i].Updated > tasks.Items[j].Updated
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].Updated > items[j].Updated
 		})
 	case "due-date":
-		sort.Slice(tasks.Items, func(i, j int) bool {
-			if tasks.Items[i].Due == "" {
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].Due == "" {
 				return false
 			}
-			if tasks.Items[j].Due == "" {
+			if items[j].Due == "" {
 				return true
 			}
-			dueI, _ := time.Parse(time.RFC3339, tasks.Items[i].Due)
-			dueJ, _ := time.Parse(time.RFC3339, tasks.Items[j].Due)
+			dueI, _ := time.Parse(time.RFC3339, items[i].Due)
+			dueJ, _ := time.Parse(time.RFC3339, items[j].Due)
 			if dueI.Equal(dueJ) {
-				return tasks.Items[i].Title < tasks.Items[j].Title
+				return items[i].Title < items[j].Title
 			}
 			return dueI.Before(dueJ)
 		})
+	case "position":
+		// Positions are only comparable between siblings, which is all that
+		// is needed to render the hierarchy in order.
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Position < items[j].Position
+		})
 	}
-
-	return tasks, nil
 }
 
-func (c *onlineClient) GetTask(opts GetTaskOptions) (*tasks.Task, error) {
-	return c.service.Tasks.Get(opts.TaskListID, opts.TaskID).Do()
+func (c *onlineClient) GetTask(ctx context.Context, opts GetTaskOptions) (*tasks.Task, error) {
+	return classified(c.service.Tasks.Get(opts.TaskListID, opts.TaskID).Context(ctx).Do())
 }
 
-func (c *onlineClient) CreateTask(opts CreateTaskOptions) (*tasks.Task, error) {
+func (c *onlineClient) CreateTask(ctx context.Context, opts CreateTaskOptions) (*tasks.Task, error) {
 	task := &tasks.Task{
 		Title: opts.Title,
 		Notes: opts.Notes,
 		Due:   opts.Due,
 	}
-	return c.service.Tasks.Insert(opts.TaskListID, task).Do()
+	call := c.service.Tasks.Insert(opts.TaskListID, task)
+	if opts.Parent != "" {
+		call = call.Parent(opts.Parent)
+	}
+	if opts.Previous != "" {
+		call = call.Previous(opts.Previous)
+	}
+	return classified(call.Context(ctx).Do())
 }
 
-func (c *onlineClient) UpdateTask(opts UpdateTaskOptions) (*tasks.Task, error) {
+func (c *onlineClient) UpdateTask(ctx context.Context, opts UpdateTaskOptions) (*tasks.Task, error) {
+	// Patch only sends the fields that are set, so the other fields of the
+	// task are kept.
 	task := &tasks.Task{
 		Title: opts.Title,
 		Notes: opts.Notes,
 		Due:   opts.Due,
 	}
-	return c.service.Tasks.Update(opts.TaskListID, opts.TaskID, task).Do()
+	return classified(c.service.Tasks.Patch(opts.TaskListID, opts.TaskID, task).Context(ctx).Do())
+}
+
+func (c *onlineClient) ReplaceTask(ctx context.Context, opts ReplaceTaskOptions) (*tasks.Task, error) {
+	// Empty fields are sent as null, because Patch leaves out empty fields
+	// and the API rejects an empty due date.
+	task := &tasks.Task{
+		Title:           opts.Title,
+		Notes:           opts.Notes,
+		Due:             opts.Due,
+		Status:          opts.Status,
+		Hidden:          opts.Hidden,
+		ForceSendFields: []string{"Hidden"},
+	}
+	for field, value := range map[string]string{"Notes": opts.Notes, "Due": opts.Due} {
+		if value == "" {
+			task.NullFields = append(task.NullFields, field)
+		}
+	}
+	if opts.Completed != "" {
+		task.Completed = &opts.Completed
+	} else if opts.Status != "completed" {
+		task.NullFields = append(task.NullFields, "Completed")
+	}
+	return classified(c.service.Tasks.Patch(opts.TaskListID, opts.TaskID, task).Context(ctx).Do())
 }
 
-func (c *onlineClient) CompleteTask(opts CompleteTaskOptions) (*tasks.Task, error) {
-	task, err := c.service.Tasks.Get(opts.TaskListID, opts.TaskID).Do()
+func (c *onlineClient) CompleteTask(ctx context.Context, opts CompleteTaskOptions) (*tasks.Task, error) {
+	task, err := c.service.Tasks.Get(opts.TaskListID, opts.TaskID).Context(ctx).Do()
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 	task.Status = "completed"
-	return c.service.Tasks.Update(opts.TaskListID, opts.TaskID, task).Do()
+	return classified(c.service.Tasks.Update(opts.TaskListID, opts.TaskID, task).Context(ctx).Do())
 }
 
-func (c *onlineClient) UncompleteTask(opts UncompleteTaskOptions) (*tasks.Task, error) {
-	task, err := c.service.Tasks.Get(opts.TaskListID, opts.TaskID).Do()
+func (c *onlineClient) UncompleteTask(ctx context.Context, opts UncompleteTaskOptions) (*tasks.Task, error) {
+	task, err := c.service.Tasks.Get(opts.TaskListID, opts.TaskID).Context(ctx).Do()
 	if err != nil {
-		return nil, err
+		return nil, classify(err)
 	}
 	task.Status = "needsAction"
-	return c.service.Tasks.Update(opts.TaskListID, opts.TaskID, task).Do()
+	return classified(c.service.Tasks.Update(opts.TaskListID, opts.TaskID, task).Context(ctx).Do())
+}
+
+func (c *onlineClient) MoveTask(ctx context.Context, opts MoveTaskOptions) (*tasks.Task, error) {
+	call := c.service.Tasks.Move(opts.TaskListID, opts.TaskID)
+	if opts.Parent != "" {
+		call = call.Parent(opts.Parent)
+	}
+	if opts.Previous != "" {
+		call = call.Previous(opts.Previous)
+	}
+	return classified(call.Context(ctx).Do())
 }
 
-func (c *onlineClient) DeleteTask(opts DeleteTaskOptions) error {
-	return c.service.Tasks.Delete(opts.TaskListID, opts.TaskID).Do()
+func (c *onlineClient) DeleteTask(ctx context.Context, opts DeleteTaskOptions) error {
+	return classify(c.service.Tasks.Delete(opts.TaskListID, opts.TaskID).Context(ctx).Do())
 }
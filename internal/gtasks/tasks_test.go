@@ -1,10 +1,16 @@
 package gtasks
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/yanicksenn/gtasks/internal/ui"
+	"google.golang.org/api/tasks/v1"
 )
 
 func TestTasksLifecycle(t *testing.T) {
@@ -14,7 +20,7 @@ func TestTasksLifecycle(t *testing.T) {
 	var taskListID string
 	CaptureOutput(t, func() {
 		opts := CreateTaskListOptions{Title: "Shopping List"}
-		list, err := client.CreateTaskList(opts)
+		list, err := client.CreateTaskList(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("CreateTaskList failed: %v", err)
 		}
@@ -24,7 +30,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 2. Initial list of tasks should be empty
 	output := CaptureOutput(t, func() {
 		opts := ListTasksOptions{TaskListID: taskListID}
-		tasks, err := client.ListTasks(opts)
+		tasks, err := client.ListTasks(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("ListTasks failed: %v", err)
 		}
@@ -38,7 +44,7 @@ func TestTasksLifecycle(t *testing.T) {
 	var taskID string
 	CaptureOutput(t, func() {
 		opts := CreateTaskOptions{TaskListID: taskListID, Title: "Buy Milk"}
-		task, err := client.CreateTask(opts)
+		task, err := client.CreateTask(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("CreateTask failed: %v", err)
 		}
@@ -48,7 +54,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 4. List should now contain the new task
 	output = CaptureOutput(t, func() {
 		opts := ListTasksOptions{TaskListID: taskListID}
-		tasks, err := client.ListTasks(opts)
+		tasks, err := client.ListTasks(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("ListTasks failed: %v", err)
 		}
@@ -61,7 +67,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 5. Get the task by ID
 	output = CaptureOutput(t, func() {
 		opts := GetTaskOptions{TaskListID: taskListID, TaskID: taskID}
-		task, err := client.GetTask(opts)
+		task, err := client.GetTask(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("GetTask failed: %v", err)
 		}
@@ -74,7 +80,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 6. Update the task
 	CaptureOutput(t, func() {
 		opts := UpdateTaskOptions{TaskListID: taskListID, TaskID: taskID, Title: "Buy Almond Milk"}
-		_, err := client.UpdateTask(opts)
+		_, err := client.UpdateTask(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("UpdateTask failed: %v", err)
 		}
@@ -83,7 +89,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 7. Complete the task
 	CaptureOutput(t, func() {
 		opts := CompleteTaskOptions{TaskListID: taskListID, TaskID: taskID}
-		_, err := client.CompleteTask(opts)
+		_, err := client.CompleteTask(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("CompleteTask failed: %v", err)
 		}
@@ -92,7 +98,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 8. List should show the task as completed
 	output = CaptureOutput(t, func() {
 		opts := ListTasksOptions{TaskListID: taskListID, ShowCompleted: true}
-		tasks, err := client.ListTasks(opts)
+		tasks, err := client.ListTasks(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("ListTasks failed: %v", err)
 		}
@@ -105,7 +111,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 9. Uncomplete the task
 	CaptureOutput(t, func() {
 		opts := UncompleteTaskOptions{TaskListID: taskListID, TaskID: taskID}
-		_, err := client.UncompleteTask(opts)
+		_, err := client.UncompleteTask(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("UncompleteTask failed: %v", err)
 		}
@@ -114,7 +120,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 10. List should show the task as not completed
 	output = CaptureOutput(t, func() {
 		opts := ListTasksOptions{TaskListID: taskListID, ShowCompleted: true}
-		tasks, err := client.ListTasks(opts)
+		tasks, err := client.ListTasks(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("ListTasks failed: %v", err)
 		}
@@ -127,7 +133,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 11. Delete the task
 	CaptureOutput(t, func() {
 		opts := DeleteTaskOptions{TaskListID: taskListID, TaskID: taskID}
-		err := client.DeleteTask(opts)
+		err := client.DeleteTask(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("DeleteTask failed: %v", err)
 		}
@@ -136,7 +142,7 @@ func TestTasksLifecycle(t *testing.T) {
 	// 12. Final list of tasks should be empty again
 	output = CaptureOutput(t, func() {
 		opts := ListTasksOptions{TaskListID: taskListID}
-		tasks, err := client.ListTasks(opts)
+		tasks, err := client.ListTasks(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("ListTasks failed: %v", err)
 		}
@@ -154,7 +160,7 @@ func TestTaskPrint(t *testing.T) {
 	var taskListID string
 	CaptureOutput(t, func() {
 		opts := CreateTaskListOptions{Title: "Shopping List"}
-		list, err := client.CreateTaskList(opts)
+		list, err := client.CreateTaskList(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("CreateTaskList failed: %v", err)
 		}
@@ -165,7 +171,7 @@ func TestTaskPrint(t *testing.T) {
 	var taskID string
 	CaptureOutput(t, func() {
 		opts := CreateTaskOptions{TaskListID: taskListID, Title: "Buy Milk"}
-		task, err := client.CreateTask(opts)
+		task, err := client.CreateTask(context.Background(), opts)
 		if err != nil {
 			t.Fatalf("CreateTask failed: %v", err)
 		}
@@ -174,7 +180,7 @@ func TestTaskPrint(t *testing.T) {
 
 	// 3. Print the title
 	output := CaptureOutput(t, func() {
-		task, err := client.GetTask(GetTaskOptions{TaskListID: taskListID, TaskID: taskID})
+		task, err := client.GetTask(context.Background(), GetTaskOptions{TaskListID: taskListID, TaskID: taskID})
 		if err != nil {
 			t.Fatalf("GetTask failed: %v", err)
 		}
@@ -186,3 +192,238 @@ func TestTaskPrint(t *testing.T) {
 		t.Errorf("expected output to contain 'Buy Milk', got '%s'", output)
 	}
 }
+
+func TestSubtasks(t *testing.T) {
+	client := newTestOfflineClient(t)
+
+	list, err := client.CreateTaskList(context.Background(), CreateTaskListOptions{Title: "Project"})
+	if err != nil {
+		t.Fatalf("CreateTaskList failed: %v", err)
+	}
+
+	// 1. Create a parent task with two subtasks
+	parent, err := client.CreateTask(context.Background(), CreateTaskOptions{TaskListID: list.Id, Title: "Parent"})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	first, err := client.CreateTask(context.Background(), CreateTaskOptions{TaskListID: list.Id, Title: "First", Parent: parent.Id})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	second, err := client.CreateTask(context.Background(), CreateTaskOptions{TaskListID: list.Id, Title: "Second", Parent: parent.Id, Previous: first.Id})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if second.Parent != parent.Id {
+		t.Errorf("expected parent %s, got %s", parent.Id, second.Parent)
+	}
+	if first.Position >= second.Position {
+		t.Errorf("expected %q to be positioned before %q", first.Position, second.Position)
+	}
+
+	// 2. Move the second subtask to the first position
+	if _, err := client.MoveTask(context.Background(), MoveTaskOptions{TaskListID: list.Id, TaskID: second.Id, Parent: parent.Id}); err != nil {
+		t.Fatalf("MoveTask failed: %v", err)
+	}
+	output := CaptureOutput(t, func() {
+		tasks, err := client.ListTasks(context.Background(), ListTasksOptions{TaskListID: list.Id, SortBy: "position"})
+		if err != nil {
+			t.Fatalf("ListTasks failed: %v", err)
+		}
+		ui.NewPrinter(os.Stdout, "table", false).PrintTasks(tasks)
+	})
+	expected := "[ ]     Parent         " + parent.Id + "\n[ ]       Second       " + second.Id + "\n[ ]       First        " + first.Id + "\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("expected output to contain %q, got %q", expected, output)
+	}
+
+	// 3. A task cannot be moved below its own subtask
+	if _, err := client.MoveTask(context.Background(), MoveTaskOptions{TaskListID: list.Id, TaskID: parent.Id, Parent: first.Id}); err == nil {
+		t.Errorf("expected an error when moving a task below its own subtask")
+	}
+
+	// 4. Deleting the parent deletes its subtasks
+	if err := client.DeleteTask(context.Background(), DeleteTaskOptions{TaskListID: list.Id, TaskID: parent.Id}); err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+	tasks, err := client.ListTasks(context.Background(), ListTasksOptions{TaskListID: list.Id})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(tasks.Items) != 0 {
+		t.Errorf("expected subtasks to be deleted, got %d tasks", len(tasks.Items))
+	}
+}
+
+func TestListTasksPagination(t *testing.T) {
+	server := newFakeTasksServer(t)
+	client := server.client(t)
+
+	list := server.addTaskList("Big List")
+	for i := 0; i < 250; i++ {
+		title := fmt.Sprintf("Task %03d", i)
+		if i%10 == 0 {
+			title += " deploy"
+		}
+		server.addTask(list.Id, &tasks.Task{Title: title})
+	}
+
+	// 1. All pages are fetched
+	all, err := client.ListTasks(context.Background(), ListTasksOptions{TaskListID: list.Id})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(all.Items) != 250 {
+		t.Errorf("expected 250 tasks, got %d", len(all.Items))
+	}
+
+	// 2. MaxResults caps the number of tasks across pages
+	limited, err := client.ListTasks(context.Background(), ListTasksOptions{TaskListID: list.Id, MaxResults: 45, PageSize: 20})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(limited.Items) != 45 {
+		t.Errorf("expected 45 tasks, got %d", len(limited.Items))
+	}
+
+	// 3. The iterator stops fetching when the consumer stops
+	count := 0
+	for _, err := range client.AllTasks(context.Background(), ListTasksOptions{TaskListID: list.Id, PageSize: 100}) {
+		if err != nil {
+			t.Fatalf("AllTasks failed: %v", err)
+		}
+		count++
+		if count == 120 {
+			break
+		}
+	}
+	if count != 120 {
+		t.Errorf("expected to iterate over 120 tasks, got %d", count)
+	}
+
+	// 4. Filters are applied while streaming, MaxResults applies to matches
+	filtered, err := ListFilteredTasks(context.Background(), client, ListTasksOptions{TaskListID: list.Id, MaxResults: 20}, FilterOptions{TitleContains: "deploy"})
+	if err != nil {
+		t.Fatalf("ListFilteredTasks failed: %v", err)
+	}
+	if len(filtered.Items) != 20 {
+		t.Errorf("expected 20 matching tasks, got %d", len(filtered.Items))
+	}
+	for _, task := range filtered.Items {
+		if !strings.Contains(task.Title, "deploy") {
+			t.Errorf("unexpected task %q in filtered result", task.Title)
+		}
+	}
+}
+
+func TestListTasks_SortsBeforeMaxResults(t *testing.T) {
+	server := newFakeTasksServer(t)
+	client := server.client(t)
+
+	// The API returns the tasks in the reverse of their alphabetical order
+	list := server.addTaskList("Reversed")
+	for i := 6; i >= 0; i-- {
+		server.addTask(list.Id, &tasks.Task{Title: fmt.Sprintf("Task %d", i)})
+	}
+
+	// All pages are read and sorted before MaxResults is applied
+	opts := ListTasksOptions{TaskListID: list.Id, SortBy: "alphabetical", MaxResults: 3, PageSize: 2}
+	result, err := client.ListTasks(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	var titles []string
+	for _, task := range result.Items {
+		titles = append(titles, task.Title)
+	}
+	if want := []string{"Task 0", "Task 1", "Task 2"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("expected %v, got %v", want, titles)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	server := newFakeTasksServer(t)
+	list := server.addTaskList("List")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clients := map[string]Client{
+		"online":  server.client(t),
+		"offline": newTestOfflineClient(t),
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			if _, err := client.ListTasks(ctx, ListTasksOptions{TaskListID: list.Id}); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected ListTasks to fail with context.Canceled, got %v", err)
+			}
+			if _, err := client.CreateTask(ctx, CreateTaskOptions{TaskListID: list.Id, Title: "Never"}); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected CreateTask to fail with context.Canceled, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateTask_KeepsOtherFields(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTasksServer(t)
+	list := f.addTaskList("Work")
+	task := f.addTask(list.Id, &tasks.Task{Title: "Report", Notes: "Quarterly", Due: "2026-11-01T00:00:00.000Z"})
+
+	// Online and offline, fields that are not given are left as they are.
+	for name, client := range map[string]Client{"online": f.client(t), "offline": newTestOfflineClient(t)} {
+		t.Run(name, func(t *testing.T) {
+			listID, taskID := list.Id, task.Id
+			if name == "offline" {
+				offlineList, _ := client.CreateTaskList(ctx, CreateTaskListOptions{Title: "Work"})
+				created, _ := client.CreateTask(ctx, CreateTaskOptions{TaskListID: offlineList.Id, Title: "Report", Notes: "Quarterly", Due: "2026-11-01T00:00:00.000Z"})
+				listID, taskID = offlineList.Id, created.Id
+			}
+
+			updated, err := client.UpdateTask(ctx, UpdateTaskOptions{TaskListID: listID, TaskID: taskID, Title: "Annual report"})
+			if err != nil {
+				t.Fatalf("UpdateTask failed: %v", err)
+			}
+			if updated.Title != "Annual report" || updated.Notes != "Quarterly" || !strings.HasPrefix(updated.Due, "2026-11-01") {
+				t.Errorf("expected only the title to change, got %+v", updated)
+			}
+		})
+	}
+}
+
+func TestListFilteredTasks_SortsBeforeMaxResults(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeTasksServer(t)
+	list := f.addTaskList("Work")
+	titles := []string{"C deploy", "A deploy", "D", "B deploy"}
+	for _, title := range titles {
+		f.addTask(list.Id, &tasks.Task{Title: title})
+	}
+
+	// Online and offline, the first matches in sort order are returned.
+	for name, client := range map[string]Client{"online": f.client(t), "offline": newTestOfflineClient(t)} {
+		t.Run(name, func(t *testing.T) {
+			listID := list.Id
+			if name == "offline" {
+				offlineList, _ := client.CreateTaskList(ctx, CreateTaskListOptions{Title: "Work"})
+				for _, title := range titles {
+					client.CreateTask(ctx, CreateTaskOptions{TaskListID: offlineList.Id, Title: title})
+				}
+				listID = offlineList.Id
+			}
+
+			listOpts := ListTasksOptions{TaskListID: listID, SortBy: "alphabetical", MaxResults: 2}
+			result, err := ListFilteredTasks(ctx, client, listOpts, FilterOptions{TitleContains: "deploy"})
+			if err != nil {
+				t.Fatalf("ListFilteredTasks failed: %v", err)
+			}
+			var got []string
+			for _, task := range result.Items {
+				got = append(got, task.Title)
+			}
+			if want := []string{"A deploy", "B deploy"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package lockedfile
+
+import "os"
+
+// tryLock does not lock the file on systems without advisory locks. Writes
+// are still atomic, but concurrent processes may overwrite each other's
+// changes.
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile does nothing on systems without advisory locks.
+func unlockFile(f *os.File) error {
+	return nil
+}
+
+// syncDir does nothing on these systems.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package lockedfile writes files that are shared between gtasks processes,
+// such as the configuration, the tokens and the offline store.
+//
+// Files are replaced atomically, so that readers and crashes never leave a
+// truncated file, and read-modify-write cycles are serialized with advisory
+// locks, so that concurrent processes do not overwrite each other's changes.
+package lockedfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned when a file stays locked by another process for
+// longer than the lock timeout.
+var ErrLocked = errors.New("locked by another gtasks process")
+
+// lockTimeout is how long Lock waits for another process to release a lock.
+var lockTimeout = 10 * time.Second
+
+// Lock takes an exclusive advisory lock for the file at path and returns a
+// function that releases it. The lock is held on a separate file, path +
+// ".lock", because the file itself is replaced on every write. The lock file
+// is never removed, since removing it would let two processes lock different
+// files. Locks are released when the process exits, even if it crashes.
+func Lock(path string) (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	delay := time.Millisecond
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s is %w", path, ErrLocked)
+		}
+		time.Sleep(delay)
+		delay = min(2*delay, 50*time.Millisecond)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// Write atomically replaces the file at path with data. The data is written
+// to a temporary file in the same directory, flushed to disk and renamed
+// over the file, so that the file holds either the old or the new data even
+// if the process crashes. Write does not lock the file; use Lock or
+// Transform to serialize writers.
+func Write(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	// Flush the directory entry, so that the rename survives a crash.
+	return syncDir(dir)
+}
+
+// Transform locks the file at path, reads it and replaces it with the data
+// returned by fn. Because the file is read after the lock is taken, fn
+// always sees the changes of other processes. data is nil if the file does
+// not exist. The file is left unchanged if fn returns an error.
+func Transform(path string, perm os.FileMode, fn func(data []byte) ([]byte, error)) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	data, err = fn(data)
+	if err != nil {
+		return err
+	}
+	return Write(path, data, perm)
+}
@@ -0,0 +1,148 @@
+package lockedfile
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// increment adds one to the counter stored in a file.
+func increment(data []byte) ([]byte, error) {
+	n, _ := strconv.Atoi(string(data))
+	return []byte(strconv.Itoa(n + 1)), nil
+}
+
+// TestHelperProcess is run as a separate process by
+// TestTransform_ConcurrentProcesses. It increments the counter in the file
+// named by GTASKS_TEST_COUNTER_FILE.
+func TestHelperProcess(t *testing.T) {
+	path := os.Getenv("GTASKS_TEST_COUNTER_FILE")
+	if path == "" {
+		return
+	}
+	n, _ := strconv.Atoi(os.Getenv("GTASKS_TEST_INCREMENTS"))
+	for range n {
+		if err := Transform(path, 0600, increment); err != nil {
+			t.Fatalf("failed to increment the counter: %v", err)
+		}
+	}
+}
+
+func TestTransform_ConcurrentProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	const processes, increments = 6, 40
+
+	var cmds []*exec.Cmd
+	for range processes {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(os.Environ(), "GTASKS_TEST_COUNTER_FILE="+path, "GTASKS_TEST_INCREMENTS="+strconv.Itoa(increments))
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start a writer: %v", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("writer failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read the counter: %v", err)
+	}
+	if got := string(data); got != strconv.Itoa(processes*increments) {
+		t.Errorf("expected the counter to be %d, got %s", processes*increments, got)
+	}
+}
+
+func TestTransform_ConcurrentGoroutines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	const writers, increments = 8, 25
+
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range increments {
+				if err := Transform(path, 0600, increment); err != nil {
+					t.Errorf("failed to increment the counter: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, _ := os.ReadFile(path)
+	if got := string(data); got != strconv.Itoa(writers*increments) {
+		t.Errorf("expected the counter to be %d, got %s", writers*increments, got)
+	}
+}
+
+func TestTransform_Error(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+	if err := Write(path, []byte("old"), 0600); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	failure := errors.New("failure")
+	err := Transform(path, 0600, func(data []byte) ([]byte, error) {
+		return nil, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of the function, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("expected the file to be unchanged, got %q", data)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "file")
+	for _, content := range []string{"first", "second"} {
+		if err := Write(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != content {
+			t.Errorf("expected %q, got %q", content, data)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind.
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the file, got %v", entries)
+	}
+}
+
+func TestLock_Timeout(t *testing.T) {
+	defer func(timeout time.Duration) { lockTimeout = timeout }(lockTimeout)
+	lockTimeout = 50 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "file")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+	if _, err := Lock(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+
+	unlock()
+	unlock, err = Lock(path)
+	if err != nil {
+		t.Fatalf("failed to lock after unlocking: %v", err)
+	}
+	unlock()
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// ArchiveVersion is the version of the archive format written by this
+// version of gtasks. Files without a version predate versioning and are
+// read as version 1.
+const ArchiveVersion = 1
+
+// Data is the content of the store.
+type Data struct {
+	TaskLists map[string]*tasks.TaskList        `json:"task_lists"`
+	Tasks     map[string]map[string]*tasks.Task `json:"tasks"` // taskListID -> taskID -> task
+	NextID    int                               `json:"next_id"`
+	Journal   []JournalEntry                    `json:"journal,omitempty"` // offline mutations not yet synced
+	IDMap     map[string]string                 `json:"id_map,omitempty"`  // local ID -> server ID
+}
+
+// Archive is the file format of the offline store and of backups, so that
+// a backup can seed offline mode.
+type Archive struct {
+	Version int `json:"version"`
+	// Account and Created describe backups: the account that was backed up
+	// and the time of the backup in RFC3339 format. They are empty for the
+	// offline store.
+	Account string `json:"account,omitempty"`
+	Created string `json:"created,omitempty"`
+	Data    Data   `json:"data"`
+}
+
+// ReadArchive reads an archive. It fails for archives written by a newer
+// version of gtasks.
+func ReadArchive(r io.Reader) (*Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return nil, err
+	}
+	if archive.Version > ArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is not supported (latest is %d)", archive.Version, ArchiveVersion)
+	}
+	archive.Version = ArchiveVersion
+	archive.Data.init()
+	return &archive, nil
+}
+
+// WriteArchive writes an archive as indented JSON.
+func WriteArchive(w io.Writer, archive *Archive) error {
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// init creates the maps of data that was read without them.
+func (d *Data) init() {
+	if d.TaskLists == nil {
+		d.TaskLists = make(map[string]*tasks.TaskList)
+	}
+	if d.Tasks == nil {
+		d.Tasks = make(map[string]map[string]*tasks.Task)
+	}
+	if d.IDMap == nil { // Files written before sync support
+		d.IDMap = make(map[string]string)
+	}
+	if d.NextID < 1 {
+		d.NextID = 1
+	}
+}
+
+// localID matches the IDs generated by newID.
+var localID = regexp.MustCompile(`^id(\d+)$`)
+
+// Seed replaces the content of the store with the data of an archive, such
+// as a backup. It refuses to discard a journal that has not been synced
+// yet.
+func (s *InMemoryStore) Seed(data Data) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if n := len(s.Data.Journal); n > 0 {
+		return fmt.Errorf("the offline store has %d unsynced change(s)", n)
+	}
+	data.init()
+
+	// Make sure new local IDs do not collide with the seeded ones.
+	nextID := max(data.NextID, s.Data.NextID)
+	for listID, list := range data.Tasks {
+		for _, id := range append(mapKeys(list), listID) {
+			if m := localID.FindStringSubmatch(id); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				nextID = max(nextID, n+1)
+			}
+		}
+	}
+	data.NextID = nextID
+	s.Data = data
+	return s.persist()
+}
+
+// mapKeys returns the keys of a map.
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// siblings returns the tasks with the given parent ordered by position,
+// excluding the task with the given ID. The caller must hold the lock.
+func (s *InMemoryStore) siblings(listID, parent, exclude string) []*tasks.Task {
+	var result []*tasks.Task
+	for id, task := range s.Data.Tasks[listID] {
+		if task.Parent == parent && id != exclude {
+			result = append(result, task)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Position != result[j].Position {
+			return result[i].Position < result[j].Position
+		}
+		return result[i].Id < result[j].Id
+	})
+	return result
+}
+
+// place inserts a task among its siblings after the sibling with the given
+// previous ID, or first if previous is empty, and renumbers the positions of
+// all siblings in the same format as the Google Tasks API. The caller must
+// hold the lock.
+func (s *InMemoryStore) place(listID string, task *tasks.Task, previous string) {
+	siblings := s.siblings(listID, task.Parent, task.Id)
+	index := 0
+	for i, sibling := range siblings {
+		if sibling.Id == previous {
+			index = i + 1
+			break
+		}
+	}
+	ordered := append([]*tasks.Task{}, siblings[:index]...)
+	ordered = append(ordered, task)
+	ordered = append(ordered, siblings[index:]...)
+	for i, sibling := range ordered {
+		sibling.Position = fmt.Sprintf("%020d", i)
+	}
+}
+
+// checkPlacement validates the parent and previous sibling for a new or
+// moved task. The caller must hold the lock.
+func (s *InMemoryStore) checkPlacement(listID, taskID, parent, previous string) error {
+	if parent != "" {
+		if _, ok := s.Data.Tasks[listID][parent]; !ok {
+			return fmt.Errorf("parent task %s %w", parent, ErrNotFound)
+		}
+		for id := parent; id != ""; {
+			if id == taskID {
+				return fmt.Errorf("%w: cannot move task %s below itself", ErrInvalidPlacement, taskID)
+			}
+			ancestor, ok := s.Data.Tasks[listID][id]
+			if !ok {
+				break
+			}
+			id = ancestor.Parent
+		}
+	}
+	if previous != "" {
+		sibling, ok := s.Data.Tasks[listID][previous]
+		if !ok {
+			return fmt.Errorf("previous task %s %w", previous, ErrNotFound)
+		}
+		if sibling.Parent != parent {
+			return fmt.Errorf("%w: previous task %s is not a sibling under the same parent", ErrInvalidPlacement, previous)
+		}
+		if previous == taskID {
+			return fmt.Errorf("%w: task %s cannot be its own previous sibling", ErrInvalidPlacement, taskID)
+		}
+	}
+	return nil
+}
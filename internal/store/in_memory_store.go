@@ -2,11 +2,14 @@ package store
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/yanicksenn/gtasks/internal/lockedfile"
 	"google.golang.org/api/tasks/v1"
 )
 
@@ -14,50 +17,49 @@ const (
 	offlineDataFile = "offline.json"
 )
 
+var (
+	// ErrNotFound is returned for task lists and tasks that are not in the
+	// store.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidPlacement is returned when a task cannot be placed below a
+	// parent or after a previous sibling.
+	ErrInvalidPlacement = errors.New("invalid placement")
+)
+
 // InMemoryStore manages the state of tasks and task lists in memory,
 // with an option to persist to a local file.
 type InMemoryStore struct {
 	mu   sync.Mutex
 	path string // Path for persistence; if empty, store is transient.
-	Data struct {
-		TaskLists map[string]*tasks.TaskList       `json:"task_lists"`
-		Tasks     map[string]map[string]*tasks.Task `json:"tasks"` // taskListID -> taskID -> task
-		NextID    int                              `json:"next_id"`
-	} `json:"data"`
+	// loaded describes the file as it was last read or written, to tell
+	// whether another process has changed it since.
+	loaded os.FileInfo
+	now    func() time.Time
+	// idPrefix is the prefix of generated IDs.
+	idPrefix string
+	Data     Data `json:"data"`
 }
 
 // NewInMemoryStore creates a new in-memory store. If a path is provided,
-// it loads data from that file if it exists.
+// it loads data from that file if it exists. The file can be shared with
+// other processes: changes are made under a file lock to the latest data in
+// the file.
 func NewInMemoryStore(path string) (*InMemoryStore, error) {
-	store := &InMemoryStore{path: path}
-	store.Data.TaskLists = make(map[string]*tasks.TaskList)
-	store.Data.Tasks = make(map[string]map[string]*tasks.Task)
-	store.Data.NextID = 1
+	store := &InMemoryStore{path: path, now: time.Now, idPrefix: "id"}
+	store.Data.init()
 
 	if path == "" {
 		return store, nil // Transient store
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return nil, err
-	}
-
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return store, store.persist() // Create the file if it doesn't exist
-	}
-
-	data, err := os.ReadFile(path)
+	unlock, err := store.lock()
 	if err != nil {
 		return nil, err
 	}
-	if len(data) == 0 { // File is empty
+	defer unlock()
+	if store.loaded == nil { // File is missing or empty
 		return store, store.persist()
 	}
-
-	if err := json.Unmarshal(data, &store.Data); err != nil {
-		return nil, err
-	}
-
 	return store, nil
 }
 
@@ -69,6 +71,22 @@ func NewTestStore() *InMemoryStore {
 	return store
 }
 
+// SetClock replaces the clock used to timestamp journal entries.
+func (s *InMemoryStore) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetIDPrefix replaces the prefix of generated IDs, which is "id" by
+// default. Stores that stand in for the server use another prefix, so that
+// their IDs cannot be mistaken for local IDs.
+func (s *InMemoryStore) SetIDPrefix(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idPrefix = prefix
+}
+
 // GetOfflineStorePath returns the default path for the offline data file.
 func GetOfflineStorePath() (string, error) {
 	home, err := os.UserHomeDir()
@@ -78,42 +96,145 @@ func GetOfflineStorePath() (string, error) {
 	return filepath.Join(home, ".config", "gtasks", offlineDataFile), nil
 }
 
-// persist writes the current state of the store to the file system.
+// lock locks the store for a change. If the store is persisted, it also
+// locks the file against other processes and reloads it if they changed
+// it, so that the change is made to the latest data. The returned function
+// unlocks both.
+func (s *InMemoryStore) lock() (func(), error) {
+	s.mu.Lock()
+	if s.path == "" {
+		return s.mu.Unlock, nil
+	}
+
+	unlockFile, err := lockedfile.Lock(s.path)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if err := s.reload(); err != nil {
+		unlockFile()
+		s.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlockFile()
+		s.mu.Unlock()
+	}, nil
+}
+
+// rlock locks the store for reading and reloads the file if another
+// process changed it. The file is not locked, because it is only ever
+// replaced as a whole.
+func (s *InMemoryStore) rlock() (func(), error) {
+	s.mu.Lock()
+	if err := s.reload(); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	return s.mu.Unlock, nil
+}
+
+// reload reads the file if it changed since it was last read or written. A
+// missing or empty file keeps the data in memory. The caller must hold the
+// lock.
+func (s *InMemoryStore) reload() error {
+	if s.path == "" {
+		return nil
+	}
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 || s.unchanged(info) {
+		return nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	archive, err := ReadArchive(f)
+	if err != nil {
+		return err
+	}
+	s.Data = archive.Data
+	s.loaded = info
+	return nil
+}
+
+// unchanged reports whether the file is still the one that was last read
+// or written. Every write replaces the file, so another process's write
+// changes its identity or at least its modification time.
+func (s *InMemoryStore) unchanged(info os.FileInfo) bool {
+	return s.loaded != nil && os.SameFile(info, s.loaded) &&
+		info.ModTime().Equal(s.loaded.ModTime()) && info.Size() == s.loaded.Size()
+}
+
+// persist writes the current state of the store to the file system. The
+// caller must hold the lock.
 func (s *InMemoryStore) persist() error {
 	if s.path == "" {
 		return nil // Don't persist for a transient store
 	}
-	data, err := json.MarshalIndent(s.Data, "", "  ")
+	data, err := json.MarshalIndent(Archive{Version: ArchiveVersion, Data: s.Data}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := lockedfile.Write(s.path, data, 0600); err != nil {
+		return err
+	}
+	info, err := os.Stat(s.path)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.path, data, 0600)
+	s.loaded = info
+	return nil
+}
+
+// resolve maps a local ID that has already been synced to its server ID.
+// Unknown IDs are returned unchanged.
+func (s *InMemoryStore) resolve(id string) string {
+	if mapped, ok := s.Data.IDMap[id]; ok {
+		return mapped
+	}
+	return id
 }
 
 // newID generates a new unique ID for a task or task list.
 func (s *InMemoryStore) newID() string {
-	id := fmt.Sprintf("id%d", s.Data.NextID)
+	id := fmt.Sprintf("%s%d", s.idPrefix, s.Data.NextID)
 	s.Data.NextID++
 	return id
 }
 
 // CreateTaskList creates a new task list in the store.
 func (s *InMemoryStore) CreateTaskList(list *tasks.TaskList) (*tasks.TaskList, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	id := s.newID()
 	newList := &tasks.TaskList{Id: id, Title: list.Title}
 	s.Data.TaskLists[id] = newList
 	s.Data.Tasks[id] = make(map[string]*tasks.Task)
+	s.record(JournalEntry{Op: OpCreateTaskList, TaskListID: id, TaskList: copyTaskList(newList)})
 
 	return newList, s.persist()
 }
 
 // ListTaskLists returns all the task lists in the store.
 func (s *InMemoryStore) ListTaskLists() ([]*tasks.TaskList, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.rlock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	var lists []*tasks.TaskList
 	for _, list := range s.Data.TaskLists {
 		lists = append(lists, list)
@@ -123,33 +244,90 @@ func (s *InMemoryStore) ListTaskLists() ([]*tasks.TaskList, error) {
 
 // GetTaskList returns a task list from the store by its ID.
 func (s *InMemoryStore) GetTaskList(id string) (*tasks.TaskList, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.Data.TaskLists[id], nil
+	unlock, err := s.rlock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	list, ok := s.Data.TaskLists[s.resolve(id)]
+	if !ok {
+		return nil, fmt.Errorf("task list %s %w", id, ErrNotFound)
+	}
+	return list, nil
 }
 
 // UpdateTaskList updates a task list in the store.
 func (s *InMemoryStore) UpdateTaskList(id string, list *tasks.TaskList) (*tasks.TaskList, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	existingList := s.Data.TaskLists[id]
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	existingList, ok := s.Data.TaskLists[s.resolve(id)]
+	if !ok {
+		return nil, fmt.Errorf("task list %s %w", id, ErrNotFound)
+	}
+	id = existingList.Id
+	base := copyTaskList(existingList)
 	existingList.Title = list.Title
+	s.record(JournalEntry{
+		Op:           OpUpdateTaskList,
+		TaskListID:   id,
+		TaskList:     copyTaskList(existingList),
+		BaseTaskList: base,
+		BaseUpdated:  base.Updated,
+		BaseEtag:     base.Etag,
+	})
 	return existingList, s.persist()
 }
 
 // DeleteTaskList deletes a task list from the store.
 func (s *InMemoryStore) DeleteTaskList(id string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	list, ok := s.Data.TaskLists[s.resolve(id)]
+	if !ok {
+		return fmt.Errorf("task list %s %w", id, ErrNotFound)
+	}
+	id = list.Id
+	s.record(JournalEntry{
+		Op:           OpDeleteTaskList,
+		TaskListID:   id,
+		BaseTaskList: copyTaskList(list),
+		BaseUpdated:  list.Updated,
+		BaseEtag:     list.Etag,
+	})
 	delete(s.Data.TaskLists, id)
 	delete(s.Data.Tasks, id)
 	return s.persist()
 }
 
-// CreateTask creates a new task in the store.
-func (s *InMemoryStore) CreateTask(listID string, task *tasks.Task) (*tasks.Task, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// CreateTask creates a new task in the store. If the task has a parent, it
+// is created as a subtask. The task is placed after the sibling with the
+// given previous ID, or first among its siblings if previous is empty.
+func (s *InMemoryStore) CreateTask(listID string, task *tasks.Task, previous string) (*tasks.Task, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	listID = s.resolve(listID)
+	parent, previous := s.resolve(task.Parent), s.resolve(previous)
+	// Before the first sync, tasks created in @default are kept under that
+	// name and replayed against the default task list of the server.
+	if _, ok := s.Data.TaskLists[listID]; !ok && listID != "@default" {
+		return nil, fmt.Errorf("task list %s %w", listID, ErrNotFound)
+	}
+	if _, ok := s.Data.Tasks[listID]; !ok {
+		s.Data.Tasks[listID] = make(map[string]*tasks.Task)
+	}
+	if err := s.checkPlacement(listID, "", parent, previous); err != nil {
+		return nil, err
+	}
 
 	id := s.newID()
 	newTask := &tasks.Task{
@@ -157,28 +335,74 @@ func (s *InMemoryStore) CreateTask(listID string, task *tasks.Task) (*tasks.Task
 		Title:  task.Title,
 		Notes:  task.Notes,
 		Due:    task.Due,
+		Parent: parent,
 		Status: "needsAction",
 	}
-	if _, ok := s.Data.Tasks[listID]; !ok {
-		s.Data.Tasks[listID] = make(map[string]*tasks.Task)
-	}
 	s.Data.Tasks[listID][id] = newTask
+	s.place(listID, newTask, previous)
+	s.record(JournalEntry{Op: OpCreateTask, TaskListID: listID, TaskID: id, Task: copyTask(newTask), Previous: previous})
 	return newTask, s.persist()
 }
 
+// MoveTask moves a task below another parent and after the sibling with the
+// given previous ID. An empty parent moves the task to the top level and an
+// empty previous moves it first among its siblings.
+func (s *InMemoryStore) MoveTask(listID, taskID, parent, previous string) (*tasks.Task, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	listID, taskID = s.resolve(listID), s.resolve(taskID)
+	parent, previous = s.resolve(parent), s.resolve(previous)
+	task, ok := s.Data.Tasks[listID][taskID]
+	if !ok {
+		return nil, fmt.Errorf("task %s %w", taskID, ErrNotFound)
+	}
+	if err := s.checkPlacement(listID, taskID, parent, previous); err != nil {
+		return nil, err
+	}
+
+	base := copyTask(task)
+	task.Parent = parent
+	s.place(listID, task, previous)
+	s.record(JournalEntry{
+		Op:          OpMoveTask,
+		TaskListID:  listID,
+		TaskID:      taskID,
+		Task:        copyTask(task),
+		BaseTask:    base,
+		BaseUpdated: base.Updated,
+		BaseEtag:    base.Etag,
+		Previous:    previous,
+	})
+	return task, s.persist()
+}
+
 // GetTask returns a task from the store by its ID.
 func (s *InMemoryStore) GetTask(listID, taskID string) (*tasks.Task, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.Data.Tasks[listID][taskID], nil
+	unlock, err := s.rlock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	task, ok := s.Data.Tasks[s.resolve(listID)][s.resolve(taskID)]
+	if !ok {
+		return nil, fmt.Errorf("task %s %w", taskID, ErrNotFound)
+	}
+	return task, nil
 }
 
 // ListTasks returns all the tasks in a given task list.
 func (s *InMemoryStore) ListTasks(listID string) ([]*tasks.Task, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.rlock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	var tasks []*tasks.Task
-	for _, task := range s.Data.Tasks[listID] {
+	for _, task := range s.Data.Tasks[s.resolve(listID)] {
 		tasks = append(tasks, task)
 	}
 	return tasks, nil
@@ -186,9 +410,17 @@ func (s *InMemoryStore) ListTasks(listID string) ([]*tasks.Task, error) {
 
 // UpdateTask updates a task in the store.
 func (s *InMemoryStore) UpdateTask(listID, taskID string, task *tasks.Task) (*tasks.Task, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	existingTask := s.Data.Tasks[listID][taskID]
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	listID, taskID = s.resolve(listID), s.resolve(taskID)
+	existingTask, ok := s.Data.Tasks[listID][taskID]
+	if !ok {
+		return nil, fmt.Errorf("task %s %w", taskID, ErrNotFound)
+	}
+	base := copyTask(existingTask)
 	if task.Title != "" {
 		existingTask.Title = task.Title
 	}
@@ -201,13 +433,129 @@ func (s *InMemoryStore) UpdateTask(listID, taskID string, task *tasks.Task) (*ta
 	if task.Status != "" {
 		existingTask.Status = task.Status
 	}
+	s.record(JournalEntry{
+		Op:          taskUpdateOp(base, existingTask),
+		TaskListID:  listID,
+		TaskID:      taskID,
+		Task:        copyTask(existingTask),
+		BaseTask:    base,
+		BaseUpdated: base.Updated,
+		BaseEtag:    base.Etag,
+	})
 	return existingTask, s.persist()
 }
 
-// DeleteTask deletes a task from the store.
+// ReplaceTask replaces the title, notes, due date, status, completion time
+// and hidden flag of a task in the store. Unlike UpdateTask, empty fields
+// are cleared. A completed task without a completion time is completed now.
+func (s *InMemoryStore) ReplaceTask(listID, taskID string, task *tasks.Task) (*tasks.Task, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	listID, taskID = s.resolve(listID), s.resolve(taskID)
+	existingTask, ok := s.Data.Tasks[listID][taskID]
+	if !ok {
+		return nil, fmt.Errorf("task %s %w", taskID, ErrNotFound)
+	}
+	base := copyTask(existingTask)
+	existingTask.Title, existingTask.Notes, existingTask.Due = task.Title, task.Notes, task.Due
+	existingTask.Status, existingTask.Completed, existingTask.Hidden = task.Status, nil, false
+	if task.Status == "completed" {
+		completed := time.Now().UTC().Format(time.RFC3339)
+		if task.Completed != nil {
+			completed = *task.Completed
+		}
+		existingTask.Completed, existingTask.Hidden = &completed, task.Hidden
+	}
+	s.record(JournalEntry{
+		Op:          taskUpdateOp(base, existingTask),
+		TaskListID:  listID,
+		TaskID:      taskID,
+		Task:        copyTask(existingTask),
+		BaseTask:    base,
+		BaseUpdated: base.Updated,
+		BaseEtag:    base.Etag,
+	})
+	return existingTask, s.persist()
+}
+
+// DeleteTask deletes a task and its subtasks from the store.
 func (s *InMemoryStore) DeleteTask(listID, taskID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	listID, taskID = s.resolve(listID), s.resolve(taskID)
+	task, ok := s.Data.Tasks[listID][taskID]
+	if !ok {
+		return fmt.Errorf("task %s %w", taskID, ErrNotFound)
+	}
+	s.record(JournalEntry{
+		Op:          OpDeleteTask,
+		TaskListID:  listID,
+		TaskID:      taskID,
+		BaseTask:    copyTask(task),
+		BaseUpdated: task.Updated,
+		BaseEtag:    task.Etag,
+	})
+	s.deleteSubtree(listID, taskID)
+	return s.persist()
+}
+
+// deleteSubtree deletes a task and all of its descendants. The caller must
+// hold the lock.
+func (s *InMemoryStore) deleteSubtree(listID, taskID string) {
+	for id, task := range s.Data.Tasks[listID] {
+		if task.Parent == taskID {
+			s.deleteSubtree(listID, id)
+		}
+	}
 	delete(s.Data.Tasks[listID], taskID)
+}
+
+// Replace swaps the content of the store with a snapshot of the server
+// state. Callers must replay and acknowledge the journal first. If another
+// process has recorded changes since, Replace fails instead of discarding
+// them. The ID map is kept so that previously issued local IDs still
+// resolve.
+func (s *InMemoryStore) Replace(lists []*tasks.TaskList, items map[string][]*tasks.Task) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if n := len(s.Data.Journal); n > 0 {
+		return fmt.Errorf("the offline store has %d unsynced change(s) that were made during the sync", n)
+	}
+	s.Data.TaskLists = make(map[string]*tasks.TaskList)
+	s.Data.Tasks = make(map[string]map[string]*tasks.Task)
+
+	for _, list := range lists {
+		s.Data.TaskLists[list.Id] = list
+		s.Data.Tasks[list.Id] = make(map[string]*tasks.Task)
+		for _, task := range items[list.Id] {
+			s.Data.Tasks[list.Id][task.Id] = task
+		}
+	}
+	// The server lists the default task list first.
+	if len(lists) > 0 {
+		s.Data.IDMap["@default"] = lists[0].Id
+	}
 	return s.persist()
 }
+
+// copyTaskList returns a shallow copy of a task list.
+func copyTaskList(list *tasks.TaskList) *tasks.TaskList {
+	c := *list
+	return &c
+}
+
+// copyTask returns a shallow copy of a task.
+func copyTask(task *tasks.Task) *tasks.Task {
+	c := *task
+	return &c
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+func TestInMemoryStore_ConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), offlineDataFile)
+	first, err := NewInMemoryStore(path)
+	if err != nil {
+		t.Fatalf("failed to open the store: %v", err)
+	}
+	list, err := first.CreateTaskList(&tasks.TaskList{Title: "Shared"})
+	if err != nil {
+		t.Fatalf("failed to create a task list: %v", err)
+	}
+
+	// Two stores on the same file stand in for two processes.
+	second, err := NewInMemoryStore(path)
+	if err != nil {
+		t.Fatalf("failed to open the store: %v", err)
+	}
+	const writers, creates = 4, 10
+	var wg sync.WaitGroup
+	for i := range writers {
+		store := []*InMemoryStore{first, second}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range creates {
+				if _, err := store.CreateTask(list.Id, &tasks.Task{Title: fmt.Sprintf("Task %d.%d", i, j)}, ""); err != nil {
+					t.Errorf("failed to create a task: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	reopened, err := NewInMemoryStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen the store: %v", err)
+	}
+	items, _ := reopened.ListTasks(list.Id)
+	if len(items) != writers*creates {
+		t.Errorf("expected %d tasks, got %d", writers*creates, len(items))
+	}
+	// One entry for the task list and one for each task, numbered without
+	// gaps or duplicates.
+	journal := reopened.Journal()
+	for i, entry := range journal {
+		if entry.Seq != i+1 {
+			t.Fatalf("expected entry %d to have seq %d, got %d", i, i+1, entry.Seq)
+		}
+	}
+	if len(journal) != writers*creates+1 {
+		t.Errorf("expected %d journal entries, got %d", writers*creates+1, len(journal))
+	}
+
+	// The first store sees the tasks created through the second one.
+	if items, _ := first.ListTasks(list.Id); len(items) != writers*creates {
+		t.Errorf("expected the first store to reload %d tasks, got %d", writers*creates, len(items))
+	}
+}
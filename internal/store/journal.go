@@ -0,0 +1,125 @@
+package store
+
+import (
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// Operation is the kind of mutation recorded in a journal entry.
+type Operation string
+
+const (
+	OpCreateTaskList Operation = "create_task_list"
+	OpUpdateTaskList Operation = "update_task_list"
+	OpDeleteTaskList Operation = "delete_task_list"
+	OpCreateTask     Operation = "create_task"
+	OpUpdateTask     Operation = "update_task"
+	OpCompleteTask   Operation = "complete_task"
+	OpUncompleteTask Operation = "uncomplete_task"
+	OpMoveTask       Operation = "move_task"
+	OpDeleteTask     Operation = "delete_task"
+)
+
+// JournalEntry records a single mutation made in offline mode. Entries are
+// replayed in order against the server on the next sync.
+type JournalEntry struct {
+	// Seq is the position of the entry in the journal.
+	Seq int `json:"seq"`
+	// Time is the time of the mutation in RFC3339 format.
+	Time       string    `json:"time"`
+	Op         Operation `json:"op"`
+	TaskListID string    `json:"task_list_id"`
+	TaskID     string    `json:"task_id,omitempty"`
+	// Task and TaskList hold the state of the item after the mutation.
+	Task     *tasks.Task     `json:"task,omitempty"`
+	TaskList *tasks.TaskList `json:"task_list,omitempty"`
+	// BaseTask and BaseTaskList hold the state of the item before the
+	// mutation. They are used to tell local from remote changes on replay.
+	BaseTask     *tasks.Task     `json:"base_task,omitempty"`
+	BaseTaskList *tasks.TaskList `json:"base_task_list,omitempty"`
+	// BaseUpdated and BaseEtag identify the server version of the item the
+	// mutation was made against. They are empty for items created offline.
+	BaseUpdated string `json:"base_updated,omitempty"`
+	BaseEtag    string `json:"base_etag,omitempty"`
+	// Previous is the ID of the previous sibling for created and moved tasks.
+	Previous string `json:"previous,omitempty"`
+}
+
+// ItemID returns the ID of the task or task list the entry refers to.
+func (e JournalEntry) ItemID() string {
+	if e.TaskID != "" {
+		return e.TaskID
+	}
+	return e.TaskListID
+}
+
+// record appends an entry to the journal. The caller must hold the lock.
+func (s *InMemoryStore) record(entry JournalEntry) {
+	entry.Seq = 1
+	if n := len(s.Data.Journal); n > 0 {
+		entry.Seq = s.Data.Journal[n-1].Seq + 1
+	}
+	entry.Time = s.now().UTC().Format(time.RFC3339)
+	s.Data.Journal = append(s.Data.Journal, entry)
+}
+
+// taskUpdateOp classifies a task update. Updates that only change the status
+// are recorded as completions or uncompletions.
+func taskUpdateOp(before, after *tasks.Task) Operation {
+	if before.Title == after.Title && before.Notes == after.Notes && before.Due == after.Due && before.Status != after.Status {
+		if after.Status == "completed" {
+			return OpCompleteTask
+		}
+		return OpUncompleteTask
+	}
+	return OpUpdateTask
+}
+
+// Journal returns a copy of the pending journal entries in order.
+func (s *InMemoryStore) Journal() []JournalEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]JournalEntry(nil), s.Data.Journal...)
+}
+
+// AcknowledgeJournal removes the entries with the given sequence numbers
+// from the journal once they have been replayed against the server.
+func (s *InMemoryStore) AcknowledgeJournal(seqs ...int) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	acked := make(map[int]bool, len(seqs))
+	for _, seq := range seqs {
+		acked[seq] = true
+	}
+	var remaining []JournalEntry
+	for _, entry := range s.Data.Journal {
+		if !acked[entry.Seq] {
+			remaining = append(remaining, entry)
+		}
+	}
+	s.Data.Journal = remaining
+	return s.persist()
+}
+
+// MapID records that the local ID has been replaced by the given server ID.
+func (s *InMemoryStore) MapID(localID, serverID string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s.Data.IDMap[localID] = serverID
+	return s.persist()
+}
+
+// ResolveID maps a local ID that has already been synced to its server ID.
+// Unknown IDs are returned unchanged.
+func (s *InMemoryStore) ResolveID(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resolve(id)
+}
@@ -1,11 +1,14 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/yanicksenn/gtasks/internal/gtasks"
+	"github.com/yanicksenn/gtasks/internal/ui"
 	"google.golang.org/api/tasks/v1"
 )
 
@@ -27,17 +30,20 @@ import (
 
 // Model is the state of the TUI application.
 type Model struct {
+	ctx        context.Context
 	client     gtasks.Client
 	err        error
 	taskListID string
 	taskList   *tasks.TaskList
 	tasks      []*tasks.Task
+	depths     map[string]int // task ID -> depth in the task hierarchy
 	cursor     int
 }
 
-// New creates a new TUI model.
-func New(client gtasks.Client, taskListID string) (*Model, error) {
+// New creates a new TUI model. The context bounds all calls to the client.
+func New(ctx context.Context, client gtasks.Client, taskListID string) (*Model, error) {
 	return &Model{
+		ctx:        ctx,
 		client:     client,
 		taskListID: taskListID,
 	}, nil
@@ -73,7 +79,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.taskList = msg.taskList
 	// Handle fetched tasks
 	case tasksFetchedMsg:
-		m.tasks = msg.tasks
+		m.tasks = nil
+		m.depths = make(map[string]int)
+		for _, node := range ui.TaskTree(msg.tasks) {
+			m.tasks = append(m.tasks, node.Task)
+			m.depths[node.Task.Id] = node.Depth
+		}
 	// Handle toggled task completion
 	case taskCompletionToggledMsg:
 		m.tasks[m.cursor] = msg.task
@@ -115,7 +126,8 @@ func (m *Model) renderTask(i int, task *tasks.Task) string {
 		style = style.Foreground(lipgloss.Color("205"))
 	}
 
-	return fmt.Sprintf("%s\n", style.Render(fmt.Sprintf("%s %s", checkbox, task.Title)))
+	indent := strings.Repeat("  ", m.depths[task.Id])
+	return fmt.Sprintf("%s%s\n", indent, style.Render(fmt.Sprintf("%s %s", checkbox, task.Title)))
 }
 
 // taskListFetchedMsg is a message that is sent when the task list is fetched.
@@ -140,7 +152,7 @@ type errMsg struct {
 
 // fetchTaskList fetches the task list from the client.
 func (m *Model) fetchTaskList() tea.Msg {
-	taskList, err := m.client.GetTaskList(gtasks.GetTaskListOptions{
+	taskList, err := m.client.GetTaskList(m.ctx, gtasks.GetTaskListOptions{
 		TaskListID: m.taskListID,
 	})
 	if err != nil {
@@ -151,7 +163,7 @@ func (m *Model) fetchTaskList() tea.Msg {
 
 // fetchTasks fetches the tasks from the client.
 func (m *Model) fetchTasks() tea.Msg {
-	tasks, err := m.client.ListTasks(gtasks.ListTasksOptions{
+	tasks, err := m.client.ListTasks(m.ctx, gtasks.ListTasksOptions{
 		TaskListID:    m.taskListID,
 		ShowCompleted: true,
 		SortBy:        "alphabetical",
@@ -170,12 +182,12 @@ func (m *Model) toggleTaskCompletion() tea.Msg {
 	var err error
 
 	if task.Status == "completed" {
-		updatedTask, err = m.client.UncompleteTask(gtasks.UncompleteTaskOptions{
+		updatedTask, err = m.client.UncompleteTask(m.ctx, gtasks.UncompleteTaskOptions{
 			TaskListID: m.taskListID,
 			TaskID:     task.Id,
 		})
 	} else {
-		updatedTask, err = m.client.CompleteTask(gtasks.CompleteTaskOptions{
+		updatedTask, err = m.client.CompleteTask(m.ctx, gtasks.CompleteTaskOptions{
 			TaskListID: m.taskListID,
 			TaskID:     task.Id,
 		})
@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AccountStatus is the status of the stored token of an account.
+type AccountStatus struct {
+	Account  string   `json:"account" yaml:"account"`
+	Active   bool     `json:"active" yaml:"active"`
+	ClientID string   `json:"clientId" yaml:"clientid"`
+	Scopes   []string `json:"scopes" yaml:"scopes"`
+	// Expiry is when the access token expires, in RFC 3339 format.
+	Expiry string `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+	// Refreshed is when the token was last issued or refreshed, in RFC 3339
+	// format. It is empty if it is unknown.
+	Refreshed string `json:"refreshed,omitempty" yaml:"refreshed,omitempty"`
+	// Refresh is ok if refreshing the token works, failed if it does not,
+	// and unchecked if it was not tried.
+	Refresh string `json:"refresh" yaml:"refresh"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// The values of AccountStatus.Refresh.
+const (
+	RefreshOK        = "ok"
+	RefreshFailed    = "failed"
+	RefreshUnchecked = "unchecked"
+)
+
+// AccountStatuses is a collection of account statuses.
+type AccountStatuses struct {
+	Items []AccountStatus `json:"items" yaml:"items"`
+}
+
+// PrintAccountStatuses prints the status of the tokens of all accounts.
+func (p *Printer) PrintAccountStatuses(statuses *AccountStatuses) error {
+	switch p.format {
+	case JSONFormat:
+		return json.NewEncoder(p.out).Encode(statuses)
+	case YAMLFormat:
+		return yaml.NewEncoder(p.out).Encode(statuses)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(statuses)
+	case CSVFormat, TSVFormat:
+		return p.printAccountStatusRows(statuses.Items)
+	case MarkdownFormat:
+		for _, status := range statuses.Items {
+			fmt.Fprintf(p.out, "- %s: refresh %s, expires %s, scopes %s\n", accountLabel(status), status.Refresh, orDash(status.Expiry), strings.Join(shortScopes(status.Scopes), " "))
+		}
+		return nil
+	default:
+		if p.quiet {
+			return nil
+		}
+		if len(statuses.Items) == 0 {
+			fmt.Fprintln(p.out, "No accounts authenticated.")
+			return nil
+		}
+		t := &table{headers: []string{"ACCOUNT", "REFRESH", "EXPIRY", "REFRESHED", "SCOPES"}, flex: 4}
+		for _, status := range statuses.Items {
+			refreshTone := toneNone
+			if status.Refresh == RefreshFailed {
+				refreshTone = toneOverdue
+			}
+			t.rows = append(t.rows, []tableCell{
+				{text: accountLabel(status)},
+				{text: status.Refresh, tone: refreshTone},
+				{text: orDash(status.Expiry)},
+				{text: orDash(status.Refreshed)},
+				{text: strings.Join(shortScopes(status.Scopes), " ")},
+			})
+		}
+		if err := p.printTable(t); err != nil {
+			return err
+		}
+		// The errors are too long for the table.
+		for _, status := range statuses.Items {
+			if status.Error != "" {
+				fmt.Fprintf(p.out, "\n%s: %s\n", status.Account, status.Error)
+			}
+		}
+		return nil
+	}
+}
+
+// printAccountStatusRows prints account statuses as CSV or TSV.
+func (p *Printer) printAccountStatusRows(statuses []AccountStatus) error {
+	columns, err := p.selectColumns(AccountStatusColumns, defaultAccountStatusColumns)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(statuses))
+	for i, status := range statuses {
+		rows[i] = make([]string, len(columns))
+		for j, column := range columns {
+			switch column {
+			case "account":
+				rows[i][j] = status.Account
+			case "active":
+				rows[i][j] = fmt.Sprint(status.Active)
+			case "client":
+				rows[i][j] = status.ClientID
+			case "scopes":
+				rows[i][j] = strings.Join(status.Scopes, " ")
+			case "expiry":
+				rows[i][j] = status.Expiry
+			case "refreshed":
+				rows[i][j] = status.Refreshed
+			case "refresh":
+				rows[i][j] = status.Refresh
+			case "error":
+				rows[i][j] = status.Error
+			}
+		}
+	}
+	return p.printRows(columns, rows)
+}
+
+// accountLabel returns the account, marked if it is the active one.
+func accountLabel(status AccountStatus) string {
+	if status.Active {
+		return status.Account + " (active)"
+	}
+	return status.Account
+}
+
+// shortScopes returns the scopes without Google's common prefix.
+func shortScopes(scopes []string) []string {
+	short := make([]string, len(scopes))
+	for i, scope := range scopes {
+		short[i] = strings.TrimPrefix(scope, "https://www.googleapis.com/auth/")
+	}
+	return short
+}
+
+// orDash returns the text, or a dash if it is empty.
+func orDash(text string) string {
+	if text == "" {
+		return "-"
+	}
+	return text
+}
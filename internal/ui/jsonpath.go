@@ -0,0 +1,542 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled JSONPath template in the style of kubectl:
+// text with expressions in braces, such as
+//
+//	{range .items[*]}{.id}{"\t"}{.title}{"\n"}{end}
+//
+// Supported are fields (.title, ['title']), wildcards (.*, [*]), indexes
+// and slices ([0], [-1], [1:3]), recursive descent (..title), filters
+// ([?(@.status=="completed")]), string literals and range/end. An
+// expression with several results prints them separated by spaces, and
+// missing fields print nothing.
+type JSONPath struct {
+	nodes []jsonPathNode
+}
+
+// jsonPathNode is a piece of a template: text, an expression or a range.
+type jsonPathNode struct {
+	text    string
+	path    []jsonPathStep
+	isRange bool
+	body    []jsonPathNode
+}
+
+// jsonPathStep is one step of an expression.
+type jsonPathStep struct {
+	kind   string // root, field, wildcard, recursive, index, slice, filter
+	name   string
+	index  int
+	start  *int
+	end    *int
+	filter []jsonPathStep
+	op     string
+	value  any
+}
+
+// ParseJSONPath compiles a JSONPath template.
+func ParseJSONPath(text string) (*JSONPath, error) {
+	nodes, _, err := parseJSONPathNodes(text, false)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONPath{nodes: nodes}, nil
+}
+
+// parseJSONPathNodes parses nodes up to the end of the text, or up to a
+// matching {end} if inRange is set. It returns the text after the {end}.
+func parseJSONPathNodes(text string, inRange bool) ([]jsonPathNode, string, error) {
+	var nodes []jsonPathNode
+	for text != "" {
+		open := strings.IndexByte(text, '{')
+		if open < 0 {
+			nodes = append(nodes, jsonPathNode{text: text})
+			text = ""
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jsonPathNode{text: text[:open]})
+		}
+		closing := closingBrace(text, open)
+		if closing < 0 {
+			return nil, "", fmt.Errorf("jsonpath: unclosed { in %q", text[open:])
+		}
+		expr := strings.TrimSpace(text[open+1 : closing])
+		text = text[closing+1:]
+
+		switch {
+		case expr == "end":
+			if !inRange {
+				return nil, "", fmt.Errorf("jsonpath: {end} without {range}")
+			}
+			return nodes, text, nil
+		case strings.HasPrefix(expr, "range "):
+			path, err := parseJSONPathExpr(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+			body, rest, err := parseJSONPathNodes(text, true)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{isRange: true, path: path, body: body})
+			text = rest
+		case strings.HasPrefix(expr, `"`) || strings.HasPrefix(expr, "'"):
+			literal, err := unquoteJSONPath(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{text: literal})
+		default:
+			path, err := parseJSONPathExpr(expr)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jsonPathNode{path: path})
+		}
+	}
+	if inRange {
+		return nil, "", fmt.Errorf("jsonpath: {range} without {end}")
+	}
+	return nodes, "", nil
+}
+
+// closingBrace returns the index of the brace that closes the one at open,
+// skipping quoted strings.
+func closingBrace(text string, open int) int {
+	var quote byte
+	for i := open + 1; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteJSONPath unquotes a string literal in double or single quotes.
+func unquoteJSONPath(s string) (string, error) {
+	if strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) >= 2 {
+		s = `"` + strings.ReplaceAll(s[1:len(s)-1], `"`, `\"`) + `"`
+	}
+	literal, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("jsonpath: invalid string literal %s", s)
+	}
+	return literal, nil
+}
+
+// parseJSONPathExpr parses an expression such as $.items[*].title.
+func parseJSONPathExpr(expr string) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	s := expr
+	if strings.HasPrefix(s, "$") {
+		steps = append(steps, jsonPathStep{kind: "root"})
+		s = s[1:]
+	} else {
+		s = strings.TrimPrefix(s, "@")
+	}
+
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			name, rest := jsonPathName(s[2:])
+			if name == "" {
+				return nil, fmt.Errorf("jsonpath: expected a field name after .. in %q", expr)
+			}
+			steps = append(steps, jsonPathStep{kind: "recursive", name: name})
+			s = rest
+		case strings.HasPrefix(s, ".*"):
+			steps = append(steps, jsonPathStep{kind: "wildcard"})
+			s = s[2:]
+		case strings.HasPrefix(s, "."):
+			name, rest := jsonPathName(s[1:])
+			if name != "" {
+				steps = append(steps, jsonPathStep{kind: "field", name: name})
+			}
+			s = rest
+		case strings.HasPrefix(s, "["):
+			end := closingBracket(s)
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath: unclosed [ in %q", expr)
+			}
+			step, err := parseJSONPathBracket(strings.TrimSpace(s[1:end]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("jsonpath: unexpected %q in %q", s, expr)
+		}
+	}
+	return steps, nil
+}
+
+// jsonPathName splits a field name off the start of s.
+func jsonPathName(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' && s[i] != ' ' && s[i] != '\t' {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// closingBracket returns the index of the bracket that closes the one at
+// the start of s, skipping quoted strings and nested brackets.
+func closingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseJSONPathBracket parses the content of brackets.
+func parseJSONPathBracket(s string) (jsonPathStep, error) {
+	switch {
+	case s == "*":
+		return jsonPathStep{kind: "wildcard"}, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		name, err := unquoteJSONPath(s)
+		return jsonPathStep{kind: "field", name: name}, err
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		return parseJSONPathFilter(strings.TrimSpace(s[2 : len(s)-1]))
+	case strings.Contains(s, ":"):
+		step := jsonPathStep{kind: "slice"}
+		from, to, _ := strings.Cut(s, ":")
+		for _, bound := range []struct {
+			text string
+			dst  **int
+		}{{from, &step.start}, {to, &step.end}} {
+			if text := strings.TrimSpace(bound.text); text != "" {
+				n, err := strconv.Atoi(text)
+				if err != nil {
+					return step, fmt.Errorf("jsonpath: invalid slice [%s]", s)
+				}
+				*bound.dst = &n
+			}
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return jsonPathStep{}, fmt.Errorf("jsonpath: invalid index [%s]", s)
+		}
+		return jsonPathStep{kind: "index", index: n}, nil
+	}
+}
+
+// parseJSONPathFilter parses a filter such as @.status=="completed".
+func parseJSONPathFilter(s string) (jsonPathStep, error) {
+	step := jsonPathStep{kind: "filter"}
+	path := s
+operators:
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if i := strings.Index(s, op); i >= 0 {
+			path, step.op = strings.TrimSpace(s[:i]), op
+			literal := strings.TrimSpace(s[i+len(op):])
+			switch {
+			case strings.HasPrefix(literal, "'") || strings.HasPrefix(literal, `"`):
+				value, err := unquoteJSONPath(literal)
+				if err != nil {
+					return step, err
+				}
+				step.value = value
+			case literal == "true" || literal == "false":
+				step.value = literal == "true"
+			default:
+				n, err := strconv.ParseFloat(literal, 64)
+				if err != nil {
+					return step, fmt.Errorf("jsonpath: invalid value %q in filter", literal)
+				}
+				step.value = n
+			}
+			break operators
+		}
+	}
+	if !strings.HasPrefix(path, "@") {
+		return step, fmt.Errorf("jsonpath: filter must start with @: %q", s)
+	}
+	filter, err := parseJSONPathExpr(path)
+	step.filter = filter
+	return step, err
+}
+
+// Execute writes the template for the data, which is converted to JSON
+// values first, so that fields have their JSON names.
+func (j *JSONPath) Execute(w io.Writer, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var root any
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&root); err != nil {
+		return err
+	}
+	return executeJSONPath(w, j.nodes, root, root)
+}
+
+// executeJSONPath writes nodes for the current value.
+func executeJSONPath(w io.Writer, nodes []jsonPathNode, root, current any) error {
+	for _, node := range nodes {
+		switch {
+		case node.isRange:
+			for _, value := range evalJSONPath(node.path, root, current) {
+				if err := executeJSONPath(w, node.body, root, value); err != nil {
+					return err
+				}
+			}
+		case node.path != nil:
+			values := evalJSONPath(node.path, root, current)
+			parts := make([]string, len(values))
+			for i, value := range values {
+				parts[i] = formatJSONPathValue(value)
+			}
+			if _, err := io.WriteString(w, strings.Join(parts, " ")); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(w, node.text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evalJSONPath returns the values an expression selects.
+func evalJSONPath(steps []jsonPathStep, root, current any) []any {
+	values := []any{current}
+	for _, step := range steps {
+		var next []any
+		for _, value := range values {
+			next = append(next, applyJSONPathStep(step, root, value)...)
+		}
+		values = next
+	}
+	return values
+}
+
+// applyJSONPathStep applies one step to a value.
+func applyJSONPathStep(step jsonPathStep, root, value any) []any {
+	switch step.kind {
+	case "root":
+		return []any{root}
+	case "field":
+		if m, ok := value.(map[string]any); ok {
+			if field, ok := m[step.name]; ok {
+				return []any{field}
+			}
+		}
+		return nil
+	case "wildcard":
+		return jsonPathChildren(value)
+	case "recursive":
+		var result []any
+		var walk func(any)
+		walk = func(v any) {
+			if m, ok := v.(map[string]any); ok {
+				if field, ok := m[step.name]; ok {
+					result = append(result, field)
+				}
+			}
+			for _, child := range jsonPathChildren(v) {
+				walk(child)
+			}
+		}
+		walk(value)
+		return result
+	case "index":
+		list, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		i := step.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil
+		}
+		return []any{list[i]}
+	case "slice":
+		list, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		start, end := 0, len(list)
+		if step.start != nil {
+			start = clampIndex(*step.start, len(list))
+		}
+		if step.end != nil {
+			end = clampIndex(*step.end, len(list))
+		}
+		if start >= end {
+			return nil
+		}
+		return list[start:end]
+	case "filter":
+		var result []any
+		for _, child := range jsonPathChildren(value) {
+			if matchJSONPathFilter(step, root, child) {
+				result = append(result, child)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// jsonPathChildren returns the elements of an array or the values of an
+// object ordered by key.
+func jsonPathChildren(value any) []any {
+	switch v := value.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		children := make([]any, len(keys))
+		for i, key := range keys {
+			children[i] = v[key]
+		}
+		return children
+	}
+	return nil
+}
+
+// clampIndex resolves a negative index and clamps it to [0, n].
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+// matchJSONPathFilter reports whether a value passes a filter.
+func matchJSONPathFilter(step jsonPathStep, root, value any) bool {
+	values := evalJSONPath(step.filter, root, value)
+	if step.op == "" {
+		return len(values) > 0 && values[0] != nil && values[0] != false
+	}
+	if len(values) == 0 {
+		return step.op == "!="
+	}
+	got := values[0]
+	if n, ok := got.(json.Number); ok {
+		got, _ = n.Float64()
+	}
+
+	switch want := step.value.(type) {
+	case float64:
+		n, ok := got.(float64)
+		if !ok {
+			return step.op == "!="
+		}
+		return compareJSONPath(step.op, cmpFloat(n, want))
+	case string:
+		s, ok := got.(string)
+		if !ok {
+			return step.op == "!="
+		}
+		return compareJSONPath(step.op, strings.Compare(s, want))
+	case bool:
+		b, ok := got.(bool)
+		switch step.op {
+		case "==":
+			return ok && b == want
+		case "!=":
+			return !ok || b != want
+		}
+	}
+	return false
+}
+
+// cmpFloat compares two numbers.
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareJSONPath applies a comparison operator to the result of a compare.
+func compareJSONPath(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// formatJSONPathValue formats a selected value: strings and numbers as
+// they are, objects and arrays as JSON.
+func formatJSONPathValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
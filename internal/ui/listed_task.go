@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"encoding/json"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// ListedTask is a task together with the task list it belongs to, as shown
+// by views that span several task lists. Account is set by views that span
+// several accounts.
+type ListedTask struct {
+	*tasks.Task   `yaml:",inline"`
+	TaskListID    string `json:"taskListId" yaml:"tasklistid"`
+	TaskListTitle string `json:"taskListTitle" yaml:"tasklisttitle"`
+	Account       string `json:"account,omitempty" yaml:"account,omitempty"`
+}
+
+// ListedTasks is a collection of tasks from several task lists.
+type ListedTasks struct {
+	Items []ListedTask `json:"items" yaml:"items"`
+}
+
+// MarshalJSON encodes the task as the API does and adds the task list
+// fields. It is needed because the embedded task's own MarshalJSON would
+// otherwise drop them.
+func (t ListedTask) MarshalJSON() ([]byte, error) {
+	fields, err := apiFields(t.Task)
+	if err != nil {
+		return nil, err
+	}
+	if fields["taskListId"], err = json.Marshal(t.TaskListID); err != nil {
+		return nil, err
+	}
+	if fields["taskListTitle"], err = json.Marshal(t.TaskListTitle); err != nil {
+		return nil, err
+	}
+	if err := addAccount(fields, t.Account); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// hasAccounts reports whether the tasks are tagged with their account.
+func (l *ListedTasks) hasAccounts() bool {
+	for _, item := range l.Items {
+		if item.Account != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sameTaskList reports whether two tasks belong to the same task list of
+// the same account.
+func sameTaskList(a, b ListedTask) bool {
+	return a.TaskListID == b.TaskListID && a.Account == b.Account
+}
+
+// ListedTaskList is a task list together with the account it belongs to, as
+// shown by views that span several accounts.
+type ListedTaskList struct {
+	*tasks.TaskList `yaml:",inline"`
+	Account         string `json:"account,omitempty" yaml:"account,omitempty"`
+}
+
+// ListedTaskLists is a collection of task lists from several accounts.
+type ListedTaskLists struct {
+	Items []ListedTaskList `json:"items" yaml:"items"`
+}
+
+// MarshalJSON encodes the task list as the API does and adds the account.
+func (l ListedTaskList) MarshalJSON() ([]byte, error) {
+	fields, err := apiFields(l.TaskList)
+	if err != nil {
+		return nil, err
+	}
+	if err := addAccount(fields, l.Account); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// listedTaskLists returns task lists without an account.
+func listedTaskLists(lists []*tasks.TaskList) []ListedTaskList {
+	items := make([]ListedTaskList, len(lists))
+	for i, list := range lists {
+		items[i] = ListedTaskList{TaskList: list}
+	}
+	return items
+}
+
+// apiFields returns the fields of an API resource as the API encodes them.
+func apiFields(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// addAccount adds the account to the fields unless it is empty.
+func addAccount(fields map[string]json.RawMessage, account string) error {
+	if account == "" {
+		return nil
+	}
+	var err error
+	fields["account"], err = json.Marshal(account)
+	return err
+}
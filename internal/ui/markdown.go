@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// WriteChecklist writes tasks as a markdown checklist. Subtasks are nested
+// under their parent, and the due date and notes of a task follow it as
+// nested text, with the notes quoted:
+//
+//   - [ ] Write report
+//     Due: 2026-11-01
+//     > Ask Bob for the numbers
+//   - [x] Draft outline
+func WriteChecklist(w io.Writer, items []*tasks.Task) error {
+	for _, node := range TaskTree(items) {
+		indent := strings.Repeat("  ", node.Depth)
+		status := " "
+		if node.Task.Status == "completed" {
+			status = "x"
+		}
+		if _, err := fmt.Fprintf(w, "%s- [%s] %s\n", indent, status, node.Task.Title); err != nil {
+			return err
+		}
+		if due, _, _ := strings.Cut(node.Task.Due, "T"); due != "" {
+			if _, err := fmt.Fprintf(w, "%s  Due: %s\n", indent, due); err != nil {
+				return err
+			}
+		}
+		if node.Task.Notes != "" {
+			for _, line := range strings.Split(node.Task.Notes, "\n") {
+				if _, err := fmt.Fprintln(w, strings.TrimRight(indent+"  > "+line, " ")); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
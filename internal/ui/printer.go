@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"text/template"
 
+	"github.com/charmbracelet/lipgloss"
 	"google.golang.org/api/tasks/v1"
 	"gopkg.in/yaml.v3"
 )
@@ -17,29 +20,129 @@ const (
 	JSONFormat OutputFormat = "json"
 	// YAMLFormat is the YAML output format.
 	YAMLFormat OutputFormat = "yaml"
-	// TableFormat is the table output format.
+	// TableFormat is the table output format: aligned columns, truncated to
+	// the terminal width and coloured by due date.
 	TableFormat OutputFormat = "table"
+	// MarkdownFormat is the markdown output format. Tasks are rendered as
+	// checklists.
+	MarkdownFormat OutputFormat = "markdown"
+	// CSVFormat is the comma-separated values output format.
+	CSVFormat OutputFormat = "csv"
+	// TSVFormat is the tab-separated values output format.
+	TSVFormat OutputFormat = "tsv"
+	// TemplateFormat renders the data with a Go template.
+	TemplateFormat OutputFormat = "template"
+	// JSONPathFormat renders the data with a JSONPath template.
+	JSONPathFormat OutputFormat = "jsonpath"
 )
 
 // Printer handles formatting and printing data to the console.
 type Printer struct {
-	out    io.Writer
-	format OutputFormat
-	quiet  bool
+	out       io.Writer
+	format    OutputFormat
+	quiet     bool
+	columns   []string
+	noHeaders bool
+	// expression is the template of the template and JSONPath formats.
+	expression string
+	template   *template.Template
+	jsonPath   *JSONPath
+	// err reports an unknown format, or a template given to another format.
+	err error
+	// width is the width tables are truncated to, and renderer renders
+	// colours; it is nil if colours are disabled.
+	width    int
+	renderer *lipgloss.Renderer
 }
 
-// NewPrinter creates a new Printer.
+// NewPrinter creates a new Printer. The template and JSONPath formats take
+// their template after an equals sign, as in "jsonpath={.items[*].title}".
+// Unknown formats fall back to the table format and are reported by Validate.
 func NewPrinter(out io.Writer, format string, quiet bool) *Printer {
+	format, expression, hasExpression := strings.Cut(format, "=")
 	var f OutputFormat
+	var err error
 	switch format {
 	case "json":
 		f = JSONFormat
 	case "yaml":
 		f = YAMLFormat
+	case "markdown", "md":
+		f = MarkdownFormat
+	case "csv":
+		f = CSVFormat
+	case "tsv":
+		f = TSVFormat
+	case "template", "go-template":
+		f = TemplateFormat
+	case "jsonpath":
+		f = JSONPathFormat
+	case "table", "":
+		f = TableFormat
 	default:
 		f = TableFormat
+		err = fmt.Errorf("unknown output format %q (use table, json, yaml, markdown, csv, tsv, template=TEMPLATE or jsonpath=TEMPLATE)", format)
+	}
+	if err == nil && hasExpression && f != TemplateFormat && f != JSONPathFormat {
+		err = fmt.Errorf("the %s format does not take a template", format)
+	}
+	return &Printer{out: out, format: f, quiet: quiet, expression: expression, err: err}
+}
+
+// SetTemplate switches the printer to the template format with the given
+// Go template.
+func (p *Printer) SetTemplate(text string) {
+	p.format = TemplateFormat
+	p.expression = text
+	p.template = nil
+}
+
+// Validate reports an unknown format and compiles the template of the
+// template and JSONPath formats, so that invalid templates are reported
+// before any work is done.
+func (p *Printer) Validate() error {
+	if p.err != nil {
+		return p.err
+	}
+	switch p.format {
+	case TemplateFormat:
+		if p.template != nil {
+			return nil
+		}
+		if p.expression == "" {
+			return fmt.Errorf("the template format needs a template (use --output template=... or --template-file)")
+		}
+		tmpl, err := ParseTemplate(p.expression)
+		if err != nil {
+			return err
+		}
+		p.template = tmpl
+	case JSONPathFormat:
+		if p.jsonPath != nil {
+			return nil
+		}
+		if p.expression == "" {
+			return fmt.Errorf("the jsonpath format needs a template (use --output jsonpath=...)")
+		}
+		jsonPath, err := ParseJSONPath(p.expression)
+		if err != nil {
+			return err
+		}
+		p.jsonPath = jsonPath
 	}
-	return &Printer{out: out, format: f, quiet: quiet}
+	return nil
+}
+
+// printCustom renders data with the template of the template or JSONPath
+// format.
+func (p *Printer) printCustom(data any) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.format == TemplateFormat {
+		return p.template.Execute(p.out, data)
+	}
+	return p.jsonPath.Execute(p.out, data)
 }
 
 // PrintTaskLists prints a list of task lists.
@@ -49,6 +152,15 @@ func (p *Printer) PrintTaskLists(lists *tasks.TaskLists) error {
 		return json.NewEncoder(p.out).Encode(lists)
 	case YAMLFormat:
 		return yaml.NewEncoder(p.out).Encode(lists)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(lists)
+	case CSVFormat, TSVFormat:
+		return p.printTaskListRows(listedTaskLists(lists.Items), defaultTaskListColumns)
+	case MarkdownFormat:
+		for _, item := range lists.Items {
+			fmt.Fprintf(p.out, "- %s\n", item.Title)
+		}
+		return nil
 	default:
 		if p.quiet {
 			return nil
@@ -57,11 +169,43 @@ func (p *Printer) PrintTaskLists(lists *tasks.TaskLists) error {
 			fmt.Fprintln(p.out, "No task lists found.")
 			return nil
 		}
-		fmt.Fprintln(p.out, "Task Lists:")
+		t := &table{headers: []string{"TITLE", "ID"}}
 		for _, item := range lists.Items {
-			fmt.Fprintf(p.out, "- %s (%s)\n", item.Title, item.Id)
+			t.rows = append(t.rows, []tableCell{{text: singleLine(item.Title)}, {text: item.Id}})
+		}
+		return p.printTable(t)
+	}
+}
+
+// PrintListedTaskLists prints task lists from several accounts.
+func (p *Printer) PrintListedTaskLists(listed *ListedTaskLists) error {
+	switch p.format {
+	case JSONFormat:
+		return json.NewEncoder(p.out).Encode(listed)
+	case YAMLFormat:
+		return yaml.NewEncoder(p.out).Encode(listed)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(listed)
+	case CSVFormat, TSVFormat:
+		return p.printTaskListRows(listed.Items, defaultAccountTaskListColumns)
+	case MarkdownFormat:
+		for _, item := range listed.Items {
+			fmt.Fprintf(p.out, "- %s (%s)\n", item.Title, item.Account)
 		}
 		return nil
+	default:
+		if p.quiet {
+			return nil
+		}
+		if len(listed.Items) == 0 {
+			fmt.Fprintln(p.out, "No task lists found.")
+			return nil
+		}
+		t := &table{headers: []string{"TITLE", "ACCOUNT", "ID"}}
+		for _, item := range listed.Items {
+			t.rows = append(t.rows, []tableCell{{text: singleLine(item.Title)}, {text: item.Account}, {text: item.Id}})
+		}
+		return p.printTable(t)
 	}
 }
 
@@ -72,6 +216,13 @@ func (p *Printer) PrintTaskList(list *tasks.TaskList) error {
 		return json.NewEncoder(p.out).Encode(list)
 	case YAMLFormat:
 		return yaml.NewEncoder(p.out).Encode(list)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(list)
+	case CSVFormat, TSVFormat:
+		return p.printTaskListRows(listedTaskLists([]*tasks.TaskList{list}), defaultTaskListColumns)
+	case MarkdownFormat:
+		fmt.Fprintf(p.out, "# %s\n", list.Title)
+		return nil
 	default:
 		if p.quiet {
 			return nil
@@ -90,6 +241,16 @@ func (p *Printer) PrintTasks(tasks *tasks.Tasks) error {
 		return json.NewEncoder(p.out).Encode(tasks)
 	case YAMLFormat:
 		return yaml.NewEncoder(p.out).Encode(tasks)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(tasks)
+	case CSVFormat, TSVFormat:
+		items := make([]ListedTask, len(tasks.Items))
+		for i, task := range tasks.Items {
+			items[i] = ListedTask{Task: task}
+		}
+		return p.printTaskRows(items, defaultTaskColumns)
+	case MarkdownFormat:
+		return WriteChecklist(p.out, tasks.Items)
 	default:
 		if p.quiet {
 			return nil
@@ -98,15 +259,82 @@ func (p *Printer) PrintTasks(tasks *tasks.Tasks) error {
 			fmt.Fprintln(p.out, "No tasks found.")
 			return nil
 		}
-		fmt.Fprintln(p.out, "Tasks:")
-		for _, item := range tasks.Items {
-			status := " "
-			if item.Status == "completed" {
-				status = "x"
+		t := &table{headers: []string{"STATUS", "TITLE", "DUE", "ID"}, flex: 1}
+		for _, node := range TaskTree(tasks.Items) {
+			t.rows = append(t.rows, taskRow(node.Task, node.Depth, "", false))
+		}
+		return p.printTable(t)
+	}
+}
+
+// PrintListedTasks prints tasks from several task lists, grouped by task
+// list in the table format.
+func (p *Printer) PrintListedTasks(listed *ListedTasks) error {
+	switch p.format {
+	case JSONFormat:
+		return json.NewEncoder(p.out).Encode(listed)
+	case YAMLFormat:
+		return yaml.NewEncoder(p.out).Encode(listed)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(listed)
+	case CSVFormat, TSVFormat:
+		defaults := defaultListedTaskColumns
+		if listed.hasAccounts() {
+			defaults = defaultAccountTaskColumns
+		}
+		return p.printTaskRows(listed.Items, defaults)
+	case MarkdownFormat:
+		for i := 0; i < len(listed.Items); {
+			// Print the tasks of one task list under a heading.
+			if i > 0 {
+				fmt.Fprintln(p.out)
+			}
+			first := listed.Items[i]
+			var items []*tasks.Task
+			for ; i < len(listed.Items) && sameTaskList(listed.Items[i], first); i++ {
+				items = append(items, listed.Items[i].Task)
+			}
+			heading := first.TaskListTitle
+			if first.Account != "" {
+				heading += " (" + first.Account + ")"
+			}
+			fmt.Fprintf(p.out, "## %s\n\n", heading)
+			if err := WriteChecklist(p.out, items); err != nil {
+				return err
 			}
-			fmt.Fprintf(p.out, "[%s] %s (%s)\n", status, item.Title, item.Id)
 		}
 		return nil
+	default:
+		if p.quiet {
+			return nil
+		}
+		if len(listed.Items) == 0 {
+			fmt.Fprintln(p.out, "No tasks found.")
+			return nil
+		}
+		withAccount := listed.hasAccounts()
+		t := &table{headers: []string{"STATUS", "TITLE", "DUE", "LIST", "ID"}, flex: 1}
+		if withAccount {
+			t.headers = []string{"STATUS", "TITLE", "DUE", "LIST", "ACCOUNT", "ID"}
+		}
+		for i := 0; i < len(listed.Items); {
+			// Add the tasks of one task list as a tree.
+			first := listed.Items[i]
+			var items []*tasks.Task
+			for ; i < len(listed.Items) && sameTaskList(listed.Items[i], first); i++ {
+				items = append(items, listed.Items[i].Task)
+			}
+			for _, node := range TaskTree(items) {
+				row := taskRow(node.Task, node.Depth, singleLine(first.TaskListTitle), true)
+				if withAccount {
+					// The account goes before the ID in the last column.
+					id := row[len(row)-1]
+					row = append(row[:len(row)-1], tableCell{first.Account, id.tone}, id)
+				}
+				t.rows = append(t.rows, row)
+			}
+		}
+		return p.printTable(t)
 	}
 }
 
@@ -117,15 +345,28 @@ func (p *Printer) PrintTask(task *tasks.Task) error {
 		return json.NewEncoder(p.out).Encode(task)
 	case YAMLFormat:
 		return yaml.NewEncoder(p.out).Encode(task)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(task)
+	case CSVFormat, TSVFormat:
+		return p.printTaskRows([]ListedTask{{Task: task}}, defaultTaskColumns)
+	case MarkdownFormat:
+		return WriteChecklist(p.out, []*tasks.Task{task})
 	default:
 		if p.quiet {
 			return nil
 		}
+		due, _, _ := strings.Cut(task.Due, "T")
 		fmt.Fprintf(p.out, "ID:      %s\n", task.Id)
 		fmt.Fprintf(p.out, "Title:   %s\n", task.Title)
-		fmt.Fprintf(p.out, "Status:  %s\n", task.Status)
+		fmt.Fprintf(p.out, "Status:  %s\n", p.colored(task.Status, dueTone(task)))
 		fmt.Fprintf(p.out, "Notes:   %s\n", task.Notes)
-		fmt.Fprintf(p.out, "Due:     %s\n", task.Due)
+		fmt.Fprintf(p.out, "Due:     %s\n", p.colored(due, dueTone(task)))
+		if task.Completed != nil {
+			fmt.Fprintf(p.out, "Done:    %s\n", *task.Completed)
+		}
+		if task.Parent != "" {
+			fmt.Fprintf(p.out, "Parent:  %s\n", task.Parent)
+		}
 		fmt.Fprintf(p.out, "Self:    %s\n", task.SelfLink)
 		return nil
 	}
@@ -138,6 +379,19 @@ func (p *Printer) PrintAccounts(accounts []string, activeAccount string) error {
 		return json.NewEncoder(p.out).Encode(accounts)
 	case YAMLFormat:
 		return yaml.NewEncoder(p.out).Encode(accounts)
+	case TemplateFormat, JSONPathFormat:
+		return p.printCustom(accounts)
+	case CSVFormat, TSVFormat:
+		return p.printAccountRows(accounts, activeAccount)
+	case MarkdownFormat:
+		for _, account := range accounts {
+			if account == activeAccount {
+				fmt.Fprintf(p.out, "- %s (active)\n", account)
+			} else {
+				fmt.Fprintf(p.out, "- %s\n", account)
+			}
+		}
+		return nil
 	default:
 		if p.quiet {
 			return nil
@@ -163,6 +417,9 @@ func (p *Printer) PrintSuccess(message string) error {
 	if p.quiet {
 		return nil
 	}
+	if p.format == TemplateFormat || p.format == JSONPathFormat {
+		return p.printCustom(&Message{Message: message})
+	}
 	fmt.Fprintln(p.out, message)
 	return nil
 }
@@ -172,7 +429,11 @@ func (p *Printer) PrintDelete(resource string, id string) error {
 	if p.quiet {
 		return nil
 	}
-	fmt.Fprintf(p.out, "Successfully deleted %s: %s\n", resource, id)
+	message := fmt.Sprintf("Successfully deleted %s: %s", resource, id)
+	if p.format == TemplateFormat || p.format == JSONPathFormat {
+		return p.printCustom(&Message{Message: message, Resource: resource, ID: id})
+	}
+	fmt.Fprintln(p.out, message)
 	return nil
 }
 
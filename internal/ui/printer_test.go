@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"google.golang.org/api/tasks/v1"
 	"gopkg.in/yaml.v3"
@@ -94,3 +95,459 @@ func TestPrinter_PrintAccounts(t *testing.T) {
 		}
 	})
 }
+
+func TestPrinter_PrintAccountStatuses(t *testing.T) {
+	statuses := &AccountStatuses{
+		Items: []AccountStatus{
+			{Account: "a@example.com", Active: true, Scopes: []string{"https://www.googleapis.com/auth/tasks"}, Expiry: "2026-10-17T13:00:00Z", Refreshed: "2026-10-17T12:00:00Z", Refresh: RefreshOK},
+			{Account: "b@example.com", Scopes: []string{"https://www.googleapis.com/auth/tasks.readonly"}, Refresh: RefreshFailed, Error: "token refresh failed"},
+		},
+	}
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		if err := p.PrintAccountStatuses(statuses); err != nil {
+			t.Fatalf("PrintAccountStatuses failed: %v", err)
+		}
+		expected := "ACCOUNT                 REFRESH  EXPIRY                REFRESHED             SCOPES\n" +
+			"a@example.com (active)  ok       2026-10-17T13:00:00Z  2026-10-17T12:00:00Z  tasks\n" +
+			"b@example.com           failed   -                     -                     tasks.readonly\n" +
+			"\nb@example.com: token refresh failed\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "csv", false)
+		p.SetColumns([]string{"account", "refresh", "error"})
+		if err := p.PrintAccountStatuses(statuses); err != nil {
+			t.Fatalf("PrintAccountStatuses failed: %v", err)
+		}
+		expected := "account,refresh,error\na@example.com,ok,\nb@example.com,failed,token refresh failed\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+}
+
+func TestPrinter_PrintTasksHierarchy(t *testing.T) {
+	items := &tasks.Tasks{
+		Items: []*tasks.Task{
+			{Id: "c", Title: "Child", Parent: "p"},
+			{Id: "p", Title: "Parent"},
+			{Id: "g", Title: "Grandchild", Parent: "c"},
+			{Id: "o", Title: "Orphan", Parent: "missing"},
+		},
+	}
+
+	var buf bytes.Buffer
+	p := NewPrinter(&buf, "table", false)
+	p.PrintTasks(items)
+
+	expected := "STATUS  TITLE           DUE  ID\n" +
+		"[ ]     Parent               p\n" +
+		"[ ]       Child              c\n" +
+		"[ ]         Grandchild       g\n" +
+		"[ ]     Orphan               o\n"
+	if buf.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, buf.String())
+	}
+}
+
+func TestPrinter_Table(t *testing.T) {
+	today := time.Now()
+	items := &tasks.Tasks{
+		Items: []*tasks.Task{
+			{Id: "1", Title: "Pay the rent before the landlord calls", Due: today.AddDate(0, 0, -1).Format(time.DateOnly) + "T00:00:00.000Z"},
+			{Id: "2", Title: "Water plants", Due: today.Format(time.DateOnly) + "T00:00:00.000Z"},
+			{Id: "3", Title: "Buy milk", Status: "completed"},
+		},
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		p.SetWidth(40)
+		if err := p.PrintTasks(items); err != nil {
+			t.Fatalf("PrintTasks failed: %v", err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if width := len([]rune(line)); width > 40 {
+				t.Errorf("expected lines of at most 40 columns, got %d: %q", width, line)
+			}
+		}
+		if !strings.Contains(buf.String(), "Pay the rent be…") {
+			t.Errorf("expected the title to be truncated, got\n%s", buf.String())
+		}
+	})
+
+	t.Run("colors", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		p.SetColor(true)
+		if err := p.PrintTasks(items); err != nil {
+			t.Fatalf("PrintTasks failed: %v", err)
+		}
+		lines := strings.Split(buf.String(), "\n")
+		for i, code := range map[int]string{1: "\x1b[31m", 2: "\x1b[33m", 3: "\x1b[2m"} {
+			if !strings.Contains(lines[i], code) {
+				t.Errorf("expected line %d to contain %q, got %q", i, code, lines[i])
+			}
+		}
+	})
+
+	t.Run("no colors", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		if err := p.PrintTasks(items); err != nil {
+			t.Fatalf("PrintTasks failed: %v", err)
+		}
+		if strings.Contains(buf.String(), "\x1b[") {
+			t.Errorf("expected no escape codes, got %q", buf.String())
+		}
+	})
+}
+
+func TestPrinter_PrintTasksMarkdown(t *testing.T) {
+	items := &tasks.Tasks{
+		Items: []*tasks.Task{
+			{Id: "p", Title: "Parent", Due: "2026-11-01T00:00:00.000Z", Notes: "First\n\nSecond"},
+			{Id: "c", Title: "Child", Parent: "p", Status: "completed"},
+		},
+	}
+
+	var buf bytes.Buffer
+	p := NewPrinter(&buf, "markdown", false)
+	if err := p.PrintTasks(items); err != nil {
+		t.Fatalf("PrintTasks failed: %v", err)
+	}
+
+	expected := "- [ ] Parent\n  Due: 2026-11-01\n  > First\n  >\n  > Second\n  - [x] Child\n"
+	if buf.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, buf.String())
+	}
+}
+
+func TestPrinter_PrintListedTasks(t *testing.T) {
+	listed := &ListedTasks{
+		Items: []ListedTask{
+			{Task: &tasks.Task{Id: "1", Title: "Deploy"}, TaskListID: "work", TaskListTitle: "Work"},
+			{Task: &tasks.Task{Id: "2", Title: "Rollback", Parent: "1"}, TaskListID: "work", TaskListTitle: "Work"},
+			{Task: &tasks.Task{Id: "3", Title: "Groceries", Status: "completed"}, TaskListID: "home", TaskListTitle: "Home"},
+		},
+	}
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		expected := "STATUS  TITLE       DUE  LIST  ID\n" +
+			"[ ]     Deploy           Work  1\n" +
+			"[ ]       Rollback       Work  2\n" +
+			"[x]     Groceries        Home  3\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "markdown", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		expected := "## Work\n\n- [ ] Deploy\n  - [ ] Rollback\n\n## Home\n\n- [x] Groceries\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "json", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		var decoded struct {
+			Items []struct {
+				Id            string `json:"id"`
+				Title         string `json:"title"`
+				TaskListID    string `json:"taskListId"`
+				TaskListTitle string `json:"taskListTitle"`
+			} `json:"items"`
+		}
+		if err := json.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatalf("failed to decode json: %v", err)
+		}
+		if len(decoded.Items) != 3 || decoded.Items[2].Title != "Groceries" || decoded.Items[2].TaskListTitle != "Home" {
+			t.Errorf("unexpected json output: %+v", decoded.Items)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "yaml", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		var decoded struct {
+			Items []struct {
+				Title         string `yaml:"title"`
+				TaskListTitle string `yaml:"tasklisttitle"`
+			} `yaml:"items"`
+		}
+		if err := yaml.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatalf("failed to decode yaml: %v", err)
+		}
+		if len(decoded.Items) != 3 || decoded.Items[0].Title != "Deploy" || decoded.Items[0].TaskListTitle != "Work" {
+			t.Errorf("unexpected yaml output: %+v", decoded.Items)
+		}
+	})
+}
+
+func TestPrinter_MultipleAccounts(t *testing.T) {
+	// The same task list of two accounts is printed as two task lists.
+	listed := &ListedTasks{
+		Items: []ListedTask{
+			{Task: &tasks.Task{Id: "1", Title: "Deploy"}, TaskListID: "work", TaskListTitle: "Work", Account: "a@example.com"},
+			{Task: &tasks.Task{Id: "2", Title: "Review"}, TaskListID: "work", TaskListTitle: "Work", Account: "b@example.com"},
+		},
+	}
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		expected := "STATUS  TITLE   DUE  LIST  ACCOUNT        ID\n" +
+			"[ ]     Deploy       Work  a@example.com  1\n" +
+			"[ ]     Review       Work  b@example.com  2\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "markdown", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		expected := "## Work (a@example.com)\n\n- [ ] Deploy\n\n## Work (b@example.com)\n\n- [ ] Review\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "csv", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		expected := "id,title,due,status,list,account\n1,Deploy,,,Work,a@example.com\n2,Review,,,Work,b@example.com\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("task lists", func(t *testing.T) {
+		lists := &ListedTaskLists{
+			Items: []ListedTaskList{
+				{TaskList: &tasks.TaskList{Id: "work", Title: "Work"}, Account: "a@example.com"},
+				{TaskList: &tasks.TaskList{Id: "home", Title: "Home"}, Account: "b@example.com"},
+			},
+		}
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		if err := p.PrintListedTaskLists(lists); err != nil {
+			t.Fatalf("PrintListedTaskLists failed: %v", err)
+		}
+		expected := "TITLE  ACCOUNT        ID\nWork   a@example.com  work\nHome   b@example.com  home\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+
+		buf.Reset()
+		p = NewPrinter(&buf, "json", false)
+		if err := p.PrintListedTaskLists(lists); err != nil {
+			t.Fatalf("PrintListedTaskLists failed: %v", err)
+		}
+		var decoded struct {
+			Items []struct {
+				Id      string `json:"id"`
+				Account string `json:"account"`
+			} `json:"items"`
+		}
+		if err := json.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatalf("failed to decode json: %v", err)
+		}
+		if len(decoded.Items) != 2 || decoded.Items[1].Id != "home" || decoded.Items[1].Account != "b@example.com" {
+			t.Errorf("unexpected json output: %+v", decoded.Items)
+		}
+	})
+}
+
+func TestPrinter_Tabular(t *testing.T) {
+	completed := "2026-10-12T16:00:00.000Z"
+	listed := &ListedTasks{
+		Items: []ListedTask{
+			{Task: &tasks.Task{Id: "1", Title: "Deploy, then verify", Notes: "Line 1\nLine \"2\"", Due: "2026-11-01T00:00:00.000Z", Status: "needsAction"}, TaskListID: "work", TaskListTitle: "Work"},
+			{Task: &tasks.Task{Id: "2", Title: "Groceries", Status: "completed", Completed: &completed}, TaskListID: "home", TaskListTitle: "Home"},
+		},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "csv", false)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		expected := "id,title,due,status,list\n1,\"Deploy, then verify\",2026-11-01,needsAction,Work\n2,Groceries,,completed,Home\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+
+	t.Run("tsv with columns", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "tsv", false)
+		p.SetColumns([]string{"Title", "notes", "completed"})
+		p.SetNoHeaders(true)
+		if err := p.PrintListedTasks(listed); err != nil {
+			t.Fatalf("PrintListedTasks failed: %v", err)
+		}
+		expected := "Deploy, then verify\t\"Line 1\nLine \"\"2\"\"\"\t\nGroceries\t\t2026-10-12T16:00:00.000Z\n"
+		if buf.String() != expected {
+			t.Errorf("expected output %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "csv", false)
+		p.SetColumns([]string{"owner"})
+		if err := p.PrintTaskLists(&tasks.TaskLists{}); err == nil || !strings.Contains(err.Error(), "unknown column: owner") {
+			t.Errorf("expected an unknown column error, got %v", err)
+		}
+	})
+
+	t.Run("accounts", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "csv", false)
+		if err := p.PrintAccounts([]string{"a@example.com", "b@example.com"}, "b@example.com"); err != nil {
+			t.Fatalf("PrintAccounts failed: %v", err)
+		}
+		expected := "account,active\na@example.com,false\nb@example.com,true\n"
+		if buf.String() != expected {
+			t.Errorf("expected output\n%s\ngot\n%s", expected, buf.String())
+		}
+	})
+}
+
+func TestPrinter_Template(t *testing.T) {
+	list := &tasks.Tasks{
+		Items: []*tasks.Task{
+			{Id: "1", Title: "Write report", Due: "2026-11-01T00:00:00.000Z", Status: "needsAction"},
+			{Id: "2", Title: "Buy milk", Status: "completed"},
+		},
+	}
+
+	t.Run("tasks", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, `template={{range .Items}}{{if isCompleted .}}x{{else}}-{{end}} {{.Title}} {{date "Jan 2" .Due | default "someday"}}{{"\n"}}{{end}}`, false)
+		if err := p.PrintTasks(list); err != nil {
+			t.Fatalf("PrintTasks failed: %v", err)
+		}
+		expected := "- Write report Nov 1\nx Buy milk someday\n"
+		if buf.String() != expected {
+			t.Errorf("expected output %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("delete message", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "table", false)
+		p.SetTemplate("{{.Resource}} {{.ID}}: {{.Message}}\n")
+		if err := p.PrintDelete("task", "42"); err != nil {
+			t.Fatalf("PrintDelete failed: %v", err)
+		}
+		expected := "task 42: Successfully deleted task: 42\n"
+		if buf.String() != expected {
+			t.Errorf("expected output %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, format := range []string{"template={{.Title", "template", "jsonpath={.items[}", "jsn", "json=x", "table={{.Title}}"} {
+			if err := NewPrinter(&bytes.Buffer{}, format, false).Validate(); err == nil {
+				t.Errorf("expected an error for %q", format)
+			}
+		}
+	})
+}
+
+func TestPrinter_JSONPath(t *testing.T) {
+	listed := &ListedTasks{
+		Items: []ListedTask{
+			{Task: &tasks.Task{Id: "1", Title: "Write report", Status: "needsAction"}, TaskListID: "work", TaskListTitle: "Work"},
+			{Task: &tasks.Task{Id: "2", Title: "Buy milk", Status: "completed"}, TaskListID: "home", TaskListTitle: "Home"},
+			{Task: &tasks.Task{Id: "3", Title: "Call Ann", Status: "needsAction"}, TaskListID: "home", TaskListTitle: "Home"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"field", "{.items[0].title}", "Write report"},
+		{"wildcard", "{.items[*].id}", "1 2 3"},
+		{"negative index", "{.items[-1].title}", "Call Ann"},
+		{"slice", "{.items[1:].id}", "2 3"},
+		{"bracket field", "{.items[0]['title']}", "Write report"},
+		{"recursive", "{..taskListTitle}", "Work Home Home"},
+		{"filter", `{.items[?(@.status=="completed")].title}`, "Buy milk"},
+		{"filter existence", `{.items[?(@.nonexistent)].title}`, ""},
+		{"range", `{range .items[*]}{.id}{"\t"}{.title}{"\n"}{end}`, "1\tWrite report\n2\tBuy milk\n3\tCall Ann\n"},
+		{"text", "Tasks: {.items[0].id}", "Tasks: 1"},
+		{"missing", "{.items[0].due}", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := NewPrinter(&buf, "jsonpath="+tt.template, false)
+			if err := p.PrintListedTasks(listed); err != nil {
+				t.Fatalf("PrintListedTasks failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected output %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	t.Run("accounts", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, "jsonpath={[1]}", false)
+		if err := p.PrintAccounts([]string{"a@example.com", "b@example.com"}, "a@example.com"); err != nil {
+			t.Fatalf("PrintAccounts failed: %v", err)
+		}
+		if buf.String() != "b@example.com" {
+			t.Errorf("expected output %q, got %q", "b@example.com", buf.String())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, template := range []string{"{.items", "{range .items[*]}{.id}", "{end}", "{.items[?(@.a ~ 1)]}", `{"open}`} {
+			if _, err := ParseJSONPath(template); err == nil {
+				t.Errorf("expected an error for %q", template)
+			}
+		}
+	})
+}
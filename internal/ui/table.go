@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+	"google.golang.org/api/tasks/v1"
+)
+
+// tone is the colour of a table cell.
+type tone int
+
+const (
+	toneNone tone = iota
+	toneHeader
+	toneOverdue
+	toneToday
+	toneCompleted
+)
+
+// tableCell is a cell of a table.
+type tableCell struct {
+	text string
+	tone tone
+}
+
+// table is a table with aligned columns. The flex column is truncated when
+// the table is wider than the terminal.
+type table struct {
+	headers []string
+	flex    int
+	rows    [][]tableCell
+}
+
+// tableGap separates the columns of a table.
+const tableGap = "  "
+
+// minFlexWidth is the width the flex column is never truncated below.
+const minFlexWidth = 10
+
+// SetColor enables or disables colours in the table format.
+func (p *Printer) SetColor(color bool) {
+	if !color {
+		p.renderer = nil
+		return
+	}
+	// Basic ANSI colours render the same on every colour terminal.
+	p.renderer = lipgloss.NewRenderer(p.out)
+	p.renderer.SetColorProfile(termenv.ANSI)
+}
+
+// SetWidth sets the width tables are truncated to. Zero disables
+// truncation.
+func (p *Printer) SetWidth(width int) {
+	p.width = width
+}
+
+// style returns the style of a tone, or nil if colours are disabled.
+func (p *Printer) style(t tone) *lipgloss.Style {
+	if p.renderer == nil || t == toneNone {
+		return nil
+	}
+	style := p.renderer.NewStyle()
+	switch t {
+	case toneHeader:
+		style = style.Bold(true)
+	case toneOverdue:
+		style = style.Foreground(lipgloss.Color("1"))
+	case toneToday:
+		style = style.Foreground(lipgloss.Color("3"))
+	case toneCompleted:
+		style = style.Faint(true)
+	}
+	return &style
+}
+
+// colored renders text in the colour of a tone.
+func (p *Printer) colored(text string, t tone) string {
+	if style := p.style(t); style != nil && text != "" {
+		return style.Render(text)
+	}
+	return text
+}
+
+// printTable prints a table with aligned columns.
+func (p *Printer) printTable(t *table) error {
+	widths := make([]int, len(t.headers))
+	for i, header := range t.headers {
+		widths[i] = ansi.StringWidth(header)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], ansi.StringWidth(cell.text))
+		}
+	}
+
+	// Shrink the flex column to fit the terminal.
+	if p.width > 0 {
+		total := len(tableGap) * (len(widths) - 1)
+		for _, width := range widths {
+			total += width
+		}
+		if excess := total - p.width; excess > 0 {
+			widths[t.flex] = max(widths[t.flex]-excess, min(widths[t.flex], minFlexWidth))
+		}
+	}
+
+	header := make([]tableCell, len(t.headers))
+	for i, text := range t.headers {
+		header[i] = tableCell{text: text, tone: toneHeader}
+	}
+	for _, row := range append([][]tableCell{header}, t.rows...) {
+		var line strings.Builder
+		for i, cell := range row {
+			text := ansi.Truncate(cell.text, widths[i], "…")
+			padding := widths[i] - ansi.StringWidth(text)
+			line.WriteString(p.colored(text, cell.tone))
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", padding) + tableGap)
+			}
+		}
+		if _, err := fmt.Fprintln(p.out, strings.TrimRight(line.String(), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskRow returns the cells of a task: status, title indented by depth, due
+// date and, if withList is set, the task list, followed by the ID.
+func taskRow(task *tasks.Task, depth int, list string, withList bool) []tableCell {
+	status, rowTone := "[ ]", toneNone
+	if task.Status == "completed" {
+		status, rowTone = "[x]", toneCompleted
+	}
+	due, _, _ := strings.Cut(task.Due, "T")
+
+	title := strings.Repeat("  ", depth) + singleLine(task.Title)
+	row := []tableCell{{status, rowTone}, {title, rowTone}, {due, dueTone(task)}}
+	if withList {
+		row = append(row, tableCell{list, rowTone})
+	}
+	return append(row, tableCell{task.Id, rowTone})
+}
+
+// dueTone returns the tone of a task's due date: overdue if it is before
+// today, today if it is today, and completed for completed tasks.
+func dueTone(task *tasks.Task) tone {
+	if task.Status == "completed" {
+		return toneCompleted
+	}
+	due, _, _ := strings.Cut(task.Due, "T")
+	today := time.Now().Format(time.DateOnly)
+	switch {
+	case due == "":
+		return toneNone
+	case due < today:
+		return toneOverdue
+	case due == today:
+		return toneToday
+	}
+	return toneNone
+}
+
+// singleLine replaces line breaks and tabs with spaces, so that a cell stays
+// on one line.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(s)
+}
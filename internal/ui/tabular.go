@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Columns available in the CSV and TSV formats.
+var (
+	TaskColumns          = []string{"id", "title", "notes", "due", "status", "completed", "updated", "parent", "position", "hidden", "list", "listid", "account"}
+	TaskListColumns      = []string{"id", "title", "updated", "account"}
+	AccountColumns       = []string{"account", "active"}
+	AccountStatusColumns = []string{"account", "active", "client", "scopes", "expiry", "refreshed", "refresh", "error"}
+)
+
+// Columns printed if none are selected.
+var (
+	defaultTaskColumns          = []string{"id", "title", "due", "status"}
+	defaultListedTaskColumns    = []string{"id", "title", "due", "status", "list"}
+	defaultTaskListColumns      = []string{"id", "title"}
+	defaultAccountColumns       = []string{"account", "active"}
+	defaultAccountStatusColumns = []string{"account", "active", "refresh", "expiry", "scopes"}
+	// Tasks and task lists from several accounts also name their account.
+	defaultAccountTaskColumns     = []string{"id", "title", "due", "status", "list", "account"}
+	defaultAccountTaskListColumns = []string{"id", "title", "account"}
+)
+
+// SetColumns selects the columns of the CSV and TSV formats. Nil selects
+// the default columns.
+func (p *Printer) SetColumns(columns []string) {
+	p.columns = columns
+}
+
+// SetNoHeaders omits the header row of the CSV and TSV formats.
+func (p *Printer) SetNoHeaders(noHeaders bool) {
+	p.noHeaders = noHeaders
+}
+
+// Tabular reports whether the printer prints rows of columns, that is CSV
+// or TSV.
+func (p *Printer) Tabular() bool {
+	return p.format == CSVFormat || p.format == TSVFormat
+}
+
+// selectColumns returns the selected columns, checking that they are
+// available.
+func (p *Printer) selectColumns(available, defaults []string) ([]string, error) {
+	if len(p.columns) == 0 {
+		return defaults, nil
+	}
+	columns := make([]string, len(p.columns))
+	for i, column := range p.columns {
+		columns[i] = strings.ToLower(strings.TrimSpace(column))
+		if !contains(available, columns[i]) {
+			return nil, fmt.Errorf("unknown column: %s. Available columns: %s", column, strings.Join(available, ", "))
+		}
+	}
+	return columns, nil
+}
+
+// printRows prints a header and rows as CSV or TSV. Fields that contain the
+// separator, quotes or newlines are quoted.
+func (p *Printer) printRows(columns []string, rows [][]string) error {
+	w := csv.NewWriter(p.out)
+	if p.format == TSVFormat {
+		w.Comma = '\t'
+	}
+	if !p.noHeaders {
+		if err := w.Write(columns); err != nil {
+			return err
+		}
+	}
+	return w.WriteAll(rows)
+}
+
+// printTaskRows prints tasks as CSV or TSV.
+func (p *Printer) printTaskRows(items []ListedTask, defaults []string) error {
+	columns, err := p.selectColumns(TaskColumns, defaults)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = make([]string, len(columns))
+		for j, column := range columns {
+			rows[i][j] = taskColumn(item, column)
+		}
+	}
+	return p.printRows(columns, rows)
+}
+
+// taskColumn returns the value of a column for a task. Due dates are
+// printed as dates, as Google Tasks ignores the time.
+func taskColumn(item ListedTask, column string) string {
+	task := item.Task
+	switch column {
+	case "id":
+		return task.Id
+	case "title":
+		return task.Title
+	case "notes":
+		return task.Notes
+	case "due":
+		due, _, _ := strings.Cut(task.Due, "T")
+		return due
+	case "status":
+		return task.Status
+	case "completed":
+		if task.Completed != nil {
+			return *task.Completed
+		}
+		return ""
+	case "updated":
+		return task.Updated
+	case "parent":
+		return task.Parent
+	case "position":
+		return task.Position
+	case "hidden":
+		return strconv.FormatBool(task.Hidden)
+	case "list":
+		return item.TaskListTitle
+	case "listid":
+		return item.TaskListID
+	case "account":
+		return item.Account
+	}
+	return ""
+}
+
+// printTaskListRows prints task lists as CSV or TSV.
+func (p *Printer) printTaskListRows(lists []ListedTaskList, defaults []string) error {
+	columns, err := p.selectColumns(TaskListColumns, defaults)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(lists))
+	for i, list := range lists {
+		rows[i] = make([]string, len(columns))
+		for j, column := range columns {
+			switch column {
+			case "id":
+				rows[i][j] = list.Id
+			case "title":
+				rows[i][j] = list.Title
+			case "updated":
+				rows[i][j] = list.Updated
+			case "account":
+				rows[i][j] = list.Account
+			}
+		}
+	}
+	return p.printRows(columns, rows)
+}
+
+// printAccountRows prints accounts as CSV or TSV.
+func (p *Printer) printAccountRows(accounts []string, activeAccount string) error {
+	columns, err := p.selectColumns(AccountColumns, defaultAccountColumns)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(accounts))
+	for i, account := range accounts {
+		rows[i] = make([]string, len(columns))
+		for j, column := range columns {
+			switch column {
+			case "account":
+				rows[i][j] = account
+			case "active":
+				rows[i][j] = strconv.FormatBool(account == activeAccount)
+			}
+		}
+	}
+	return p.printRows(columns, rows)
+}
+
+// contains reports whether the list contains the value.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
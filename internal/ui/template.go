@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+// Message is the data of success and delete messages in the template and
+// JSONPath formats.
+type Message struct {
+	Message string `json:"message"`
+	// Resource and ID name the deleted item of delete messages.
+	Resource string `json:"resource,omitempty"`
+	ID       string `json:"id,omitempty"`
+}
+
+// templateFuncs are the functions available in templates in addition to
+// the built-in ones.
+var templateFuncs = template.FuncMap{
+	// date formats an RFC3339 timestamp with a Go layout, e.g.
+	// {{date "Jan 2" .Due}}. Empty timestamps stay empty.
+	"date": func(layout, value string) (string, error) {
+		if value == "" {
+			return "", nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(layout), nil
+	},
+	// isCompleted reports whether a task is completed.
+	"isCompleted": func(v any) bool {
+		switch task := v.(type) {
+		case *tasks.Task:
+			return task != nil && task.Status == "completed"
+		case ListedTask:
+			return task.Task != nil && task.Status == "completed"
+		}
+		return false
+	},
+	// json encodes a value as JSON.
+	"json": func(v any) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	// default returns the value, or def if the value is empty.
+	"default": func(def, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// ParseTemplate compiles a Go template for the template format.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
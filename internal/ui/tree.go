@@ -0,0 +1,51 @@
+package ui
+
+import "google.golang.org/api/tasks/v1"
+
+// TaskNode is a task together with its depth in the task hierarchy.
+type TaskNode struct {
+	Task  *tasks.Task
+	Depth int
+}
+
+// TaskTree orders tasks so that subtasks directly follow their parent while
+// keeping the relative order of the input among siblings. Tasks whose parent
+// is not part of the input are treated as top-level tasks.
+func TaskTree(items []*tasks.Task) []TaskNode {
+	present := make(map[string]bool, len(items))
+	for _, item := range items {
+		present[item.Id] = true
+	}
+
+	children := make(map[string][]*tasks.Task)
+	var roots []*tasks.Task
+	for _, item := range items {
+		if item.Parent != "" && present[item.Parent] && item.Parent != item.Id {
+			children[item.Parent] = append(children[item.Parent], item)
+		} else {
+			roots = append(roots, item)
+		}
+	}
+
+	nodes := make([]TaskNode, 0, len(items))
+	visited := make(map[string]bool, len(items))
+	var walk func(task *tasks.Task, depth int)
+	walk = func(task *tasks.Task, depth int) {
+		if visited[task.Id] {
+			return
+		}
+		visited[task.Id] = true
+		nodes = append(nodes, TaskNode{Task: task, Depth: depth})
+		for _, child := range children[task.Id] {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	// Tasks in a parent cycle are unreachable from the roots.
+	for _, item := range items {
+		walk(item, 0)
+	}
+	return nodes
+}